@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+
+	"github.com/qwaq-dev/culina/internal/repository/postgres"
+	"github.com/qwaq-dev/culina/pkg/config"
+	"github.com/qwaq-dev/culina/pkg/logger/sl"
+	"github.com/qwaq-dev/culina/structures"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const adminRole = "Admin"
+
+// runCreateAdmin creates an admin, an existing user is made admin and keeps
+// the password. Without -password it is read from CULINA_ADMIN_PASSWORD to
+// keep it out of the shell history.
+//
+//	CONFIG_PATH=./config/config.yaml culina create-admin -username admin -email admin@example.com
+func runCreateAdmin(cfg *config.Config, log *slog.Logger, args []string) int {
+	flags := flag.NewFlagSet("create-admin", flag.ContinueOnError)
+	username := flags.String("username", "", "username of the admin")
+	email := flags.String("email", "", "email of a new admin")
+	password := flags.String("password", "", "password of a new admin, CULINA_ADMIN_PASSWORD by default")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	// the env var is not the flag default, so usage never prints it
+	if *password == "" {
+		*password = os.Getenv("CULINA_ADMIN_PASSWORD")
+	}
+	if *username == "" {
+		fmt.Fprintln(os.Stderr, "-username is required")
+		return 2
+	}
+
+	db, err := postgres.InitDataBase(cfg.Database, log)
+	if err != nil {
+		log.Error("Error connecting to database", sl.Err(err))
+		return 1
+	}
+	defer db.Close()
+
+	userRepo := &postgres.PostgresUserRepository{DB: db}
+	uow := &postgres.PostgresUnitOfWork{DB: db, Log: log}
+	ctx := context.Background()
+
+	user, err := userRepo.SelectUser(ctx, *username, log)
+	if err != nil {
+		return 1
+	}
+
+	if user == nil {
+		if *email == "" || *password == "" {
+			fmt.Fprintln(os.Stderr, "-email and -password are required for a new user")
+			return 2
+		}
+
+		hash, err := bcrypt.GenerateFromPassword([]byte(*password), bcrypt.DefaultCost)
+		if err != nil {
+			log.Error("Error with generating hash", sl.Err(err))
+			return 1
+		}
+
+		user = &structures.User{Email: *email, Username: *username, Password: string(hash)}
+	}
+
+	// a new user is not left behind without the role
+	err = uow.Do(ctx, func(ctx context.Context) error {
+		if user.Id == 0 {
+			var err error
+			user.Id, err = userRepo.InsertUser(ctx, user, log)
+			if err != nil {
+				log.Error("Error with inserting user", sl.Err(err))
+				return err
+			}
+		}
+		return userRepo.UpdateUserRole(ctx, user.Id, adminRole, log)
+	})
+	if err != nil {
+		return 1
+	}
+
+	log.Info("User is admin", slog.Int("id", user.Id), slog.String("username", user.Username))
+	return 0
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"flag"
+	"io"
+	"log/slog"
+	"os"
+
+	"github.com/qwaq-dev/culina/internal/repository/postgres"
+	"github.com/qwaq-dev/culina/pkg/config"
+	"github.com/qwaq-dev/culina/pkg/logger/sl"
+)
+
+const exportBatchSize = 500
+
+// runExport writes published recipes as json lines, seed reads them back.
+//
+//	CONFIG_PATH=./config/config.yaml culina export -out recipes.jsonl
+func runExport(cfg *config.Config, log *slog.Logger, args []string) int {
+	flags := flag.NewFlagSet("export", flag.ContinueOnError)
+	out := flags.String("out", "", "output file, stdout by default")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	var w io.Writer = os.Stdout
+	if *out != "" {
+		f, err := os.Create(*out)
+		if err != nil {
+			log.Error("Error with creating export file", sl.Err(err))
+			return 1
+		}
+		defer f.Close()
+		w = f
+	}
+
+	db, err := postgres.InitDataBase(cfg.Database, log)
+	if err != nil {
+		log.Error("Error connecting to database", sl.Err(err))
+		return 1
+	}
+	defer db.Close()
+
+	dashboardRepo := &postgres.PostgresDashboardRepository{DB: db}
+	ctx := context.Background()
+
+	buf := bufio.NewWriter(w)
+	encoder := json.NewEncoder(buf)
+	exported, afterId := 0, 0
+	for {
+		recipes, err := dashboardRepo.SelectRecipesForIndex(ctx, afterId, exportBatchSize, log)
+		if err != nil {
+			return 1
+		}
+		if len(recipes) == 0 {
+			break
+		}
+
+		for _, recipe := range recipes {
+			if err := encoder.Encode(recipe); err != nil {
+				log.Error("Error with writing recipe", slog.Int("id", recipe.Id), sl.Err(err))
+				return 1
+			}
+		}
+		exported += len(recipes)
+		afterId = recipes[len(recipes)-1].Id
+	}
+
+	if err := buf.Flush(); err != nil {
+		log.Error("Error with writing export", sl.Err(err))
+		return 1
+	}
+
+	log.Info("Recipes were exported", slog.Int("recipes", exported))
+	return 0
+}
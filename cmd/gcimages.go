@@ -0,0 +1,55 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"log/slog"
+	"os"
+
+	"github.com/qwaq-dev/culina/internal/repository/postgres"
+	"github.com/qwaq-dev/culina/internal/repository/storage"
+	"github.com/qwaq-dev/culina/internal/service/imagegc"
+	"github.com/qwaq-dev/culina/pkg/config"
+	"github.com/qwaq-dev/culina/pkg/logger/sl"
+)
+
+// runGCImages removes uploaded images no recipe or review uses and prints the report.
+//
+//	CONFIG_PATH=./config/config.yaml culina gc-images -dry-run
+func runGCImages(cfg *config.Config, log *slog.Logger, args []string) int {
+	flags := flag.NewFlagSet("gc-images", flag.ContinueOnError)
+	dryRun := flags.Bool("dry-run", false, "only report files that would be removed")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	db, err := postgres.InitDataBase(cfg.Database, log)
+	if err != nil {
+		log.Error("Error connecting to database", sl.Err(err))
+		return 1
+	}
+	defer db.Close()
+
+	store, err := storage.New(cfg.Storage)
+	if err != nil {
+		log.Error("Error with connecting to storage", sl.Err(err))
+		return 1
+	}
+
+	imageRepo := &postgres.PostgresImageRepository{DB: db}
+	report, err := imagegc.New(imageRepo, store, cfg.Images.GCGracePeriod, log).Run(context.Background(), *dryRun)
+	if err != nil {
+		log.Error("Error with images gc", sl.Err(err))
+		return 1
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	encoder.Encode(report)
+
+	if len(report.Failed) > 0 {
+		return 1
+	}
+	return 0
+}
@@ -1,16 +1,20 @@
+// Command culina is the Culina server and its maintenance tools.
+//
+//	CONFIG_PATH=./config/config.yaml culina <command> [flags]
+//
+// Without a command the server is started.
 package main
 
 import (
+	"fmt"
+	"io"
 	"log/slog"
 	"os"
+	"strings"
 
-	"github.com/gofiber/fiber/v2"
-	"github.com/qwaq-dev/culina/internal/repository/postgres"
-	"github.com/qwaq-dev/culina/internal/repository/typesense"
-	"github.com/qwaq-dev/culina/internal/routes"
 	"github.com/qwaq-dev/culina/pkg/config"
 	"github.com/qwaq-dev/culina/pkg/logger/handlers/slogpretty"
-	"github.com/qwaq-dev/culina/pkg/logger/sl"
+	"github.com/qwaq-dev/culina/pkg/requestctx"
 )
 
 const (
@@ -18,59 +22,91 @@ const (
 	envProd = "prod"
 )
 
-func main() {
-	app := fiber.New()
-	cfg := config.MustLoad()
-	log := setupLoger(cfg.Env)
+// command runs with the loaded config and returns the exit code.
+type command struct {
+	name    string
+	summary string
+	run     func(cfg *config.Config, log *slog.Logger, args []string) int
+}
 
-	db, err := postgres.InitDataBase(cfg.Database, log)
-	if err != nil {
-		log.Error("Error connecting to database", slog.String("error", err.Error()))
-		os.Exit(1)
+var commands = []command{
+	{"serve", "start the http server", runServe},
+	{"migrate", "apply or revert database migrations", runMigrate},
+	{"reindex", "rebuild the search index", runReindex},
+	{"seed", "load demo recipes from a fixture file", runSeed},
+	{"create-admin", "create an admin or make a user admin", runCreateAdmin},
+	{"gc-images", "remove uploaded images nothing uses", runGCImages},
+	{"export", "write published recipes as json lines", runExport},
+}
+
+func main() {
+	name, args := "serve", os.Args[1:]
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		name, args = args[0], args[1:]
 	}
 
-	userRepo := &postgres.PostgresUserRepository{DB: db}
-	profileRepo := &postgres.PostgresProfileRepository{DB: db}
-	dashboardRepo := &postgres.PostgresDashboardRepository{DB: db}
-	ts := typesense.NewTypesense(*dashboardRepo, log, cfg.Typesense)
+	cmd, ok := findCommand(name)
+	if !ok {
+		usage(os.Stderr)
+		if name == "help" {
+			os.Exit(0)
+		}
+		os.Exit(2)
+	}
 
-	if err := ts.ConnectToTypesense(); err != nil {
-		log.Error("Error with connecting to typesense", sl.Err(err))
-	} else {
-		log.Info("Successful connect to typesense")
+	cfg := config.MustLoad()
+	// the server logs to stdout, tools keep it for their output
+	out := os.Stderr
+	if cmd.name == "serve" {
+		out = os.Stdout
 	}
+	log := setupLoger(cfg.Env, out)
 
-	dashboardRepo.StartReviewWorker(log)
+	os.Exit(cmd.run(cfg, log, args))
+}
 
-	routes.InitRoutes(app, log, userRepo, profileRepo, dashboardRepo, *ts)
+func findCommand(name string) (command, bool) {
+	for _, cmd := range commands {
+		if cmd.name == name {
+			return cmd, true
+		}
+	}
+	return command{}, false
+}
 
-	log.Info("Server started", slog.String("port", cfg.Server.Port))
-	app.Listen(cfg.Server.Port)
+func usage(w io.Writer) {
+	fmt.Fprintln(w, "usage: culina <command> [flags]")
+	fmt.Fprintln(w)
+	for _, cmd := range commands {
+		fmt.Fprintf(w, "  %-14s %s\n", cmd.name, cmd.summary)
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, `Run "culina <command> -h" for the flags of a command.`)
 }
 
-func setupLoger(env string) *slog.Logger {
+func setupLoger(env string, w io.Writer) *slog.Logger {
 	var log *slog.Logger
 
 	switch env {
 	case envDev:
-		log = setupPrettySlog()
+		log = setupPrettySlog(w)
 	case envProd:
-		log = slog.New(
-			slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelInfo}),
-		)
+		log = slog.New(requestctx.NewHandler(
+			slog.NewJSONHandler(w, &slog.HandlerOptions{Level: slog.LevelInfo}),
+		))
 	}
 
 	return log
 }
 
-func setupPrettySlog() *slog.Logger {
+func setupPrettySlog(w io.Writer) *slog.Logger {
 	opts := slogpretty.PrettyHandlerOptions{
 		SlogOpts: &slog.HandlerOptions{
 			Level: slog.LevelDebug,
 		},
 	}
 
-	handler := opts.NewPrettyHandler(os.Stdout)
+	handler := opts.NewPrettyHandler(w)
 
-	return slog.New(handler)
+	return slog.New(requestctx.NewHandler(handler))
 }
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"math"
+	"os"
+	"strconv"
+
+	"github.com/qwaq-dev/culina/internal/repository/migrate"
+	"github.com/qwaq-dev/culina/internal/repository/postgres"
+	"github.com/qwaq-dev/culina/internal/repository/schema"
+	"github.com/qwaq-dev/culina/pkg/config"
+	"github.com/qwaq-dev/culina/pkg/logger/sl"
+)
+
+const migrateUsage = `usage: culina migrate <command>
+
+  up          apply all pending migrations
+  down [n]    revert the last n migrations, 1 by default, "all" reverts everything
+  status      print the applied version and pending migrations
+  force <v>   set the version without running migrations, 0 clears it
+`
+
+// runMigrate runs the migrate subcommand and returns the exit code.
+//
+//	CONFIG_PATH=./config/config.yaml culina migrate up
+func runMigrate(cfg *config.Config, log *slog.Logger, args []string) int {
+	if len(args) == 0 {
+		fmt.Fprint(os.Stderr, migrateUsage)
+		return 2
+	}
+
+	db, err := postgres.InitDataBase(cfg.Database, log)
+	if err != nil {
+		log.Error("Error connecting to database", sl.Err(err))
+		return 1
+	}
+	defer db.Close()
+
+	migrator, err := migrate.New(db, schema.Migrations, log)
+	if err != nil {
+		log.Error("Error with reading migrations", sl.Err(err))
+		return 1
+	}
+
+	ctx := context.Background()
+	switch args[0] {
+	case "up":
+		applied, err := migrator.Up(ctx)
+		if err != nil {
+			log.Error("Error with applying migrations", sl.Err(err))
+			return 1
+		}
+		fmt.Printf("%d migrations applied\n", applied)
+
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			steps, err = parseSteps(args[1])
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "invalid number of migrations %q\n", args[1])
+				return 2
+			}
+		}
+		reverted, err := migrator.Down(ctx, steps)
+		if err != nil {
+			log.Error("Error with reverting migrations", sl.Err(err))
+			return 1
+		}
+		fmt.Printf("%d migrations reverted\n", reverted)
+
+	case "status":
+		status, err := migrator.Status(ctx)
+		if err != nil {
+			log.Error("Error with reading migrations status", sl.Err(err))
+			return 1
+		}
+		printStatus(status)
+
+	case "force":
+		if len(args) < 2 {
+			fmt.Fprint(os.Stderr, migrateUsage)
+			return 2
+		}
+		version, err := strconv.ParseInt(args[1], 10, 64)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "invalid version %q\n", args[1])
+			return 2
+		}
+		if err := migrator.Force(ctx, version); err != nil {
+			log.Error("Error with forcing migration version", sl.Err(err))
+			return 1
+		}
+		fmt.Printf("version is set to %d\n", version)
+
+	default:
+		fmt.Fprint(os.Stderr, migrateUsage)
+		return 2
+	}
+
+	return 0
+}
+
+func parseSteps(value string) (int, error) {
+	if value == "all" {
+		return math.MaxInt, nil
+	}
+
+	steps, err := strconv.Atoi(value)
+	if err == nil && steps < 1 {
+		err = fmt.Errorf("steps must be positive")
+	}
+	return steps, err
+}
+
+func printStatus(status migrate.Status) {
+	dirty := ""
+	if status.Dirty {
+		dirty = " (dirty)"
+	}
+	fmt.Printf("version: %d%s\n", status.Version, dirty)
+
+	for _, migration := range status.Migrations {
+		state := "pending"
+		if migration.Version <= status.Version {
+			state = "applied"
+		}
+		fmt.Printf("  %06d_%s\t%s\n", migration.Version, migration.Name, state)
+	}
+}
+
+// autoMigrate applies pending migrations on startup when it is enabled.
+func autoMigrate(db *sql.DB, log *slog.Logger) error {
+	migrator, err := migrate.New(db, schema.Migrations, log)
+	if err != nil {
+		return err
+	}
+
+	applied, err := migrator.Up(context.Background())
+	if err != nil {
+		return err
+	}
+
+	log.Info("Database is migrated", slog.Int("applied", applied))
+	return nil
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"flag"
+	"log/slog"
+
+	"github.com/qwaq-dev/culina/internal/repository/postgres"
+	"github.com/qwaq-dev/culina/internal/repository/typesense"
+	"github.com/qwaq-dev/culina/pkg/config"
+	"github.com/qwaq-dev/culina/pkg/logger/sl"
+)
+
+// runReindex rebuilds the Typesense collection from the database, searches
+// keep using the old collection until the new one is ready.
+//
+//	CONFIG_PATH=./config/config.yaml culina reindex
+func runReindex(cfg *config.Config, log *slog.Logger, args []string) int {
+	flags := flag.NewFlagSet("reindex", flag.ContinueOnError)
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	if cfg.Search.Backend != "typesense" {
+		// Postgres full-text search is a generated column, it is always up to date
+		log.Info("Search backend has no index to rebuild", slog.String("backend", cfg.Search.Backend))
+		return 0
+	}
+
+	db, err := postgres.InitDataBase(cfg.Database, log)
+	if err != nil {
+		log.Error("Error connecting to database", sl.Err(err))
+		return 1
+	}
+	defer db.Close()
+
+	dashboardRepo := &postgres.PostgresDashboardRepository{DB: db}
+	searchRulesRepo := &postgres.PostgresSearchRulesRepository{DB: db}
+	ts := typesense.NewTypesense(*dashboardRepo, searchRulesRepo, log, cfg.Typesense)
+
+	if err := ts.Reindex(); err != nil {
+		log.Error("Error with rebuilding search index", sl.Err(err))
+		return 1
+	}
+	return 0
+}
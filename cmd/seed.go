@@ -0,0 +1,160 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"maps"
+	"os"
+
+	"github.com/qwaq-dev/culina/internal/repository/postgres"
+	"github.com/qwaq-dev/culina/internal/service/ingredients"
+	"github.com/qwaq-dev/culina/pkg/config"
+	"github.com/qwaq-dev/culina/pkg/logger/sl"
+	"github.com/qwaq-dev/culina/structures"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// runSeed loads recipes from a file of json lines, the format export writes.
+// Recipes are published by the author, who is created when missing. A recipe
+// is skipped when the author already has one with the same name, so seeding
+// again adds only new fixtures.
+//
+//	CONFIG_PATH=./config/config.yaml culina seed -file ./config/fixtures/recipes.jsonl
+func runSeed(cfg *config.Config, log *slog.Logger, args []string) int {
+	flags := flag.NewFlagSet("seed", flag.ContinueOnError)
+	file := flags.String("file", "./config/fixtures/recipes.jsonl", "json lines file with recipes")
+	author := flags.String("author", "culina", "username of the recipes author")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	f, err := os.Open(*file)
+	if err != nil {
+		log.Error("Error with opening fixture file", sl.Err(err))
+		return 1
+	}
+	defer f.Close()
+
+	normalizer, err := ingredients.New(cfg.Search.IngredientSynonymsFile)
+	if err != nil {
+		log.Error("Error with loading ingredient synonyms", sl.Err(err))
+		return 1
+	}
+
+	db, err := postgres.InitDataBase(cfg.Database, log)
+	if err != nil {
+		log.Error("Error connecting to database", sl.Err(err))
+		return 1
+	}
+	defer db.Close()
+
+	userRepo := &postgres.PostgresUserRepository{DB: db}
+	dashboardRepo := &postgres.PostgresDashboardRepository{DB: db}
+	uow := &postgres.PostgresUnitOfWork{DB: db, Log: log}
+
+	// a broken fixture file seeds nothing
+	inserted, skipped := 0, 0
+	err = uow.Do(context.Background(), func(ctx context.Context) error {
+		authorId, err := seedAuthor(ctx, userRepo, *author, log)
+		if err != nil {
+			log.Error("Error with creating recipes author", sl.Err(err))
+			return err
+		}
+
+		names, err := dashboardRepo.SelectRecipeNames(ctx, authorId, log)
+		if err != nil {
+			return err
+		}
+		existing := make(map[string]bool, len(names))
+		for _, name := range names {
+			existing[name] = true
+		}
+
+		decoder := json.NewDecoder(f)
+		for {
+			var recipe structures.Recipes
+			err := decoder.Decode(&recipe)
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			if err != nil {
+				log.Error("Error with reading fixture file", slog.Int("recipe", inserted+skipped+1), sl.Err(err))
+				return err
+			}
+
+			if existing[recipe.Name] {
+				skipped++
+				continue
+			}
+			existing[recipe.Name] = true
+
+			if _, err := dashboardRepo.InsertRecipe(ctx, seedRecipe(recipe, authorId, normalizer), log); err != nil {
+				return err
+			}
+			inserted++
+		}
+	})
+	if err != nil {
+		return 1
+	}
+
+	log.Info("Recipes were seeded", slog.Int("recipes", inserted), slog.Int("skipped", skipped), slog.String("author", *author))
+	return 0
+}
+
+// seedAuthor returns the id of the user, a missing user is created with
+// a random password, nobody can sign in as it.
+func seedAuthor(ctx context.Context, repo *postgres.PostgresUserRepository, username string, log *slog.Logger) (int, error) {
+	user, err := repo.SelectUser(ctx, username, log)
+	if err != nil {
+		return 0, err
+	}
+	if user != nil {
+		return user.Id, nil
+	}
+
+	password := make([]byte, 32)
+	rand.Read(password)
+	hash, err := bcrypt.GenerateFromPassword(password, bcrypt.DefaultCost)
+	if err != nil {
+		return 0, err
+	}
+
+	return repo.InsertUser(ctx, &structures.User{
+		Email:    fmt.Sprintf("%s@culina.local", username),
+		Username: username,
+		Password: string(hash),
+	}, log)
+}
+
+// seedRecipe prepares a fixture recipe the way CreateRecipe prepares a new one.
+func seedRecipe(recipe structures.Recipes, authorId int, normalizer *ingredients.Normalizer) structures.Recipes {
+	allIngredients := maps.Clone(recipe.Ingredients)
+	if allIngredients == nil {
+		allIngredients = map[string]string{}
+	}
+	for lang, t := range recipe.Translations {
+		for key, ingredient := range t.Ingredients {
+			allIngredients[lang+":"+key] = ingredient
+		}
+	}
+
+	if recipe.Lang == "" {
+		recipe.Lang = structures.DefaultLanguage
+	}
+	recipe.Id = 0
+	recipe.AuthorID = authorId
+	recipe.Draft = false
+	recipe.Ingredient_names = normalizer.Names(allIngredients)
+	// images belong to the store of the exported server
+	recipe.Imgs = map[string]string{}
+	recipe.Img_variants = nil
+	recipe.Img_placeholders = nil
+	return recipe
+}
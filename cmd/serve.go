@@ -0,0 +1,113 @@
+package main
+
+import (
+	"flag"
+	"log/slog"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/qwaq-dev/culina/internal/handlers"
+	"github.com/qwaq-dev/culina/internal/repository"
+	"github.com/qwaq-dev/culina/internal/repository/postgres"
+	"github.com/qwaq-dev/culina/internal/repository/storage"
+	"github.com/qwaq-dev/culina/internal/repository/typesense"
+	"github.com/qwaq-dev/culina/internal/routes"
+	"github.com/qwaq-dev/culina/internal/service"
+	"github.com/qwaq-dev/culina/internal/service/analytics"
+	"github.com/qwaq-dev/culina/internal/service/imagegc"
+	"github.com/qwaq-dev/culina/internal/service/images"
+	"github.com/qwaq-dev/culina/internal/service/ingredients"
+	"github.com/qwaq-dev/culina/internal/service/search"
+	"github.com/qwaq-dev/culina/internal/service/searchsync"
+	"github.com/qwaq-dev/culina/internal/service/textfilter"
+	"github.com/qwaq-dev/culina/pkg/config"
+	"github.com/qwaq-dev/culina/pkg/logger/sl"
+)
+
+func runServe(cfg *config.Config, log *slog.Logger, args []string) int {
+	flags := flag.NewFlagSet("serve", flag.ContinueOnError)
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	app := fiber.New(fiber.Config{BodyLimit: cfg.Server.BodyLimit})
+	app.Use(handlers.RequestContext(cfg.Server.RequestTimeout))
+
+	db, err := postgres.InitDataBase(cfg.Database, log)
+	if err != nil {
+		log.Error("Error connecting to database", slog.String("error", err.Error()))
+		return 1
+	}
+
+	if cfg.Database.AutoMigrate {
+		if err := autoMigrate(db, log); err != nil {
+			log.Error("Error with migrating database", sl.Err(err))
+			return 1
+		}
+	}
+
+	userRepo := &postgres.PostgresUserRepository{DB: db, Timeouts: cfg.Database.Timeouts}
+	profileRepo := &postgres.PostgresProfileRepository{DB: db, Timeouts: cfg.Database.Timeouts}
+	dashboardRepo := &postgres.PostgresDashboardRepository{DB: db, Timeouts: cfg.Database.Timeouts}
+	uow := &postgres.PostgresUnitOfWork{DB: db, Log: log}
+	commentRepo := &postgres.PostgresCommentRepository{DB: db}
+	moderationRepo := &postgres.PostgresModerationRepository{DB: db, AutoHideReports: cfg.Moderation.AutoHideReports}
+	imageRepo := &postgres.PostgresImageRepository{DB: db}
+	uploadRepo := &postgres.PostgresUploadRepository{DB: db}
+	outboxRepo := &postgres.PostgresOutboxRepository{DB: db}
+	suggestRepo := &postgres.PostgresSuggestRepository{DB: db}
+	searchRulesRepo := &postgres.PostgresSearchRulesRepository{DB: db}
+	analyticsRepo := &postgres.PostgresAnalyticsRepository{DB: db}
+	postgresSearch := &postgres.PostgresSearchIndex{DB: db, Log: log}
+
+	var searchIndex repository.SearchIndex = postgresSearch
+	var suggester repository.Suggester
+	if cfg.Search.Backend == "typesense" {
+		ts := typesense.NewTypesense(*dashboardRepo, searchRulesRepo, log, cfg.Typesense)
+		suggester = ts
+		fallback := search.NewFallback(ts, postgresSearch, log)
+		fallback.Start(cfg.Search.HealthInterval)
+		searchIndex = fallback
+
+		searchsync.NewReconciler(dashboardRepo, outboxRepo, ts, log).Start(cfg.Search.ReconcileInterval)
+	}
+	searchsync.NewRelay(outboxRepo, searchIndex, cfg.Search, log).Start()
+	suggestions := search.NewSuggestions(suggestRepo, suggester, cfg.Search, log)
+	suggestions.Start()
+	recorder := analytics.New(analyticsRepo, cfg.Analytics, log)
+	recorder.Start()
+	recorder.StartCleanup()
+
+	normalizer, err := ingredients.New(cfg.Search.IngredientSynonymsFile)
+	if err != nil {
+		log.Error("Error with loading ingredient synonyms", sl.Err(err))
+		return 1
+	}
+	normalizer.Backfill(dashboardRepo, log)
+
+	filter, err := textfilter.New(cfg.TextFilter, log)
+	if err != nil {
+		log.Error("Error with loading text filter", sl.Err(err))
+		return 1
+	}
+
+	store, err := storage.New(cfg.Storage)
+	if err != nil {
+		log.Error("Error with connecting to storage", sl.Err(err))
+		return 1
+	}
+	uploader := service.NewUploader(store, imageRepo, images.NewProcessor(cfg.Images), cfg.Storage.Timeout, log)
+	signer := images.NewSigner(cfg.Images.SigningKey, cfg.Images.SignedURLTTL)
+	resumable := service.NewResumableUploads(uploadRepo, store, uploader, cfg.Uploads, log)
+
+	imagegc.New(imageRepo, store, cfg.Images.GCGracePeriod, log).Start(cfg.Images.GCInterval)
+	resumable.StartCleanup(cfg.Uploads.CleanupInterval)
+
+	routes.InitRoutes(app, log, userRepo, profileRepo, dashboardRepo, uow, commentRepo, moderationRepo, searchRulesRepo, analyticsRepo, searchIndex, suggestions, normalizer, recorder, filter, uploader, store, signer, resumable)
+
+	log.Info("Server started", slog.String("port", cfg.Server.Port))
+	if err := app.Listen(cfg.Server.Port); err != nil {
+		log.Error("Error with starting server", sl.Err(err))
+		return 1
+	}
+	return 0
+}
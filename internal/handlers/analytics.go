@@ -0,0 +1,101 @@
+package handlers
+
+import (
+	"errors"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/qwaq-dev/culina/internal/repository"
+	"github.com/qwaq-dev/culina/structures"
+)
+
+// defaultAnalyticsPeriod is used when the report has no from parameter.
+const defaultAnalyticsPeriod = 7 * 24 * time.Hour
+
+type AnalyticsHandler struct {
+	repo repository.AnalyticsRepository
+	log  *slog.Logger
+}
+
+func NewAnalyticsHandler(repo repository.AnalyticsRepository, log *slog.Logger) *AnalyticsHandler {
+	return &AnalyticsHandler{repo: repo, log: log}
+}
+
+// localhost:8080/admin/search/analytics/top-queries?admin_id=*&from=*&to=*&limit=*
+func (h *AnalyticsHandler) TopQueries(c *fiber.Ctx) error {
+	return h.report(c, func(from, to time.Time, limit int) ([]structures.QueryStats, error) {
+		return h.repo.SelectTopQueries(from, to, limit, h.log)
+	})
+}
+
+// localhost:8080/admin/search/analytics/zero-results?admin_id=*&from=*&to=*&limit=*
+func (h *AnalyticsHandler) ZeroResults(c *fiber.Ctx) error {
+	return h.report(c, func(from, to time.Time, limit int) ([]structures.QueryStats, error) {
+		return h.repo.SelectZeroResultQueries(from, to, limit, h.log)
+	})
+}
+
+// localhost:8080/admin/search/analytics/ctr?admin_id=*&from=*&to=*&limit=*&min_searches=*
+func (h *AnalyticsHandler) Ctr(c *fiber.Ctx) error {
+	minSearches, err := strconv.Atoi(c.Query("min_searches", "10"))
+	if err != nil || minSearches < 1 {
+		minSearches = 10
+	}
+
+	return h.report(c, func(from, to time.Time, limit int) ([]structures.QueryStats, error) {
+		return h.repo.SelectQueryCtr(from, to, minSearches, limit, h.log)
+	})
+}
+
+// report checks the admin and the period shared by all reports. from and to
+// are dates (2006-01-02) or RFC 3339 times, the last week by default.
+func (h *AnalyticsHandler) report(c *fiber.Ctx, selectStats func(from, to time.Time, limit int) ([]structures.QueryStats, error)) error {
+	if err := h.repo.CheckAdmin(c.QueryInt("admin_id"), h.log); err != nil {
+		return analyticsError(c, err)
+	}
+
+	to, err := parseReportTime(c.Query("to"), time.Now())
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid to"})
+	}
+	from, err := parseReportTime(c.Query("from"), to.Add(-defaultAnalyticsPeriod))
+	if err != nil || !from.Before(to) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid from"})
+	}
+
+	limit, err := strconv.Atoi(c.Query("limit", "50"))
+	if err != nil || limit < 1 || limit > 500 {
+		limit = 50
+	}
+
+	stats, err := selectStats(from, to, limit)
+	if err != nil {
+		return analyticsError(c, err)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"from":    from,
+		"to":      to,
+		"queries": stats,
+	})
+}
+
+func parseReportTime(value string, def time.Time) (time.Time, error) {
+	if value == "" {
+		return def, nil
+	}
+	if t, err := time.Parse(time.DateOnly, value); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
+
+func analyticsError(c *fiber.Ctx, err error) error {
+	if errors.Is(err, repository.ErrNotAdmin) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "error with getting search analytics"})
+}
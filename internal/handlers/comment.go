@@ -0,0 +1,176 @@
+package handlers
+
+import (
+	"errors"
+	"log/slog"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/qwaq-dev/culina/internal/repository"
+	"github.com/qwaq-dev/culina/structures"
+)
+
+type CommentHandler struct {
+	repo repository.CommentRepository
+	log  *slog.Logger
+}
+
+func NewCommentHandler(repo repository.CommentRepository, log *slog.Logger) *CommentHandler {
+	return &CommentHandler{repo: repo, log: log}
+}
+
+const maxCommentLength = 2000
+
+/*
+	JSON{
+	    "comment_text": "can I substitute X? @username",
+	    "author_id": 1,
+	    "parent_id": 1 // optional, for replies
+	}
+*/
+func (h *CommentHandler) AddComment(c *fiber.Ctx) error {
+	comment := new(structures.Comment)
+
+	if err := c.BodyParser(comment); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid comment format"})
+	}
+
+	recipeId, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid recipe id"})
+	}
+	comment.Recipe_id = recipeId
+
+	if msg, ok := validateComment(comment); !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": msg})
+	}
+
+	created, err := h.repo.InsertComment(*comment, h.log)
+	if err != nil {
+		return commentError(c, err, "error with adding comment")
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "comment sucessfully add",
+		"comment": created,
+	})
+}
+
+/*
+	JSON{
+	    "comment_text": "text",
+	    "author_id": 1
+	}
+*/
+func (h *CommentHandler) UpdateComment(c *fiber.Ctx) error {
+	comment := new(structures.Comment)
+
+	if err := c.BodyParser(comment); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid comment format"})
+	}
+
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid comment id"})
+	}
+	comment.Id = id
+
+	if msg, ok := validateComment(comment); !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": msg})
+	}
+
+	updated, err := h.repo.UpdateComment(*comment, h.log)
+	if err != nil {
+		return commentError(c, err, "error with updating comment")
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "comment sucessfully updated",
+		"comment": updated,
+	})
+}
+
+/*
+	JSON{
+	    "author_id": 1
+	}
+*/
+func (h *CommentHandler) DeleteComment(c *fiber.Ctx) error {
+	req := struct {
+		AuthorId int `json:"author_id"`
+	}{}
+
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request params"})
+	}
+
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid comment id"})
+	}
+
+	if err := h.repo.DeleteComment(id, req.AuthorId, h.log); err != nil {
+		return commentError(c, err, "error with deleting comment")
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "comment sucessfully deleted",
+	})
+}
+
+// localhost:8080/dashboard/recipe/:id/comments?page=*&pageSize=*
+func (h *CommentHandler) RecipeComments(c *fiber.Ctx) error {
+	recipeId, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid recipe id"})
+	}
+
+	page, err := strconv.Atoi(c.Query("page", "1"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+
+	pageSize, err := strconv.Atoi(c.Query("pageSize", "20"))
+	if err != nil || pageSize < 1 {
+		pageSize = 20
+	}
+
+	comments, err := h.repo.SelectComments(recipeId, page, pageSize, h.log)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Error with getting comments",
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"page":     page,
+		"pageSize": pageSize,
+		"comments": comments,
+	})
+}
+
+func validateComment(comment *structures.Comment) (string, bool) {
+	comment.Text = strings.TrimSpace(comment.Text)
+
+	if comment.Text == "" {
+		return "Comment text is required", false
+	}
+
+	if len([]rune(comment.Text)) > maxCommentLength {
+		return "Comment is too long", false
+	}
+
+	return "", true
+}
+
+func commentError(c *fiber.Ctx, err error, msg string) error {
+	switch {
+	case errors.Is(err, repository.ErrRecipeNotFound), errors.Is(err, repository.ErrCommentNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, repository.ErrCommentEditExpired):
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": msg})
+}
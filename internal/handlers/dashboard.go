@@ -1,30 +1,54 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"log/slog"
-	"os"
+	"maps"
+	"slices"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/qwaq-dev/culina/internal/repository"
-	"github.com/qwaq-dev/culina/internal/repository/typesense"
 	"github.com/qwaq-dev/culina/internal/service"
+	"github.com/qwaq-dev/culina/internal/service/analytics"
+	"github.com/qwaq-dev/culina/internal/service/images"
+	"github.com/qwaq-dev/culina/internal/service/ingredients"
+	"github.com/qwaq-dev/culina/internal/service/search"
+	"github.com/qwaq-dev/culina/internal/service/textfilter"
 	"github.com/qwaq-dev/culina/pkg/logger/sl"
 	"github.com/qwaq-dev/culina/structures"
 )
 
 type DashboardHandler struct {
-	repo repository.DashboardRepository
-	ts   typesense.Typesense
-	log  *slog.Logger
+	repo        repository.DashboardRepository
+	uow         repository.UnitOfWork
+	search      repository.SearchIndex
+	suggestions *search.Suggestions
+	ingredients *ingredients.Normalizer
+	analytics   *analytics.Recorder
+	filter      *textfilter.Pipeline
+	uploader    *service.Uploader
+	signer      *images.Signer
+	log         *slog.Logger
 }
 
-func NewDashboardHandler(repo repository.DashboardRepository, log *slog.Logger, ts typesense.Typesense) *DashboardHandler {
+func NewDashboardHandler(repo repository.DashboardRepository, uow repository.UnitOfWork, log *slog.Logger, search repository.SearchIndex, suggestions *search.Suggestions, ingredients *ingredients.Normalizer, analytics *analytics.Recorder, filter *textfilter.Pipeline, uploader *service.Uploader, signer *images.Signer) *DashboardHandler {
 	return &DashboardHandler{
-		repo: repo,
-		log:  log,
-		ts:   ts,
+		repo:        repo,
+		uow:         uow,
+		log:         log,
+		search:      search,
+		suggestions: suggestions,
+		ingredients: ingredients,
+		analytics:   analytics,
+		filter:      filter,
+		uploader:    uploader,
+		signer:      signer,
 	}
 }
 
@@ -33,11 +57,16 @@ func NewDashboardHandler(repo repository.DashboardRepository, log *slog.Logger,
 		"name":"",
 		"descr":"",
 		"diff":"",
+		"cook_time":"30", // optional, minutes
+		"calories":"450", // optional, kcal per serving
 		"filters":["", ""],
 		"images":"{auto}",
 		"authorid":"from token",
 		"ingredients":{"first":"ingr", },
 		"steps":{"first":"step"},
+		"draft":"true", // optional, drafts are visible only to the author
+		"lang":"ru", // optional, ru|en
+		"translations":{"en":{"name":"", "descr":"", "ingredients":{}, "steps":{}}} // optional
 	}
 */
 func (h *DashboardHandler) CreateRecipe(c *fiber.Ctx) error {
@@ -45,6 +74,16 @@ func (h *DashboardHandler) CreateRecipe(c *fiber.Ctx) error {
 	descr := c.FormValue("descr")
 	diff := c.FormValue("diff")
 	authorId, _ := strconv.Atoi(c.FormValue("authorid"))
+	draft := c.FormValue("draft") == "true"
+
+	cookTime, err := strconv.Atoi(c.FormValue("cook_time", "0"))
+	if err != nil || cookTime < 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid cook_time"})
+	}
+	calories, err := strconv.Atoi(c.FormValue("calories", "0"))
+	if err != nil || calories < 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid calories"})
+	}
 
 	var filters []string
 	ingredients := make(map[string]string)
@@ -68,48 +107,125 @@ func (h *DashboardHandler) CreateRecipe(c *fiber.Ctx) error {
 		})
 	}
 
-	form, err := c.MultipartForm()
-	if err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Invalid form data",
+	lang := c.FormValue("lang", structures.DefaultLanguage)
+	if !slices.Contains(structures.Languages, lang) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Unsupported lang"})
+	}
+
+	translations := make(map[string]structures.RecipeTranslation)
+	if value := c.FormValue("translations"); value != "" {
+		if err := json.Unmarshal([]byte(value), &translations); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid translations format"})
+		}
+	}
+	for translationLang := range translations {
+		if translationLang == lang || !slices.Contains(structures.Languages, translationLang) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Unsupported translation lang " + translationLang})
+		}
+	}
+
+	body := recipeText(name, descr, ingredients, steps)
+	allIngredients := maps.Clone(ingredients)
+	for translationLang, t := range translations {
+		body += "\n" + recipeText(t.Name, t.Descr, t.Ingredients, t.Steps)
+		for key, ingredient := range t.Ingredients {
+			allIngredients[translationLang+":"+key] = ingredient
+		}
+	}
+
+	text := textfilter.Text{
+		AuthorId: authorId,
+		Kind:     structures.TargetRecipe,
+		Body:     body,
+	}
+	verdict := h.filter.Check(text)
+	if verdict.Action == textfilter.Reject {
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{
+			"error":   "Recipe was rejected by content filter",
+			"reasons": verdict.Reasons,
 		})
 	}
 
-	imgs, dirName, err := service.UploadImagesForReceip(form, authorId, c)
+	form, err := c.MultipartForm()
 	if err != nil {
-		h.log.Error("error with directory", sl.Err(err))
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Error with creating directory",
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid form data",
 		})
 	}
 
 	h.log.Info("", slog.Any("authorId", authorId))
 
 	recipe := structures.Recipes{
-		Name:        name,
-		Descr:       descr,
-		Diff:        diff,
-		Filters:     filters,
-		Imgs:        imgs,
-		AuthorID:    authorId,
-		Ingredients: ingredients,
-		Steps:       steps,
+		Name:             name,
+		Descr:            descr,
+		Diff:             diff,
+		Cook_time:        cookTime,
+		Calories:         calories,
+		Filters:          filters,
+		AuthorID:         authorId,
+		Ingredients:      ingredients,
+		Ingredient_names: h.ingredients.Names(allIngredients),
+		Steps:            steps,
+		Draft:            draft,
+		Lang:             lang,
+		Translations:     translations,
+	}
+
+	if verdict.Action == textfilter.Hold {
+		recipe.Hold_reason = verdict.Reason()
 	}
 
-	id, err := h.repo.InsertRecipe(recipe, h.log)
+	// files are stored before the transaction, the ones of a recipe that
+	// wasn't saved are removed
+	ctx, stored := repository.WithCompensations(actingUser(c, authorId))
+	recipe.Imgs, recipe.Img_variants, recipe.Img_placeholders, err = h.uploader.UploadImagesForReceip(ctx, form)
+	if err != nil {
+		stored.Run(ctx, h.log)
+		return uploadError(c, err, h.log)
+	}
+
+	err = h.uow.Do(ctx, func(ctx context.Context) error {
+		id, err := h.repo.InsertRecipe(ctx, recipe, h.log)
+		recipe.Id = id
+		return err
+	})
 	if err != nil {
-		os.Remove(dirName)
 		return c.Status(500).JSON(fiber.Map{"error": "Failed to save recipe"})
 	}
-	h.ts.AddRecipeToTypesense(recipe)
+	h.filter.Record(text)
+
+	if draft {
+		return c.Status(fiber.StatusOK).JSON(fiber.Map{
+			"message": "Recipe was saved as draft",
+			"recipe":  recipe,
+			"urls":    h.signedURLs(recipe),
+		})
+	}
+
+	if verdict.Action == textfilter.Hold {
+		return c.Status(fiber.StatusAccepted).JSON(fiber.Map{
+			"message": "Recipe was sent to moderation",
+			"recipe":  recipe,
+		})
+	}
 
-	recipe.Id = id
 	return c.Status(fiber.StatusOK).JSON(fiber.Map{
 		"message": "Recipe was upload successfully",
 		"recipe":  recipe,
 	})
 }
 
+func recipeText(name, descr string, ingredients, steps map[string]string) string {
+	parts := []string{name, descr}
+	for _, ingredient := range ingredients {
+		parts = append(parts, ingredient)
+	}
+	for _, step := range steps {
+		parts = append(parts, step)
+	}
+	return strings.Join(parts, "\n")
+}
+
 /*
 	JSON{
 	    "review_text": "text",
@@ -117,6 +233,8 @@ func (h *DashboardHandler) CreateRecipe(c *fiber.Ctx) error {
 	    "author_id": 1,
 	    "recipe_id": 1
 	}
+
+or FORM-DATA with the same fields and up to 5 "images"
 */
 func (h *DashboardHandler) AddReview(c *fiber.Ctx) error {
 	review := new(structures.Review)
@@ -128,10 +246,40 @@ func (h *DashboardHandler) AddReview(c *fiber.Ctx) error {
 		})
 	}
 
-	err := h.repo.InsertReview(*review, h.log)
+	if review.Rating_value < 1 || review.Rating_value > 5 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Rating value must be between 1 and 5",
+		})
+	}
+
+	verdict := h.filterReview(review, false)
+	if verdict.Action == textfilter.Reject {
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{
+			"error":   "Review was rejected by content filter",
+			"reasons": verdict.Reasons,
+		})
+	}
+
+	ctx, stored := repository.WithCompensations(actingUser(c, review.Reviewed_by))
+	if form, err := c.MultipartForm(); err == nil {
+		review.Imgs, err = h.uploader.UploadImagesForReview(ctx, form)
+		if err != nil {
+			stored.Run(ctx, h.log)
+			return uploadError(c, err, h.log)
+		}
+	}
+
+	err := h.uow.Do(ctx, func(ctx context.Context) error {
+		return h.repo.InsertReview(ctx, *review, h.log)
+	})
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "error with inserting review",
+		return reviewError(c, err, "error with inserting review")
+	}
+	h.filter.Record(reviewText(review, false))
+
+	if review.Hold_reason != "" {
+		return c.Status(fiber.StatusAccepted).JSON(fiber.Map{
+			"message": "review was sent to moderation",
 		})
 	}
 
@@ -142,44 +290,503 @@ func (h *DashboardHandler) AddReview(c *fiber.Ctx) error {
 
 /*
 	JSON{
-		"filters":["", ""]
+	    "review_text": "text",
+	    "rating_value": 5,
+	    "author_id": 1
+	}
+*/
+func (h *DashboardHandler) UpdateReview(c *fiber.Ctx) error {
+	review := new(structures.Review)
+
+	if err := c.BodyParser(review); err != nil {
+		h.log.Error("Ivalid review format", sl.Err(err))
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid review format",
+		})
+	}
+
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid review id"})
+	}
+	review.Id = id
+
+	if review.Rating_value < 1 || review.Rating_value > 5 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Rating value must be between 1 and 5",
+		})
+	}
+
+	verdict := h.filterReview(review, true)
+	if verdict.Action == textfilter.Reject {
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{
+			"error":   "Review was rejected by content filter",
+			"reasons": verdict.Reasons,
+		})
+	}
+
+	if err := h.repo.UpdateReview(actingUser(c, review.Reviewed_by), *review, h.log); err != nil {
+		return reviewError(c, err, "error with updating review")
+	}
+	h.filter.Record(reviewText(review, true))
+
+	if review.Hold_reason != "" {
+		return c.Status(fiber.StatusAccepted).JSON(fiber.Map{
+			"message": "review was sent to moderation",
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "review sucessfully updated",
+		"review":  review,
+	})
+}
+
+/*
+	JSON{
+	    "author_id": 1
+	}
+*/
+func (h *DashboardHandler) DeleteReview(c *fiber.Ctx) error {
+	req := struct {
+		AuthorId int `json:"author_id"`
+	}{}
+
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request params"})
+	}
+
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid review id"})
+	}
+
+	if err := h.repo.DeleteReview(actingUser(c, req.AuthorId), id, req.AuthorId, h.log); err != nil {
+		return reviewError(c, err, "error with deleting review")
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "review sucessfully deleted",
+	})
+}
+
+/*
+	JSON{
+	    "user_id": 1,
+	    "value": 1 // 1 - helpful, -1 - not helpful, 0 - remove vote
+	}
+*/
+func (h *DashboardHandler) VoteReview(c *fiber.Ctx) error {
+	vote := new(structures.ReviewVote)
+
+	if err := c.BodyParser(vote); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request params"})
+	}
+
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid review id"})
+	}
+	vote.ReviewId = id
+
+	if vote.Value < -1 || vote.Value > 1 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Vote value must be -1, 0 or 1"})
+	}
+
+	review, err := h.repo.VoteReview(actingUser(c, vote.UserId), *vote, h.log)
+	if err != nil {
+		return reviewError(c, err, "error with voting for review")
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"review": review,
+	})
+}
+
+// localhost:8080/dashboard/recipe/:id/reviews?page=*&pageSize=*&sort=helpful|newest|rating
+func (h *DashboardHandler) RecipeReviews(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid recipe id"})
+	}
+
+	page, err := strconv.Atoi(c.Query("page", "1"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+
+	pageSize, err := strconv.Atoi(c.Query("pageSize", "10"))
+	if err != nil || pageSize < 1 {
+		pageSize = 10
+	}
+
+	sort := c.Query("sort", "helpful")
+
+	reviews, err := h.repo.SelectReviewsByRecipeId(c.UserContext(), id, page, pageSize, sort, h.log)
+	if err != nil {
+		h.log.Error("Error with getting reviews", sl.Err(err))
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Error with getting reviews",
+		})
+	}
+
+	distribution, err := h.repo.SelectRatingDistribution(c.UserContext(), id, h.log)
+	if err != nil {
+		h.log.Error("Error with getting rating distribution", sl.Err(err))
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Error with getting reviews",
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"page":                page,
+		"pageSize":            pageSize,
+		"sort":                sort,
+		"reviews":             reviews,
+		"rating_distribution": distribution,
+	})
+}
+
+// localhost:8080/dashboard/recipe/:id/photos?page=*&pageSize=*
+func (h *DashboardHandler) RecipePhotos(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid recipe id"})
+	}
+
+	page, err := strconv.Atoi(c.Query("page", "1"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+
+	pageSize, err := strconv.Atoi(c.Query("pageSize", "12"))
+	if err != nil || pageSize < 1 {
+		pageSize = 12
+	}
+
+	photos, err := h.repo.SelectRecipePhotos(c.UserContext(), id, page, pageSize, h.log)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Error with getting photos",
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"page":     page,
+		"pageSize": pageSize,
+		"photos":   photos,
+	})
+}
+
+/*
+	JSON{
+	    "author_id": 1,
+	    "photo_id": 1 // 0 - unpin
 	}
 */
-func (h *DashboardHandler) Filter(c *fiber.Ctx) error {
+func (h *DashboardHandler) PinPhoto(c *fiber.Ctx) error {
 	req := struct {
-		Filters []string `json:"filters"`
+		AuthorId int `json:"author_id"`
+		PhotoId  int `json:"photo_id"`
 	}{}
 
 	if err := c.BodyParser(&req); err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request params"})
 	}
 
-	recipes, err := h.ts.FilterByTypesense(req.Filters)
+	id, err := strconv.Atoi(c.Params("id"))
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error with filter"})
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid recipe id"})
 	}
 
-	return c.Status(fiber.StatusOK).JSON(fiber.Map{"filtered recipes": recipes})
+	if err := h.repo.PinRecipePhoto(actingUser(c, req.AuthorId), id, req.AuthorId, req.PhotoId, h.log); err != nil {
+		return reviewError(c, err, "error with pinning photo")
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "photo sucessfully pinned",
+	})
+}
+
+// localhost:8080/dashboard/drafts/:authorId
+func (h *DashboardHandler) Drafts(c *fiber.Ctx) error {
+	authorId, err := strconv.Atoi(c.Params("authorId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid author id"})
+	}
+
+	drafts, err := h.repo.SelectDrafts(actingUser(c, authorId), authorId, h.log)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "error with selecting drafts"})
+	}
+
+	urls := make(map[string]string)
+	for _, draft := range drafts {
+		for key, url := range h.signedURLs(draft) {
+			urls[key] = url
+		}
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"drafts": drafts,
+		"urls":   urls,
+	})
+}
+
+/*
+	JSON{
+	    "author_id": 1
+	}
+*/
+func (h *DashboardHandler) PublishRecipe(c *fiber.Ctx) error {
+	req := struct {
+		AuthorId int `json:"author_id"`
+	}{}
+
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request params"})
+	}
+
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid recipe id"})
+	}
+
+	if err := h.repo.PublishRecipe(actingUser(c, req.AuthorId), id, req.AuthorId, h.log); err != nil {
+		return reviewError(c, err, "error with publishing recipe")
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "recipe sucessfully published",
+	})
+}
+
+// signedURLs returns expiring urls for all images of a private recipe,
+// it is empty when signed urls are disabled.
+func (h *DashboardHandler) signedURLs(recipe structures.Recipes) map[string]string {
+	urls := make(map[string]string)
+	if !h.signer.Enabled() {
+		return urls
+	}
+
+	for _, variants := range recipe.Img_variants {
+		for _, key := range variants.Keys() {
+			urls[key] = h.signer.SignURL(key)
+		}
+	}
+
+	return urls
+}
+
+// filterReview runs the review text through the content filter and marks
+// the review for moderation when the filter holds it.
+func (h *DashboardHandler) filterReview(review *structures.Review, edit bool) textfilter.Verdict {
+	verdict := h.filter.Check(reviewText(review, edit))
+
+	if verdict.Action == textfilter.Hold {
+		review.Hold_reason = verdict.Reason()
+	}
+
+	return verdict
+}
+
+func reviewText(review *structures.Review, edit bool) textfilter.Text {
+	return textfilter.Text{
+		AuthorId: review.Reviewed_by,
+		Kind:     structures.TargetReview,
+		Body:     review.Text,
+		Edit:     edit,
+	}
+}
+
+func reviewError(c *fiber.Ctx, err error, msg string) error {
+	switch {
+	case errors.Is(err, repository.ErrRecipeNotFound), errors.Is(err, repository.ErrReviewNotFound),
+		errors.Is(err, repository.ErrPhotoNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, repository.ErrOwnRecipeReview), errors.Is(err, repository.ErrOwnReviewVote),
+		errors.Is(err, repository.ErrNotRecipeAuthor):
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, repository.ErrReviewExists), errors.Is(err, repository.ErrNotDraft),
+		errors.Is(err, repository.ErrTooManyImages):
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": msg})
+}
+
+func uploadError(c *fiber.Ctx, err error, log *slog.Logger) error {
+	switch {
+	case errors.Is(err, images.ErrFileTooLarge):
+		return c.Status(fiber.StatusRequestEntityTooLarge).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, images.ErrUnsupportedType), errors.Is(err, images.ErrImageTooLarge),
+		errors.Is(err, service.ErrTooManyImages), errors.Is(err, service.ErrNoImages):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	log.Error("error with uploading images", sl.Err(err))
+	return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error with uploading images"})
 }
 
 func (h *DashboardHandler) SortBy(c *fiber.Ctx) error {
 	return nil
 }
 
-func (h *DashboardHandler) SearchByTypesense(c *fiber.Ctx) error {
-	searchText := c.Params("query")
+// maxSearchPageSize bounds pageSize of the search.
+const maxSearchPageSize = 100
+
+// localhost:8080/dashboard/search?q=*&filters=a,b&match=all|any&diff=*,*&with=*,*&without=*,*&author=*
+// &min_rating=*&max_time=*&min_calories=*&max_calories=*&page=*&pageSize=*&user_id=*&search_id=*
+// with and without are ingredients, like with=курица,рис&without=грибы, synonyms match too.
+// Only the first page is logged as a search, later pages return the search_id
+// of the first one passed back by the client, so their clicks count for it.
+// Recipes in the Accept-Language language rank first and are returned translated to it.
+func (h *DashboardHandler) Search(c *fiber.Ctx) error {
+	query, err := parseSearchQuery(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	query.Lang = preferredLanguage(c)
+	query.With = h.ingredients.NormalizeAll(query.With)
+	query.Without = h.ingredients.NormalizeAll(query.Without)
 
-	recipes, err := h.ts.SearchWithTypesense(searchText)
+	started := time.Now()
+	result, err := h.search.Search(query)
 	if err != nil {
 		h.log.Error("Error with searching", sl.Err(err))
-		return err
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error with searching"})
+	}
+
+	if query.Page > 1 {
+		if searchId := c.Query("search_id"); len(searchId) <= 32 {
+			result.Search_id = searchId
+		}
+		return c.Status(fiber.StatusOK).JSON(result)
+	}
+
+	result.Search_id = analytics.NewSearchId()
+
+	params := query
+	params.Q = ""
+	h.analytics.LogSearch(structures.SearchLogEntry{
+		Id:         result.Search_id,
+		Query:      search.NormalizeQuery(query.Q),
+		Params:     params,
+		Found:      result.Found,
+		Latency_ms: int(time.Since(started).Milliseconds()),
+		User_id:    c.QueryInt("user_id"),
+	})
+
+	return c.Status(fiber.StatusOK).JSON(result)
+}
+
+/*
+	JSON{
+	    "search_id": "from the search response",
+	    "recipe_id": 1,
+	    "position": 3, // 1 based, across pages
+	    "user_id": 1 // optional
+	}
+*/
+func (h *DashboardHandler) SearchClick(c *fiber.Ctx) error {
+	click := new(structures.SearchClick)
+
+	if err := c.BodyParser(click); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request params"})
+	}
+	if click.Search_id == "" || len(click.Search_id) > 32 || click.Recipe_id < 1 || click.Position < 1 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid click"})
+	}
+
+	h.analytics.LogClick(*click)
+
+	return c.Status(fiber.StatusAccepted).JSON(fiber.Map{
+		"message": "click accepted",
+	})
+}
+
+// localhost:8080/dashboard/suggest?q=*&limit=*
+func (h *DashboardHandler) Suggest(c *fiber.Ctx) error {
+	limit, err := strconv.Atoi(c.Query("limit", "5"))
+	if err != nil || limit < 1 {
+		limit = 5
+	}
+	limit = min(limit, search.MaxSuggestions)
+
+	suggestions, err := h.suggestions.Suggest(c.Query("q"), limit)
+	if err != nil {
+		h.log.Error("Error with getting suggestions", sl.Err(err))
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error with getting suggestions"})
 	}
 
 	return c.Status(fiber.StatusOK).JSON(fiber.Map{
-		"recipes found": recipes,
+		"suggestions": suggestions,
 	})
 }
 
+func parseSearchQuery(c *fiber.Ctx) (structures.SearchQuery, error) {
+	query := structures.SearchQuery{
+		Q:       c.Query("q"),
+		Filters: splitQuery(c.Query("filters")),
+		Match:   c.Query("match", structures.MatchAll),
+		Diff:    splitQuery(c.Query("diff")),
+		With:    splitQuery(c.Query("with")),
+		Without: splitQuery(c.Query("without")),
+	}
+
+	if query.Match != structures.MatchAll && query.Match != structures.MatchAny {
+		return query, errors.New("match must be all or any")
+	}
+
+	ints := []struct {
+		name  string
+		value *int
+		def   int
+	}{
+		{"author", &query.AuthorId, 0},
+		{"max_time", &query.MaxTime, 0},
+		{"min_calories", &query.MinCalories, 0},
+		{"max_calories", &query.MaxCalories, 0},
+		{"page", &query.Page, 1},
+		{"pageSize", &query.PerPage, 10},
+	}
+	for _, param := range ints {
+		value, err := strconv.Atoi(c.Query(param.name, strconv.Itoa(param.def)))
+		if err != nil || value < 0 {
+			return query, fmt.Errorf("invalid %s", param.name)
+		}
+		*param.value = value
+	}
+
+	if query.Page < 1 {
+		query.Page = 1
+	}
+	if query.PerPage < 1 || query.PerPage > maxSearchPageSize {
+		return query, fmt.Errorf("pageSize must be between 1 and %d", maxSearchPageSize)
+	}
+
+	if rating := c.Query("min_rating"); rating != "" {
+		value, err := strconv.ParseFloat(rating, 32)
+		if err != nil || value < 0 || value > 5 {
+			return query, errors.New("invalid min_rating")
+		}
+		query.MinRating = float32(value)
+	}
+
+	return query, nil
+}
+
+// splitQuery splits a comma separated query parameter, skipping empty values.
+func splitQuery(value string) []string {
+	var values []string
+	for _, v := range strings.Split(value, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			values = append(values, v)
+		}
+	}
+	return values
+}
+
 func (h *DashboardHandler) AllRecipes(c *fiber.Ctx) error {
 	page, err := strconv.Atoi(c.Query("page", "1"))
 	if err != nil || page < 1 {
@@ -191,7 +798,7 @@ func (h *DashboardHandler) AllRecipes(c *fiber.Ctx) error {
 		pageSize = 10
 	}
 
-	recipes, err := h.repo.SelectAllRecipes(page, pageSize, h.log)
+	recipes, err := h.repo.SelectAllRecipes(c.UserContext(), page, pageSize, h.log)
 	if err != nil {
 		h.log.Error("Error with getting all recipe", sl.Err(err))
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
@@ -199,6 +806,11 @@ func (h *DashboardHandler) AllRecipes(c *fiber.Ctx) error {
 		})
 	}
 
+	lang := preferredLanguage(c)
+	for i := range recipes {
+		recipes[i] = recipes[i].Localized(lang)
+	}
+
 	return c.Status(fiber.StatusOK).JSON(fiber.Map{
 		"page":     page,
 		"pageSize": pageSize,
@@ -209,7 +821,12 @@ func (h *DashboardHandler) AllRecipes(c *fiber.Ctx) error {
 func (h *DashboardHandler) RecipeById(c *fiber.Ctx) error {
 	id, _ := strconv.Atoi(c.Params("id"))
 
-	recipe, err := h.repo.SelectRecipeById(id, h.log)
+	recipe, err := h.repo.SelectRecipeById(c.UserContext(), id, h.log)
+	if errors.Is(err, repository.ErrRecipeNotFound) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Error with getting recipe by id",
@@ -217,6 +834,15 @@ func (h *DashboardHandler) RecipeById(c *fiber.Ctx) error {
 	}
 
 	return c.Status(fiber.StatusOK).JSON(fiber.Map{
-		"recipe": recipe,
+		"recipe": recipe.Localized(preferredLanguage(c)),
 	})
 }
+
+// preferredLanguage picks one of the recipe languages by the Accept-Language
+// header, the default language without the header.
+func preferredLanguage(c *fiber.Ctx) string {
+	if lang := c.AcceptsLanguages(structures.Languages...); lang != "" {
+		return lang
+	}
+	return structures.DefaultLanguage
+}
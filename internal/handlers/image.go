@@ -0,0 +1,209 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/qwaq-dev/culina/internal/repository"
+	"github.com/qwaq-dev/culina/internal/service/images"
+	"github.com/qwaq-dev/culina/pkg/logger/sl"
+)
+
+const (
+	// names with a content hash never change
+	immutableCacheControl = "public, max-age=31536000, immutable"
+	defaultCacheControl   = "public, max-age=3600"
+)
+
+type ImageHandler struct {
+	store  repository.BlobStore
+	repo   repository.DashboardRepository
+	signer *images.Signer
+	log    *slog.Logger
+}
+
+func NewImageHandler(store repository.BlobStore, repo repository.DashboardRepository, signer *images.Signer, log *slog.Logger) *ImageHandler {
+	return &ImageHandler{store: store, repo: repo, signer: signer, log: log}
+}
+
+// localhost:8080/images/<key>, images used only by drafts, hidden recipes and
+// hidden reviews need ?expires=*&sig=*
+func (h *ImageHandler) Serve(c *fiber.Ctx) error {
+	key, err := url.PathUnescape(c.Params("*"))
+	if err != nil || key == "" {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Image not found"})
+	}
+
+	hash, hashed := images.HashFromKey(key)
+
+	cacheControl := defaultCacheControl
+	if hashed {
+		cacheControl = immutableCacheControl
+	}
+
+	if expiresAt, ok := h.signer.Verify(key, c.Query("expires"), c.Query("sig")); ok {
+		cacheControl = fmt.Sprintf("private, max-age=%d", int(time.Until(expiresAt).Seconds()))
+	} else {
+		private, err := h.repo.IsPrivateImage(c.UserContext(), key, h.log)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error with loading image"})
+		}
+		if private {
+			// don't tell that the image exists
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Image not found"})
+		}
+	}
+
+	if hashed && etagMatches(c.Get(fiber.HeaderIfNoneMatch), `"`+hash+`"`) {
+		c.Set(fiber.HeaderETag, `"`+hash+`"`)
+		c.Set(fiber.HeaderCacheControl, cacheControl)
+		return c.SendStatus(fiber.StatusNotModified)
+	}
+
+	info, err := h.store.Stat(context.Background(), key)
+	if errors.Is(err, repository.ErrBlobNotFound) || errors.Is(err, repository.ErrInvalidKey) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Image not found"})
+	}
+	if err != nil {
+		h.log.Error("Error with getting image", slog.String("key", key), sl.Err(err))
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error with loading image"})
+	}
+
+	// files stored before keys had a hash are told apart by size and time
+	etag := fmt.Sprintf(`"%x-%x"`, info.Size, info.LastModified.UnixNano())
+	if hashed {
+		etag = `"` + hash + `"`
+	}
+
+	contentType := info.ContentType
+	if contentType == "" || contentType == "application/octet-stream" {
+		contentType = h.detectContentType(key, info.Size)
+	}
+
+	c.Set(fiber.HeaderETag, etag)
+	c.Set(fiber.HeaderCacheControl, cacheControl)
+	c.Set(fiber.HeaderAcceptRanges, "bytes")
+	c.Set(fiber.HeaderContentType, contentType)
+	if !info.LastModified.IsZero() {
+		c.Set(fiber.HeaderLastModified, info.LastModified.UTC().Format(http.TimeFormat))
+	}
+
+	if etagMatches(c.Get(fiber.HeaderIfNoneMatch), etag) {
+		return c.SendStatus(fiber.StatusNotModified)
+	}
+
+	// only single ranges are supported, for others the whole image is sent
+	start, end := int64(0), info.Size-1
+	status := fiber.StatusOK
+	rangeHeader := c.Get(fiber.HeaderRange)
+	ifRange := c.Get(fiber.HeaderIfRange)
+	if strings.HasPrefix(rangeHeader, "bytes=") && !strings.Contains(rangeHeader, ",") && (ifRange == "" || ifRange == etag) {
+		var ok bool
+		start, end, ok = parseRange(strings.TrimPrefix(rangeHeader, "bytes="), info.Size)
+		if !ok {
+			c.Set(fiber.HeaderContentRange, fmt.Sprintf("bytes */%d", info.Size))
+			return c.SendStatus(fiber.StatusRequestedRangeNotSatisfiable)
+		}
+
+		c.Set(fiber.HeaderContentRange, fmt.Sprintf("bytes %d-%d/%d", start, end, info.Size))
+		status = fiber.StatusPartialContent
+	}
+
+	if info.Size == 0 {
+		return c.SendStatus(status)
+	}
+
+	// videos are large, the file is streamed and never read into memory.
+	// The stream outlives the handler, so it doesn't use the request context.
+	rc, err := h.store.GetRange(context.Background(), key, start, end)
+	if errors.Is(err, repository.ErrBlobNotFound) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Image not found"})
+	}
+	if err != nil {
+		h.log.Error("Error with reading image", slog.String("key", key), sl.Err(err))
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error with loading image"})
+	}
+
+	// the stream is closed once it is sent
+	return c.Status(status).SendStream(rc, int(end-start+1))
+}
+
+// detectContentType sniffs the beginning of files stored without a type.
+func (h *ImageHandler) detectContentType(key string, size int64) string {
+	if size == 0 {
+		return "application/octet-stream"
+	}
+
+	rc, err := h.store.GetRange(context.Background(), key, 0, min(size, 512)-1)
+	if err != nil {
+		h.log.Error("Error with reading image", slog.String("key", key), sl.Err(err))
+		return "application/octet-stream"
+	}
+	defer rc.Close()
+
+	head, _ := io.ReadAll(rc)
+	return http.DetectContentType(head)
+}
+
+// etagMatches checks the If-None-Match header, weak validators are compared by value.
+func etagMatches(header, etag string) bool {
+	if header == "" {
+		return false
+	}
+	if strings.TrimSpace(header) == "*" {
+		return true
+	}
+
+	for _, tag := range strings.Split(header, ",") {
+		if strings.TrimPrefix(strings.TrimSpace(tag), "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// parseRange parses "start-end", "start-" and "-suffix" ranges, end is inclusive.
+func parseRange(spec string, size int64) (int64, int64, bool) {
+	first, last, found := strings.Cut(strings.TrimSpace(spec), "-")
+	if !found || size == 0 {
+		return 0, 0, false
+	}
+
+	if first == "" {
+		suffix, err := strconv.ParseInt(last, 10, 64)
+		if err != nil || suffix <= 0 {
+			return 0, 0, false
+		}
+		if suffix > size {
+			suffix = size
+		}
+		return size - suffix, size - 1, true
+	}
+
+	start, err := strconv.ParseInt(first, 10, 64)
+	if err != nil || start < 0 || start >= size {
+		return 0, 0, false
+	}
+
+	end := size - 1
+	if last != "" {
+		end, err = strconv.ParseInt(last, 10, 64)
+		if err != nil || end < start {
+			return 0, 0, false
+		}
+		if end >= size {
+			end = size - 1
+		}
+	}
+
+	return start, end, true
+}
@@ -0,0 +1,183 @@
+package handlers
+
+import (
+	"errors"
+	"log/slog"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/qwaq-dev/culina/internal/repository"
+	"github.com/qwaq-dev/culina/structures"
+)
+
+type ModerationHandler struct {
+	repo repository.ModerationRepository
+	log  *slog.Logger
+}
+
+func NewModerationHandler(repo repository.ModerationRepository, log *slog.Logger) *ModerationHandler {
+	return &ModerationHandler{repo: repo, log: log}
+}
+
+/*
+	JSON{
+	    "target_type": "recipe|review|comment|user",
+	    "target_id": 1,
+	    "reporter_id": 1,
+	    "reason": "spam|offensive|harassment|copyright|dangerous|misinformation|other",
+	    "details": "optional text"
+	}
+*/
+func (h *ModerationHandler) Report(c *fiber.Ctx) error {
+	report := new(structures.Report)
+
+	if err := c.BodyParser(report); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid report format"})
+	}
+
+	if !structures.ReportReasons[report.Reason] {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid report reason"})
+	}
+
+	created, hidden, err := h.repo.InsertReport(*report, h.log)
+	if err != nil {
+		return moderationError(c, err, "error with saving report")
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "report sucessfully sent",
+		"report":  created,
+		"hidden":  hidden,
+	})
+}
+
+// localhost:8080/moderation/queue?moderator_id=*&status=open|claimed|resolved&page=*&pageSize=*
+func (h *ModerationHandler) Queue(c *fiber.Ctx) error {
+	moderatorId, err := strconv.Atoi(c.Query("moderator_id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid moderator id"})
+	}
+
+	page, err := strconv.Atoi(c.Query("page", "1"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+
+	pageSize, err := strconv.Atoi(c.Query("pageSize", "20"))
+	if err != nil || pageSize < 1 {
+		pageSize = 20
+	}
+
+	status := c.Query("status", "open")
+
+	reports, err := h.repo.SelectReportQueue(moderatorId, status, page, pageSize, h.log)
+	if err != nil {
+		return moderationError(c, err, "error with getting reports")
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"page":     page,
+		"pageSize": pageSize,
+		"status":   status,
+		"reports":  reports,
+	})
+}
+
+/*
+	JSON{
+	    "moderator_id": 1
+	}
+*/
+func (h *ModerationHandler) Claim(c *fiber.Ctx) error {
+	req := struct {
+		ModeratorId int `json:"moderator_id"`
+	}{}
+
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request params"})
+	}
+
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid report id"})
+	}
+
+	if err := h.repo.ClaimReport(id, req.ModeratorId, h.log); err != nil {
+		return moderationError(c, err, "error with claiming report")
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "report sucessfully claimed",
+	})
+}
+
+/*
+	JSON{
+	    "moderator_id": 1,
+	    "action": "hide|delete|warn|ban|dismiss|approve",
+	    "note": "optional text"
+	}
+*/
+func (h *ModerationHandler) Resolve(c *fiber.Ctx) error {
+	req := struct {
+		ModeratorId int    `json:"moderator_id"`
+		Action      string `json:"action"`
+		Note        string `json:"note"`
+	}{}
+
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request params"})
+	}
+
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid report id"})
+	}
+
+	report, err := h.repo.ResolveReport(id, req.ModeratorId, req.Action, req.Note, h.log)
+	if err != nil {
+		return moderationError(c, err, "error with resolving report")
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "report sucessfully resolved",
+		"report":  report,
+	})
+}
+
+// localhost:8080/moderation/audit?moderator_id=*&target_type=*&target_id=*
+func (h *ModerationHandler) Audit(c *fiber.Ctx) error {
+	moderatorId, err := strconv.Atoi(c.Query("moderator_id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid moderator id"})
+	}
+
+	targetType := c.Query("target_type")
+	targetId := c.QueryInt("target_id")
+
+	actions, err := h.repo.SelectModerationActions(moderatorId, targetType, targetId, h.log)
+	if err != nil {
+		return moderationError(c, err, "error with getting moderation actions")
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"actions": actions,
+	})
+}
+
+func moderationError(c *fiber.Ctx, err error, msg string) error {
+	switch {
+	case errors.Is(err, repository.ErrInvalidTarget), errors.Is(err, repository.ErrReportNotFound),
+		errors.Is(err, repository.ErrReviewNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, repository.ErrInvalidAction):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, repository.ErrNotModerator):
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, repository.ErrReportExists), errors.Is(err, repository.ErrReportClaimed),
+		errors.Is(err, repository.ErrReportResolved):
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": msg})
+}
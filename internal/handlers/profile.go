@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"log/slog"
+	"strconv"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/qwaq-dev/culina/internal/repository"
@@ -32,7 +33,7 @@ func (h *ProfileHandler) ChangeUsername(c *fiber.Ctx) error {
 		return c.Status(400).JSON(fiber.Map{"error": "Invalid request format"})
 	}
 
-	user, err := h.repo.ChangeProfileData("username", req.NewUsername, req.Username, h.log)
+	user, err := h.repo.ChangeProfileData(c.UserContext(), "username", req.NewUsername, req.Username, h.log)
 	if err != nil {
 		h.log.Error("No user with this username", sl.Err(err))
 		return c.Status(500).JSON(fiber.Map{"error": "Error with changing user data"})
@@ -62,7 +63,7 @@ func (h *ProfileHandler) ChangePassword(c *fiber.Ctx) error {
 		return err
 	}
 
-	user, err := h.repo.ChangeProfileData("password", string(hash), req.Username, h.log)
+	user, err := h.repo.ChangeProfileData(c.UserContext(), "password", string(hash), req.Username, h.log)
 	if err != nil {
 		h.log.Error("Error with chaging password", sl.Err(err))
 		return c.Status(500).JSON(fiber.Map{"error": "Error with chaging password"})
@@ -85,7 +86,7 @@ func (h *ProfileHandler) ChangeSex(c *fiber.Ctx) error {
 		c.Status(400).JSON(fiber.Map{"error": "Invalid request format"})
 	}
 
-	user, err := h.repo.ChangeProfileData("sex", req.NewSex, req.Username, h.log)
+	user, err := h.repo.ChangeProfileData(c.UserContext(), "sex", req.NewSex, req.Username, h.log)
 	if err != nil {
 		h.log.Error("Error with changing sex", sl.Err(err))
 		return c.Status(500).JSON(fiber.Map{"error": "error with changing sex"})
@@ -94,6 +95,42 @@ func (h *ProfileHandler) ChangeSex(c *fiber.Ctx) error {
 	return c.Status(200).JSON(fiber.Map{"Sex was updated successfully": user})
 }
 
+// localhost:8080/profile/notifications/:userId?unread=true
+func (h *ProfileHandler) Notifications(c *fiber.Ctx) error {
+	userId, err := strconv.Atoi(c.Params("userId"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid user id"})
+	}
+
+	notifications, err := h.repo.SelectNotifications(actingUser(c, userId), userId, c.QueryBool("unread"), h.log)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Error with getting notifications"})
+	}
+
+	return c.Status(200).JSON(fiber.Map{"notifications": notifications})
+}
+
+//	JSON: {
+//		"user_id": 1,
+//		"ids": [1, 2] // empty - mark all
+//	}
+func (h *ProfileHandler) ReadNotifications(c *fiber.Ctx) error {
+	req := struct {
+		UserId int   `json:"user_id"`
+		Ids    []int `json:"ids"`
+	}{}
+
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid request format"})
+	}
+
+	if err := h.repo.MarkNotificationsRead(actingUser(c, req.UserId), req.UserId, req.Ids, h.log); err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Error with updating notifications"})
+	}
+
+	return c.Status(200).JSON(fiber.Map{"message": "Notifications were marked as read"})
+}
+
 func (h *ProfileHandler) RecipesFromThisAutor(c *fiber.Ctx) error {
 	return nil
 }
@@ -0,0 +1,46 @@
+package handlers
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/qwaq-dev/culina/pkg/requestctx"
+)
+
+// maxRequestIdLength limits ids taken from the X-Request-ID header.
+const maxRequestIdLength = 64
+
+// RequestContext puts the request deadline and id into the user context,
+// handlers pass it to repositories. The id is taken from X-Request-ID or
+// generated, and sent back in the same header.
+func RequestContext(timeout time.Duration) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		id := c.Get(fiber.HeaderXRequestID)
+		if id == "" || len(id) > maxRequestIdLength {
+			id = newRequestId()
+		}
+		c.Set(fiber.HeaderXRequestID, id)
+
+		ctx, cancel := context.WithTimeout(requestctx.WithRequestId(c.UserContext(), id), timeout)
+		defer cancel()
+
+		c.SetUserContext(ctx)
+		return c.Next()
+	}
+}
+
+// actingUser adds the user the request acts for to its context.
+func actingUser(c *fiber.Ctx, userId int) context.Context {
+	ctx := requestctx.WithUser(c.UserContext(), userId)
+	c.SetUserContext(ctx)
+	return ctx
+}
+
+func newRequestId() string {
+	id := make([]byte, 8)
+	rand.Read(id)
+	return hex.EncodeToString(id)
+}
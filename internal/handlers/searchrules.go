@@ -0,0 +1,379 @@
+package handlers
+
+import (
+	"errors"
+	"log/slog"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/qwaq-dev/culina/internal/repository"
+	"github.com/qwaq-dev/culina/pkg/logger/sl"
+	"github.com/qwaq-dev/culina/structures"
+)
+
+// SearchRulesHandler lets admins tune the search. Rules are saved in the
+// database and then pushed to the search index, "synced" in a response is
+// false if the push failed, the rules are pushed again with the next change
+// or reindex.
+type SearchRulesHandler struct {
+	repo   repository.SearchRulesRepository
+	search repository.SearchIndex
+	log    *slog.Logger
+}
+
+func NewSearchRulesHandler(repo repository.SearchRulesRepository, search repository.SearchIndex, log *slog.Logger) *SearchRulesHandler {
+	return &SearchRulesHandler{repo: repo, search: search, log: log}
+}
+
+// localhost:8080/admin/search/synonyms?admin_id=*
+func (h *SearchRulesHandler) Synonyms(c *fiber.Ctx) error {
+	if err := h.repo.CheckAdmin(c.QueryInt("admin_id"), h.log); err != nil {
+		return searchRulesError(c, err, "error with getting synonyms")
+	}
+
+	synonyms, err := h.repo.SelectSynonyms(h.log)
+	if err != nil {
+		return searchRulesError(c, err, "error with getting synonyms")
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"synonyms": synonyms,
+	})
+}
+
+/*
+	JSON{
+	    "admin_id": 1,
+	    "root": "optional, makes the synonym one-way",
+	    "synonyms": ["", ""]
+	}
+*/
+func (h *SearchRulesHandler) AddSynonym(c *fiber.Ctx) error {
+	synonym, adminId, err := parseSynonym(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	if err := h.repo.CheckAdmin(adminId, h.log); err != nil {
+		return searchRulesError(c, err, "error with saving synonym")
+	}
+
+	created, err := h.repo.InsertSynonym(synonym, h.log)
+	if err != nil {
+		return searchRulesError(c, err, "error with saving synonym")
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"synonym": created,
+		"synced":  h.sync(),
+	})
+}
+
+// Same JSON as AddSynonym.
+func (h *SearchRulesHandler) UpdateSynonym(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid synonym id"})
+	}
+
+	synonym, adminId, err := parseSynonym(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	if err := h.repo.CheckAdmin(adminId, h.log); err != nil {
+		return searchRulesError(c, err, "error with updating synonym")
+	}
+
+	synonym.Id = id
+	updated, err := h.repo.UpdateSynonym(synonym, h.log)
+	if err != nil {
+		return searchRulesError(c, err, "error with updating synonym")
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"synonym": updated,
+		"synced":  h.sync(),
+	})
+}
+
+/*
+	JSON{
+	    "admin_id": 1
+	}
+*/
+func (h *SearchRulesHandler) DeleteSynonym(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid synonym id"})
+	}
+
+	adminId, err := parseAdminId(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request params"})
+	}
+	if err := h.repo.CheckAdmin(adminId, h.log); err != nil {
+		return searchRulesError(c, err, "error with deleting synonym")
+	}
+
+	if err := h.repo.DeleteSynonym(id, h.log); err != nil {
+		return searchRulesError(c, err, "error with deleting synonym")
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "synonym successfully deleted",
+		"synced":  h.sync(),
+	})
+}
+
+// localhost:8080/admin/search/overrides?admin_id=*
+func (h *SearchRulesHandler) Overrides(c *fiber.Ctx) error {
+	if err := h.repo.CheckAdmin(c.QueryInt("admin_id"), h.log); err != nil {
+		return searchRulesError(c, err, "error with getting overrides")
+	}
+
+	overrides, err := h.repo.SelectOverrides(h.log)
+	if err != nil {
+		return searchRulesError(c, err, "error with getting overrides")
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"overrides": overrides,
+	})
+}
+
+/*
+	JSON{
+	    "admin_id": 1,
+	    "query": "борщ",
+	    "match": "exact|contains",
+	    "pinned": [12, 7], // recipe ids, shown first in this order
+	    "hidden": [3]      // recipe ids, never shown
+	}
+*/
+func (h *SearchRulesHandler) AddOverride(c *fiber.Ctx) error {
+	override, adminId, err := parseOverride(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	if err := h.repo.CheckAdmin(adminId, h.log); err != nil {
+		return searchRulesError(c, err, "error with saving override")
+	}
+
+	created, err := h.repo.InsertOverride(override, h.log)
+	if err != nil {
+		return searchRulesError(c, err, "error with saving override")
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"override": created,
+		"synced":   h.sync(),
+	})
+}
+
+// Same JSON as AddOverride.
+func (h *SearchRulesHandler) UpdateOverride(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid override id"})
+	}
+
+	override, adminId, err := parseOverride(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	if err := h.repo.CheckAdmin(adminId, h.log); err != nil {
+		return searchRulesError(c, err, "error with updating override")
+	}
+
+	override.Id = id
+	updated, err := h.repo.UpdateOverride(override, h.log)
+	if err != nil {
+		return searchRulesError(c, err, "error with updating override")
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"override": updated,
+		"synced":   h.sync(),
+	})
+}
+
+/*
+	JSON{
+	    "admin_id": 1
+	}
+*/
+func (h *SearchRulesHandler) DeleteOverride(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid override id"})
+	}
+
+	adminId, err := parseAdminId(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request params"})
+	}
+	if err := h.repo.CheckAdmin(adminId, h.log); err != nil {
+		return searchRulesError(c, err, "error with deleting override")
+	}
+
+	if err := h.repo.DeleteOverride(id, h.log); err != nil {
+		return searchRulesError(c, err, "error with deleting override")
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "override successfully deleted",
+		"synced":  h.sync(),
+	})
+}
+
+// localhost:8080/admin/search/stopwords?admin_id=*
+func (h *SearchRulesHandler) Stopwords(c *fiber.Ctx) error {
+	if err := h.repo.CheckAdmin(c.QueryInt("admin_id"), h.log); err != nil {
+		return searchRulesError(c, err, "error with getting stop words")
+	}
+
+	words, err := h.repo.SelectStopwords(h.log)
+	if err != nil {
+		return searchRulesError(c, err, "error with getting stop words")
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"stopwords": words,
+	})
+}
+
+/*
+	JSON{
+	    "admin_id": 1,
+	    "stopwords": ["рецепт", "как", "приготовить"] // replaces all stop words
+	}
+*/
+func (h *SearchRulesHandler) ReplaceStopwords(c *fiber.Ctx) error {
+	req := struct {
+		AdminId   int      `json:"admin_id"`
+		Stopwords []string `json:"stopwords"`
+	}{}
+
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request params"})
+	}
+	if err := h.repo.CheckAdmin(req.AdminId, h.log); err != nil {
+		return searchRulesError(c, err, "error with saving stop words")
+	}
+
+	words := cleanWords(req.Stopwords)
+	if err := h.repo.ReplaceStopwords(words, req.AdminId, h.log); err != nil {
+		return searchRulesError(c, err, "error with saving stop words")
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"stopwords": words,
+		"synced":    h.sync(),
+	})
+}
+
+// sync pushes the rules to the search index and reports whether it worked.
+func (h *SearchRulesHandler) sync() bool {
+	if err := h.search.SyncRules(); err != nil {
+		h.log.Error("Error with pushing search rules", sl.Err(err))
+		return false
+	}
+	return true
+}
+
+func parseAdminId(c *fiber.Ctx) (int, error) {
+	req := struct {
+		AdminId int `json:"admin_id"`
+	}{}
+
+	err := c.BodyParser(&req)
+	return req.AdminId, err
+}
+
+func parseSynonym(c *fiber.Ctx) (structures.SearchSynonym, int, error) {
+	req := struct {
+		AdminId  int      `json:"admin_id"`
+		Root     string   `json:"root"`
+		Synonyms []string `json:"synonyms"`
+	}{}
+
+	if err := c.BodyParser(&req); err != nil {
+		return structures.SearchSynonym{}, 0, errors.New("Invalid request params")
+	}
+
+	synonym := structures.SearchSynonym{
+		Root:       strings.TrimSpace(req.Root),
+		Synonyms:   cleanWords(req.Synonyms),
+		Updated_by: req.AdminId,
+	}
+	if len(synonym.Synonyms) == 0 || synonym.Root == "" && len(synonym.Synonyms) < 2 {
+		return synonym, req.AdminId, errors.New("Synonym needs a root and a word or at least two words")
+	}
+
+	return synonym, req.AdminId, nil
+}
+
+func parseOverride(c *fiber.Ctx) (structures.SearchOverride, int, error) {
+	req := struct {
+		AdminId int    `json:"admin_id"`
+		Query   string `json:"query"`
+		Match   string `json:"match"`
+		Pinned  []int  `json:"pinned"`
+		Hidden  []int  `json:"hidden"`
+	}{}
+
+	if err := c.BodyParser(&req); err != nil {
+		return structures.SearchOverride{}, 0, errors.New("Invalid request params")
+	}
+
+	override := structures.SearchOverride{
+		Query:      strings.TrimSpace(req.Query),
+		Match:      req.Match,
+		Pinned:     req.Pinned,
+		Hidden:     req.Hidden,
+		Updated_by: req.AdminId,
+	}
+	if override.Match == "" {
+		override.Match = structures.OverrideExact
+	}
+
+	switch {
+	case override.Query == "":
+		return override, req.AdminId, errors.New("Override query is empty")
+	case override.Match != structures.OverrideExact && override.Match != structures.OverrideContains:
+		return override, req.AdminId, errors.New("Override match must be exact or contains")
+	case len(override.Pinned) == 0 && len(override.Hidden) == 0:
+		return override, req.AdminId, errors.New("Override needs pinned or hidden recipes")
+	}
+	for _, id := range override.Pinned {
+		if slices.Contains(override.Hidden, id) {
+			return override, req.AdminId, errors.New("Recipe can't be pinned and hidden")
+		}
+	}
+
+	return override, req.AdminId, nil
+}
+
+// cleanWords trims the words and drops empty and repeated ones.
+func cleanWords(words []string) []string {
+	cleaned := []string{}
+	for _, word := range words {
+		word = strings.ToLower(strings.TrimSpace(word))
+		if word != "" && !slices.Contains(cleaned, word) {
+			cleaned = append(cleaned, word)
+		}
+	}
+	return cleaned
+}
+
+func searchRulesError(c *fiber.Ctx, err error, msg string) error {
+	switch {
+	case errors.Is(err, repository.ErrNotAdmin):
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, repository.ErrSynonymNotFound), errors.Is(err, repository.ErrOverrideNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": msg})
+}
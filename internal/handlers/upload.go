@@ -0,0 +1,214 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/qwaq-dev/culina/internal/repository"
+	"github.com/qwaq-dev/culina/internal/service"
+	"github.com/qwaq-dev/culina/internal/service/images"
+	"github.com/qwaq-dev/culina/pkg/logger/sl"
+	"github.com/qwaq-dev/culina/structures"
+)
+
+// Headers of the chunked upload protocol, named as in tus.
+const (
+	headerUploadOffset   = "Upload-Offset"
+	headerUploadLength   = "Upload-Length"
+	headerUploadChecksum = "Upload-Checksum"
+	headerUploadExpires  = "Upload-Expires"
+)
+
+type UploadHandler struct {
+	uploads *service.ResumableUploads
+	repo    repository.DashboardRepository
+	uow     repository.UnitOfWork
+	log     *slog.Logger
+}
+
+func NewUploadHandler(uploads *service.ResumableUploads, repo repository.DashboardRepository, uow repository.UnitOfWork, log *slog.Logger) *UploadHandler {
+	return &UploadHandler{uploads: uploads, repo: repo, uow: uow, log: log}
+}
+
+/*
+	JSON{
+	    "author_id": 1,
+	    "filename": "video.mp4",
+	    "size": 104857600
+	}
+*/
+func (h *UploadHandler) Create(c *fiber.Ctx) error {
+	req := struct {
+		AuthorId int    `json:"author_id"`
+		Filename string `json:"filename"`
+		Size     int64  `json:"size"`
+	}{}
+
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request params"})
+	}
+
+	session, err := h.uploads.Create(req.AuthorId, req.Filename, req.Size)
+	if err != nil {
+		return uploadSessionError(c, session, err, "error with creating upload", h.log)
+	}
+
+	setUploadHeaders(c, session)
+	c.Location("/uploads/" + session.Id)
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"upload": session,
+	})
+}
+
+// localhost:8080/uploads/:id, HEAD returns only the Upload-* headers
+func (h *UploadHandler) Status(c *fiber.Ctx) error {
+	session, err := h.uploads.Session(c.Params("id"))
+	if err != nil {
+		return uploadSessionError(c, session, err, "error with loading upload", h.log)
+	}
+
+	setUploadHeaders(c, session)
+	c.Set(fiber.HeaderCacheControl, "no-store")
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"upload": session,
+	})
+}
+
+/*
+Upload-Offset: 0
+Upload-Checksum: sha256 <base64> // optional
+BODY: chunk bytes
+*/
+func (h *UploadHandler) WriteChunk(c *fiber.Ctx) error {
+	offset, err := strconv.ParseInt(c.Get(headerUploadOffset), 10, 64)
+	if err != nil || offset < 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid Upload-Offset header"})
+	}
+
+	session, err := h.uploads.WriteChunk(c.Params("id"), offset, c.Body(), c.Get(headerUploadChecksum))
+	if err != nil {
+		return uploadSessionError(c, session, err, "error with saving chunk", h.log)
+	}
+
+	setUploadHeaders(c, session)
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"upload": session,
+	})
+}
+
+/*
+	JSON{
+	    "author_id": 1
+	}
+*/
+func (h *UploadHandler) Cancel(c *fiber.Ctx) error {
+	req := struct {
+		AuthorId int `json:"author_id"`
+	}{}
+
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request params"})
+	}
+
+	session, err := h.uploads.Session(c.Params("id"))
+	if err != nil {
+		return uploadSessionError(c, session, err, "error with loading upload", h.log)
+	}
+	if session.Author_id != req.AuthorId {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": service.ErrNotUploadOwner.Error()})
+	}
+
+	if err := h.uploads.Remove(session.Id); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "error with removing upload"})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "upload sucessfully removed",
+	})
+}
+
+/*
+	JSON{
+	    "author_id": 1,
+	    "upload_id": "..."
+	}
+*/
+func (h *UploadHandler) AttachToRecipe(c *fiber.Ctx) error {
+	req := struct {
+		AuthorId int    `json:"author_id"`
+		UploadId string `json:"upload_id"`
+	}{}
+
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request params"})
+	}
+
+	recipeId, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid recipe id"})
+	}
+
+	// the file is stored before the transaction, a file of media that
+	// can't be attached to the recipe is removed
+	ctx, stored := repository.WithCompensations(actingUser(c, req.AuthorId))
+	media, err := h.uploads.Finish(ctx, req.UploadId, req.AuthorId)
+	if err != nil {
+		stored.Run(ctx, h.log)
+		return uploadSessionError(c, structures.UploadSession{}, err, "error with saving upload", h.log)
+	}
+
+	err = h.uow.Do(ctx, func(ctx context.Context) error {
+		if media.Kind == structures.MediaImage {
+			return h.repo.AddRecipeImage(ctx, recipeId, req.AuthorId, media, service.MaxRecipeImages, h.log)
+		}
+		return h.repo.AddRecipeVideo(ctx, recipeId, req.AuthorId, *media.Video, h.log)
+	})
+	if err != nil {
+		return reviewError(c, err, "error with attaching media")
+	}
+
+	if err := h.uploads.Remove(req.UploadId); err != nil {
+		h.log.Error("Error with removing finished upload", slog.String("id", req.UploadId), sl.Err(err))
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "media sucessfully attached",
+		"media":   media,
+	})
+}
+
+func setUploadHeaders(c *fiber.Ctx, session structures.UploadSession) {
+	c.Set(headerUploadOffset, strconv.FormatInt(session.Offset, 10))
+	c.Set(headerUploadLength, strconv.FormatInt(session.Size, 10))
+	c.Set(headerUploadExpires, session.Expires_at)
+}
+
+func uploadSessionError(c *fiber.Ctx, session structures.UploadSession, err error, msg string, log *slog.Logger) error {
+	switch {
+	case errors.Is(err, repository.ErrUploadNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, repository.ErrOffsetMismatch):
+		// the client continues from the offset the server has
+		setUploadHeaders(c, session)
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": err.Error(), "offset": session.Offset})
+	case errors.Is(err, service.ErrUploadTooLarge), errors.Is(err, service.ErrChunkTooLarge),
+		errors.Is(err, images.ErrFileTooLarge):
+		return c.Status(fiber.StatusRequestEntityTooLarge).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, service.ErrChecksumMismatch), errors.Is(err, service.ErrUnsupportedMedia),
+		errors.Is(err, images.ErrUnsupportedType), errors.Is(err, images.ErrImageTooLarge):
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, service.ErrInvalidChecksum), errors.Is(err, service.ErrUploadIncomplete):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, service.ErrNotUploadOwner):
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	log.Error(msg, sl.Err(err))
+	return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": msg})
+}
@@ -38,7 +38,7 @@ func (h *UserHandler) SignIn(c *fiber.Ctx) error {
 		return c.Status(400).JSON(fiber.Map{"error": "username and password are required"})
 	}
 
-	user, err := h.repo.SelectUser(req.Username, h.log)
+	user, err := h.repo.SelectUser(c.UserContext(), req.Username, h.log)
 	if err != nil {
 		return c.Status(500).JSON(fiber.Map{"error": "Server error"})
 	}
@@ -52,6 +52,10 @@ func (h *UserHandler) SignIn(c *fiber.Ctx) error {
 		return c.Status(401).JSON(fiber.Map{"error": "Invalid password"})
 	}
 
+	if user.Banned {
+		return c.Status(403).JSON(fiber.Map{"error": "User is banned"})
+	}
+
 	h.log.Info("User has signed in", slog.String("username", user.Username))
 	return c.Status(200).JSON(fiber.Map{"message": "Successfully signed in", "user": user})
 }
@@ -75,7 +79,7 @@ func (h *UserHandler) SignUp(c *fiber.Ctx) error {
 	}
 	user.Password = string(hash)
 
-	userExists, _ := h.repo.SelectUser(user.Username, h.log)
+	userExists, _ := h.repo.SelectUser(c.UserContext(), user.Username, h.log)
 	if userExists != nil {
 		if userExists.Username == user.Username {
 			h.log.Info("User already exists")
@@ -83,7 +87,7 @@ func (h *UserHandler) SignUp(c *fiber.Ctx) error {
 		}
 	}
 
-	userId, err := h.repo.InsertUser(user, h.log)
+	userId, err := h.repo.InsertUser(c.UserContext(), user, h.log)
 	if err != nil {
 		h.log.Error("Error with inserting user data into database", sl.Err(err))
 	}
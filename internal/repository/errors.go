@@ -0,0 +1,36 @@
+package repository
+
+import "errors"
+
+var (
+	ErrRecipeNotFound  = errors.New("recipe not found")
+	ErrReviewNotFound  = errors.New("review not found")
+	ErrReviewExists    = errors.New("user already reviewed this recipe")
+	ErrOwnRecipeReview = errors.New("user can't review own recipe")
+	ErrOwnReviewVote   = errors.New("user can't vote for own review")
+	ErrNotRecipeAuthor = errors.New("user is not the recipe author")
+	ErrPhotoNotFound   = errors.New("photo not found")
+	ErrNotDraft        = errors.New("recipe is already published")
+
+	ErrCommentNotFound    = errors.New("comment not found")
+	ErrCommentEditExpired = errors.New("comment edit time is over")
+
+	ErrInvalidTarget  = errors.New("reported content not found")
+	ErrInvalidAction  = errors.New("invalid moderation action")
+	ErrReportExists   = errors.New("user already reported this content")
+	ErrReportNotFound = errors.New("report not found")
+	ErrReportClaimed  = errors.New("report is claimed by another moderator")
+	ErrReportResolved = errors.New("report is already resolved")
+	ErrNotModerator   = errors.New("user is not a moderator")
+
+	ErrBlobNotFound = errors.New("blob not found")
+	ErrInvalidKey   = errors.New("invalid blob key")
+
+	ErrUploadNotFound = errors.New("upload not found or expired")
+	ErrOffsetMismatch = errors.New("upload offset mismatch")
+	ErrTooManyImages  = errors.New("recipe has too many images")
+
+	ErrNotAdmin         = errors.New("user is not an admin")
+	ErrSynonymNotFound  = errors.New("synonym not found")
+	ErrOverrideNotFound = errors.New("override not found")
+)
@@ -0,0 +1,166 @@
+package postgres
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/qwaq-dev/culina/pkg/logger/sl"
+	"github.com/qwaq-dev/culina/structures"
+)
+
+type PostgresAnalyticsRepository struct {
+	DB *sql.DB
+}
+
+// queryStatsQuery aggregates searches of a period by query, with the clicks
+// of every search. The HAVING and ORDER BY clauses are filled in by the caller.
+const queryStatsQuery = `
+	WITH searches AS (
+		SELECT s.query, s.found, c.clicks, c.position
+		FROM search_log s
+		LEFT JOIN LATERAL (
+			SELECT COUNT(*) AS clicks, MIN(position) AS position FROM search_clicks WHERE search_id = s.id
+		) c ON true
+		WHERE s.created_at >= $1 AND s.created_at < $2 AND s.query <> ''
+	)
+	SELECT query, COUNT(*), AVG(found), COUNT(*) FILTER (WHERE found = 0),
+		COUNT(*) FILTER (WHERE clicks > 0), COALESCE(AVG(position), 0)
+	FROM searches
+	GROUP BY query
+	HAVING %s
+	ORDER BY %s
+	LIMIT $3`
+
+func (r *PostgresAnalyticsRepository) CheckAdmin(userId int, log *slog.Logger) error {
+	return checkAdmin(r.DB, userId, log)
+}
+
+func (r *PostgresAnalyticsRepository) InsertSearches(entries []structures.SearchLogEntry, log *slog.Logger) error {
+	if len(entries) == 0 {
+		return nil
+	}
+
+	ids := make([]string, len(entries))
+	queries := make([]string, len(entries))
+	params := make([]string, len(entries))
+	found := make([]int64, len(entries))
+	latency := make([]int64, len(entries))
+	users := make([]int64, len(entries))
+	for i, entry := range entries {
+		paramsJSON, _ := json.Marshal(entry.Params)
+		ids[i], queries[i], params[i] = entry.Id, entry.Query, string(paramsJSON)
+		found[i], latency[i], users[i] = int64(entry.Found), int64(entry.Latency_ms), int64(entry.User_id)
+	}
+
+	_, err := r.DB.Exec(`INSERT INTO search_log (id, query, params, found, latency_ms, user_id)
+			  SELECT id, query, params::jsonb, found, latency_ms, NULLIF(user_id, 0)
+			  FROM unnest($1::text[], $2::text[], $3::text[], $4::int[], $5::int[], $6::int[])
+			  AS e(id, query, params, found, latency_ms, user_id)
+			  ON CONFLICT (id) DO NOTHING`,
+		pq.Array(ids), pq.Array(queries), pq.Array(params), pq.Array(found), pq.Array(latency), pq.Array(users))
+	if err != nil {
+		log.Error("Error with inserting searches", sl.Err(err))
+		return err
+	}
+
+	return nil
+}
+
+func (r *PostgresAnalyticsRepository) InsertClicks(clicks []structures.SearchClick, log *slog.Logger) error {
+	if len(clicks) == 0 {
+		return nil
+	}
+
+	searchIds := make([]string, len(clicks))
+	recipeIds := make([]int64, len(clicks))
+	positions := make([]int64, len(clicks))
+	users := make([]int64, len(clicks))
+	for i, click := range clicks {
+		searchIds[i] = click.Search_id
+		recipeIds[i], positions[i], users[i] = int64(click.Recipe_id), int64(click.Position), int64(click.User_id)
+	}
+
+	_, err := r.DB.Exec(`INSERT INTO search_clicks (search_id, recipe_id, position, user_id)
+			  SELECT search_id, recipe_id, position, NULLIF(user_id, 0)
+			  FROM unnest($1::text[], $2::int[], $3::int[], $4::int[]) AS c(search_id, recipe_id, position, user_id)`,
+		pq.Array(searchIds), pq.Array(recipeIds), pq.Array(positions), pq.Array(users))
+	if err != nil {
+		log.Error("Error with inserting search clicks", sl.Err(err))
+		return err
+	}
+
+	return nil
+}
+
+func (r *PostgresAnalyticsRepository) DeleteSearchesBefore(before time.Time, log *slog.Logger) (int64, error) {
+	tx, err := r.DB.Begin()
+	if err != nil {
+		log.Error("Error with starting transaction", sl.Err(err))
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DELETE FROM search_clicks WHERE created_at < $1", before); err != nil {
+		log.Error("Error with deleting search clicks", sl.Err(err))
+		return 0, err
+	}
+
+	res, err := tx.Exec("DELETE FROM search_log WHERE created_at < $1", before)
+	if err != nil {
+		log.Error("Error with deleting searches", sl.Err(err))
+		return 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Error("Error with committing transaction", sl.Err(err))
+		return 0, err
+	}
+
+	deleted, _ := res.RowsAffected()
+	return deleted, nil
+}
+
+// SelectTopQueries returns the most searched queries.
+func (r *PostgresAnalyticsRepository) SelectTopQueries(from, to time.Time, limit int, log *slog.Logger) ([]structures.QueryStats, error) {
+	return r.selectQueryStats("true", "2 DESC, 1", log, from, to, limit)
+}
+
+// SelectZeroResultQueries returns queries which found nothing, the most frequent first.
+func (r *PostgresAnalyticsRepository) SelectZeroResultQueries(from, to time.Time, limit int, log *slog.Logger) ([]structures.QueryStats, error) {
+	return r.selectQueryStats("COUNT(*) FILTER (WHERE found = 0) > 0", "4 DESC, 2 DESC, 1", log, from, to, limit)
+}
+
+// SelectQueryCtr returns queries searched at least minSearches times, the lowest
+// click-through rate first, those are the queries whose results need work.
+func (r *PostgresAnalyticsRepository) SelectQueryCtr(from, to time.Time, minSearches, limit int, log *slog.Logger) ([]structures.QueryStats, error) {
+	return r.selectQueryStats("COUNT(*) >= $4", "COUNT(*) FILTER (WHERE clicks > 0)::float / COUNT(*), 2 DESC, 1", log, from, to, limit, minSearches)
+}
+
+func (r *PostgresAnalyticsRepository) selectQueryStats(having, order string, log *slog.Logger, args ...any) ([]structures.QueryStats, error) {
+	rows, err := r.DB.Query(fmt.Sprintf(queryStatsQuery, having, order), args...)
+	if err != nil {
+		log.Error("Error with selecting query stats", sl.Err(err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	stats := []structures.QueryStats{}
+	for rows.Next() {
+		var s structures.QueryStats
+		err := rows.Scan(&s.Query, &s.Searches, &s.Avg_found, &s.Zero_results, &s.Clicked, &s.Avg_position)
+		if err != nil {
+			log.Error("Error scanning query stats row", sl.Err(err))
+			return nil, err
+		}
+		if s.Searches > 0 {
+			s.Ctr = float64(s.Clicked) / float64(s.Searches)
+		}
+		stats = append(stats, s)
+	}
+
+	return stats, rows.Err()
+}
@@ -0,0 +1,252 @@
+package postgres
+
+import (
+	"database/sql"
+	"errors"
+	"log/slog"
+	"regexp"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/qwaq-dev/culina/internal/repository"
+	"github.com/qwaq-dev/culina/pkg/logger/sl"
+	"github.com/qwaq-dev/culina/structures"
+)
+
+type PostgresCommentRepository struct {
+	DB *sql.DB
+}
+
+// Comment can be edited only during this time after creation.
+const commentEditWindow = 15 * time.Minute
+
+var mentionRegexp = regexp.MustCompile(`@([\p{L}\p{N}_.\-]+)`)
+
+func (r *PostgresCommentRepository) InsertComment(comment structures.Comment, log *slog.Logger) (structures.Comment, error) {
+	tx, err := r.DB.Begin()
+	if err != nil {
+		log.Error("Error with starting transaction", sl.Err(err))
+		return comment, err
+	}
+	defer tx.Rollback()
+
+	var exists bool
+	err = tx.QueryRow("SELECT EXISTS(SELECT 1 FROM recipes WHERE id = $1 AND hidden_at IS NULL AND NOT draft)", comment.Recipe_id).Scan(&exists)
+	if err != nil {
+		log.Error("Error with checking recipe", sl.Err(err))
+		return comment, err
+	}
+	if !exists {
+		return comment, repository.ErrRecipeNotFound
+	}
+
+	if comment.Parent_id != nil {
+		err = tx.QueryRow(`SELECT EXISTS(SELECT 1 FROM comments
+				WHERE id = $1 AND recipe_id = $2 AND deleted_at IS NULL AND hidden_at IS NULL)`,
+			*comment.Parent_id, comment.Recipe_id).Scan(&exists)
+		if err != nil {
+			log.Error("Error with checking parent comment", sl.Err(err))
+			return comment, err
+		}
+		if !exists {
+			return comment, repository.ErrCommentNotFound
+		}
+	}
+
+	query := `INSERT INTO comments (recipe_id, parent_id, author_id, comment_text)
+			  VALUES ($1, $2, $3, $4)
+			  RETURNING id, created_at,
+				author_id = (SELECT author_id FROM recipes WHERE id = $1),
+				(SELECT username FROM users WHERE id = $3)`
+
+	err = tx.QueryRow(query, comment.Recipe_id, comment.Parent_id, comment.Author_id, comment.Text).
+		Scan(&comment.Id, &comment.Created_at, &comment.Is_recipe_author, &comment.AuthorName)
+	if err != nil {
+		log.Error("Error with inserting comment", sl.Err(err))
+		return comment, err
+	}
+
+	if err := insertMentionNotifications(tx, comment, log); err != nil {
+		return comment, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Error("Error with committing transaction", sl.Err(err))
+		return comment, err
+	}
+
+	log.Info("Comment was added", slog.Int("id", comment.Id), slog.Int("recipe_id", comment.Recipe_id))
+
+	return comment, nil
+}
+
+// UpdateComment changes the comment text if it is still inside the edit window.
+// New mentions get notifications, already notified users are skipped.
+func (r *PostgresCommentRepository) UpdateComment(comment structures.Comment, log *slog.Logger) (structures.Comment, error) {
+	tx, err := r.DB.Begin()
+	if err != nil {
+		log.Error("Error with starting transaction", sl.Err(err))
+		return comment, err
+	}
+	defer tx.Rollback()
+
+	var editable bool
+	err = tx.QueryRow(`SELECT recipe_id, created_at > NOW() - make_interval(secs => $3)
+			  FROM comments
+			  WHERE id = $1 AND author_id = $2 AND deleted_at IS NULL`,
+		comment.Id, comment.Author_id, commentEditWindow.Seconds()).Scan(&comment.Recipe_id, &editable)
+	if errors.Is(err, sql.ErrNoRows) {
+		return comment, repository.ErrCommentNotFound
+	}
+	if err != nil {
+		log.Error("Error with selecting comment", sl.Err(err))
+		return comment, err
+	}
+
+	if !editable {
+		return comment, repository.ErrCommentEditExpired
+	}
+
+	err = tx.QueryRow(`UPDATE comments SET comment_text = $1, updated_at = NOW()
+			  WHERE id = $2
+			  RETURNING parent_id, created_at`, comment.Text, comment.Id).Scan(&comment.Parent_id, &comment.Created_at)
+	if err != nil {
+		log.Error("Error with updating comment", sl.Err(err))
+		return comment, err
+	}
+	comment.Edited = true
+
+	if err := insertMentionNotifications(tx, comment, log); err != nil {
+		return comment, err
+	}
+
+	return comment, tx.Commit()
+}
+
+// DeleteComment only marks the comment as deleted, so the replies stay in the thread.
+func (r *PostgresCommentRepository) DeleteComment(commentId, authorId int, log *slog.Logger) error {
+	res, err := r.DB.Exec(`UPDATE comments SET deleted_at = NOW()
+			  WHERE id = $1 AND author_id = $2 AND deleted_at IS NULL`, commentId, authorId)
+	if err != nil {
+		log.Error("Error with deleting comment", sl.Err(err))
+		return err
+	}
+
+	if affected, _ := res.RowsAffected(); affected == 0 {
+		return repository.ErrCommentNotFound
+	}
+
+	return nil
+}
+
+// SelectComments returns one page of top level comments of the recipe
+// (newest first) with all their replies.
+func (r *PostgresCommentRepository) SelectComments(recipeId, page, pageSize int, log *slog.Logger) ([]structures.Comment, error) {
+	offset := (page - 1) * pageSize
+
+	query := `WITH RECURSIVE thread AS (
+				(SELECT * FROM comments
+				 WHERE recipe_id = $1 AND parent_id IS NULL
+				 ORDER BY created_at DESC, id DESC
+				 LIMIT $2 OFFSET $3)
+				UNION ALL
+				SELECT c.* FROM comments c
+				JOIN thread t ON c.parent_id = t.id
+			  )
+			  SELECT t.id, t.recipe_id, t.parent_id, t.author_id, u.username, t.comment_text,
+				t.author_id = rc.author_id, t.updated_at IS NOT NULL,
+				t.deleted_at IS NOT NULL OR t.hidden_at IS NOT NULL, t.created_at
+			  FROM thread t
+			  JOIN users u ON t.author_id = u.id
+			  JOIN recipes rc ON t.recipe_id = rc.id
+			  ORDER BY t.created_at, t.id`
+
+	rows, err := r.DB.Query(query, recipeId, pageSize, offset)
+	if err != nil {
+		log.Error("Error with selecting comments", sl.Err(err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	var comments []structures.Comment
+	for rows.Next() {
+		var comment structures.Comment
+		var parentId sql.NullInt64
+
+		err := rows.Scan(&comment.Id, &comment.Recipe_id, &parentId, &comment.Author_id, &comment.AuthorName,
+			&comment.Text, &comment.Is_recipe_author, &comment.Edited, &comment.Deleted, &comment.Created_at)
+		if err != nil {
+			log.Error("Error scanning comment row", sl.Err(err))
+			continue
+		}
+
+		if parentId.Valid {
+			id := int(parentId.Int64)
+			comment.Parent_id = &id
+		}
+
+		if comment.Deleted {
+			comment.Text = ""
+			comment.AuthorName = ""
+		}
+
+		comments = append(comments, comment)
+	}
+
+	return buildCommentTree(comments), nil
+}
+
+// buildCommentTree nests replies into their parents. Comments must be ordered
+// so that a parent always goes before its replies.
+func buildCommentTree(comments []structures.Comment) []structures.Comment {
+	children := make(map[int][]int)
+	var roots []int
+
+	for i, comment := range comments {
+		if comment.Parent_id == nil {
+			roots = append(roots, i)
+			continue
+		}
+		children[*comment.Parent_id] = append(children[*comment.Parent_id], i)
+	}
+
+	var build func(i int) structures.Comment
+	build = func(i int) structures.Comment {
+		comment := comments[i]
+		for _, child := range children[comment.Id] {
+			comment.Replies = append(comment.Replies, build(child))
+		}
+		return comment
+	}
+
+	tree := make([]structures.Comment, 0, len(roots))
+	for i := len(roots) - 1; i >= 0; i-- {
+		tree = append(tree, build(roots[i]))
+	}
+
+	return tree
+}
+
+func insertMentionNotifications(tx *sql.Tx, comment structures.Comment, log *slog.Logger) error {
+	var usernames []string
+	for _, match := range mentionRegexp.FindAllStringSubmatch(comment.Text, -1) {
+		usernames = append(usernames, match[1])
+	}
+
+	if len(usernames) == 0 {
+		return nil
+	}
+
+	_, err := tx.Exec(`INSERT INTO notifications (user_id, kind, actor_id, recipe_id, comment_id)
+			  SELECT u.id, 'mention', $1, $2, $3 FROM users u
+			  WHERE u.username = ANY($4) AND u.id <> $1
+				AND NOT EXISTS(SELECT 1 FROM notifications n
+					WHERE n.user_id = u.id AND n.kind = 'mention' AND n.comment_id = $3)`,
+		comment.Author_id, comment.Recipe_id, comment.Id, pq.Array(usernames))
+	if err != nil {
+		log.Error("Error with inserting mention notifications", sl.Err(err))
+		return err
+	}
+
+	return nil
+}
@@ -1,58 +1,117 @@
 package postgres
 
 import (
+	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"log/slog"
+	"strconv"
 	"sync"
 
+	"github.com/lib/pq"
+	"github.com/qwaq-dev/culina/internal/repository"
+	"github.com/qwaq-dev/culina/pkg/config"
 	"github.com/qwaq-dev/culina/pkg/logger/sl"
 	"github.com/qwaq-dev/culina/structures"
 )
 
 type PostgresDashboardRepository struct {
-	DB *sql.DB
+	DB       *sql.DB
+	Timeouts config.QueryTimeouts
 }
 
-func (p *PostgresDashboardRepository) InsertRecipe(recipe structures.Recipes, log *slog.Logger) (int, error) {
+func (p *PostgresDashboardRepository) InsertRecipe(ctx context.Context, recipe structures.Recipes, log *slog.Logger) (int, error) {
+	ctx, cancel := writeTimeout(ctx, p.Timeouts, "InsertRecipe")
+	defer cancel()
+
 	var recipeId int
 
 	ingredientsJSON, _ := json.Marshal(recipe.Ingredients)
 	stepsJSON, _ := json.Marshal(recipe.Steps)
 	imagesJSON, _ := json.Marshal(recipe.Imgs)
+	variantsJSON, _ := json.Marshal(recipe.Img_variants)
+	placeholdersJSON, _ := json.Marshal(recipe.Img_placeholders)
 	filtersJSON, _ := json.Marshal(recipe.Filters)
+	translationsJSON, _ := json.Marshal(recipe.Translations)
+	if recipe.Translations == nil {
+		translationsJSON = []byte("{}")
+	}
+
+	query := `INSERT INTO recipes (name, descr, diff, filters, ingredients, steps, author_id, imgs, img_variants, img_placeholders, draft, hidden_at, cook_time, calories, ingredient_names, lang, translations) 
+          VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, CASE WHEN $12 THEN NOW() END, $13, $14, $15, $16, $17) RETURNING id`
 
-	query := `INSERT INTO recipes (name, descr, diff, filters, ingredients, steps, author_id, imgs) 
-          VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING id`
+	held := recipe.Hold_reason != ""
 
-	err := p.DB.QueryRow(query, recipe.Name, recipe.Descr, recipe.Diff, string(filtersJSON), string(ingredientsJSON), string(stepsJSON), recipe.AuthorID, string(imagesJSON)).Scan(&recipeId)
+	tx, err := begin(ctx, p.DB)
 	if err != nil {
-		log.Error("Error with inserting data", sl.Err(err))
+		log.ErrorContext(ctx, "Error with starting transaction", sl.Err(err))
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRowContext(ctx, query, recipe.Name, recipe.Descr, recipe.Diff, string(filtersJSON), string(ingredientsJSON), string(stepsJSON), recipe.AuthorID, string(imagesJSON), string(variantsJSON), string(placeholdersJSON), recipe.Draft, held, recipe.Cook_time, recipe.Calories, pq.Array(recipe.Ingredient_names), recipe.Lang, string(translationsJSON)).Scan(&recipeId)
+	if err != nil {
+		log.ErrorContext(ctx, "Error with inserting data", sl.Err(err))
+		return 0, err
+	}
+
+	if held {
+		if err := insertAutoReport(ctx, tx, structures.TargetRecipe, recipeId, recipe.Hold_reason); err != nil {
+			log.ErrorContext(ctx, "Error with sending recipe to moderation", sl.Err(err))
+			return 0, err
+		}
+	}
+
+	var keys []string
+	for _, variants := range recipe.Img_variants {
+		keys = append(keys, variants.Keys()...)
+	}
+	if err := changeBlobRefs(ctx, tx, keys, 1); err != nil {
+		log.ErrorContext(ctx, "Error with counting recipe images", sl.Err(err))
+		return 0, err
+	}
+
+	// drafts and held recipes get into the index when they are published or approved
+	if !recipe.Draft && !held {
+		if err := enqueueSearchSync(ctx, tx, recipeId); err != nil {
+			log.ErrorContext(ctx, "Error with queueing recipe for search", sl.Err(err))
+			return 0, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.ErrorContext(ctx, "Error with committing transaction", sl.Err(err))
 		return 0, err
 	}
 
 	recipe.Id = recipeId
 
-	log.Info("Recipe was upload to db", slog.Any("recipe", recipe))
+	log.InfoContext(ctx, "Recipe was upload to db", slog.Any("recipe", recipe))
 
 	return recipeId, nil
 }
 
-func (p *PostgresDashboardRepository) SelectAllRecipes(page, pageSize int, log *slog.Logger) ([]structures.Recipes, error) {
+func (p *PostgresDashboardRepository) SelectAllRecipes(ctx context.Context, page, pageSize int, log *slog.Logger) ([]structures.Recipes, error) {
+	ctx, cancel := readTimeout(ctx, p.Timeouts, "SelectAllRecipes")
+	defer cancel()
+
 	var recipes []structures.Recipes
 
 	offset := (page - 1) * pageSize
 
-	query := `SELECT r.id, r.name, r.descr, r.diff, r.filters, r.imgs, r.author_id, 
-                 r.ingredients, r.steps, r.created_at, u.username
+	query := `SELECT r.id, r.name, r.descr, r.diff, r.cook_time, r.calories, r.filters, r.imgs, r.img_variants, r.img_placeholders, r.videos, r.author_id, 
+                 r.ingredients, r.steps, r.lang, r.translations, r.created_at, u.username
           	  FROM recipes r
           	  JOIN users u ON r.author_id = u.id
+          	  WHERE r.hidden_at IS NULL AND NOT r.draft
          	  ORDER BY r.id DESC
          	  LIMIT $1 OFFSET $2`
 
-	rows, err := p.DB.Query(query, pageSize, offset)
+	rows, err := conn(ctx, p.DB).QueryContext(ctx, query, pageSize, offset)
 	if err != nil {
-		log.Error("Error with selecting recipes", sl.Err(err))
+		log.ErrorContext(ctx, "Error with selecting recipes", sl.Err(err))
 		return nil, err
 	}
 	defer rows.Close()
@@ -62,20 +121,24 @@ func (p *PostgresDashboardRepository) SelectAllRecipes(page, pageSize int, log *
 
 	for rows.Next() {
 		var recipe structures.Recipes
-		var filtersJSON, imgsJSON, ingredientsJSON, stepsJSON []byte
+		var filtersJSON, imgsJSON, variantsJSON, placeholdersJSON, videosJSON, ingredientsJSON, stepsJSON, translationsJSON []byte
 
-		err := rows.Scan(&recipe.Id, &recipe.Name, &recipe.Descr, &recipe.Diff,
-			&filtersJSON, &imgsJSON, &recipe.AuthorID, &ingredientsJSON,
-			&stepsJSON, &recipe.Created_at, &recipe.AuthorName)
+		err := rows.Scan(&recipe.Id, &recipe.Name, &recipe.Descr, &recipe.Diff, &recipe.Cook_time, &recipe.Calories,
+			&filtersJSON, &imgsJSON, &variantsJSON, &placeholdersJSON, &videosJSON, &recipe.AuthorID, &ingredientsJSON,
+			&stepsJSON, &recipe.Lang, &translationsJSON, &recipe.Created_at, &recipe.AuthorName)
 		if err != nil {
-			log.Error("Error scanning row", sl.Err(err))
+			log.ErrorContext(ctx, "Error scanning row", sl.Err(err))
 			continue
 		}
 
 		json.Unmarshal(filtersJSON, &recipe.Filters)
 		json.Unmarshal(imgsJSON, &recipe.Imgs)
+		json.Unmarshal(variantsJSON, &recipe.Img_variants)
+		json.Unmarshal(placeholdersJSON, &recipe.Img_placeholders)
+		json.Unmarshal(videosJSON, &recipe.Videos)
 		json.Unmarshal(ingredientsJSON, &recipe.Ingredients)
 		json.Unmarshal(stepsJSON, &recipe.Steps)
+		json.Unmarshal(translationsJSON, &recipe.Translations)
 
 		recipesMap[recipe.Id] = &recipe
 		recipeIds = append(recipeIds, recipe.Id)
@@ -85,17 +148,14 @@ func (p *PostgresDashboardRepository) SelectAllRecipes(page, pageSize int, log *
 		return nil, nil
 	}
 
-	var wg sync.WaitGroup
 	var mu sync.Mutex
 
-	for _, id := range recipeIds {
-		wg.Add(1)
-
-		go func(recipeId int) {
-			defer wg.Done()
-			reviews, err := p.SelectReviewsByRecipeId(recipeId, log)
+	reads := make([]func(), 0, len(recipeIds))
+	for _, recipeId := range recipeIds {
+		reads = append(reads, func() {
+			reviews, err := p.SelectReviewsByRecipeId(ctx, recipeId, 1, defaultReviewsPageSize, "helpful", log)
 			if err != nil {
-				log.Error("Error with fetching results", sl.Err(err))
+				log.ErrorContext(ctx, "Error with fetching results", sl.Err(err))
 				return
 			}
 
@@ -104,9 +164,9 @@ func (p *PostgresDashboardRepository) SelectAllRecipes(page, pageSize int, log *
 				recipe.Reviews = reviews
 			}
 			mu.Unlock()
-		}(id)
+		})
 	}
-	wg.Wait()
+	parallel(ctx, reads...)
 
 	for _, recipe := range recipesMap {
 		recipes = append(recipes, *recipe)
@@ -115,107 +175,636 @@ func (p *PostgresDashboardRepository) SelectAllRecipes(page, pageSize int, log *
 	return recipes, nil
 }
 
-func (p *PostgresDashboardRepository) SelectRecipeById(id int, log *slog.Logger) (structures.Recipes, error) {
-	var recipe structures.Recipes
-	var filtersJSON, imgsJSON, ingredientsJSON, stepsJSON, reviewCount, avgRating []byte
-
-	query := `SELECT r.id, r.name, r.descr, r.diff, r.filters, r.imgs, 
-				r.ingredients, r.steps, r.created_at, u.username
-		      FROM recipes r
-		      JOIN users u ON r.author_id = u.id
-			  WHERE id = $1`
+// SelectRecipesForIndex returns published recipes with ids greater than afterId,
+// ordered by id and without reviews. It pages through all recipes for the search index.
+func (p *PostgresDashboardRepository) SelectRecipesForIndex(ctx context.Context, afterId, limit int, log *slog.Logger) ([]structures.Recipes, error) {
+	ctx, cancel := readTimeout(ctx, p.Timeouts, "SelectRecipesForIndex")
+	defer cancel()
 
-	rows, err := p.DB.Query(query, id)
+	rows, err := conn(ctx, p.DB).QueryContext(ctx, `SELECT r.id, r.name, r.descr, r.diff, r.cook_time, r.calories, r.filters, r.imgs, r.img_placeholders, r.author_id,
+				 r.ingredients, r.ingredient_names, r.steps, r.lang, r.translations, r.review_count, r.avg_rating
+			  FROM recipes r
+			  WHERE r.id > $1 AND r.hidden_at IS NULL AND NOT r.draft
+			  ORDER BY r.id
+			  LIMIT $2`, afterId, limit)
 	if err != nil {
-		log.Error("error with getting recipe by id", sl.Err(err))
-		return recipe, nil
+		log.ErrorContext(ctx, "Error with selecting recipes for index", sl.Err(err))
+		return nil, err
 	}
 	defer rows.Close()
 
+	var recipes []structures.Recipes
 	for rows.Next() {
-		err := rows.Scan(&recipe.Id, &recipe.Name, &recipe.Descr, &recipe.Diff, &filtersJSON,
-			&imgsJSON, &recipe.AuthorID, &ingredientsJSON, &stepsJSON, &reviewCount,
-			&avgRating, &recipe.Created_at, &recipe.AuthorID)
+		var recipe structures.Recipes
+		var filtersJSON, imgsJSON, placeholdersJSON, ingredientsJSON, stepsJSON, translationsJSON []byte
+
+		err := rows.Scan(&recipe.Id, &recipe.Name, &recipe.Descr, &recipe.Diff, &recipe.Cook_time, &recipe.Calories, &filtersJSON, &imgsJSON,
+			&placeholdersJSON, &recipe.AuthorID, &ingredientsJSON, pq.Array(&recipe.Ingredient_names), &stepsJSON, &recipe.Lang, &translationsJSON, &recipe.Review_count, &recipe.Avg_rating)
 		if err != nil {
-			log.Error("Error scanning row", sl.Err(err))
-			continue
+			log.ErrorContext(ctx, "Error scanning row", sl.Err(err))
+			return nil, err
 		}
 
-		var wg sync.WaitGroup
-		var mu sync.Mutex
+		json.Unmarshal(filtersJSON, &recipe.Filters)
+		json.Unmarshal(imgsJSON, &recipe.Imgs)
+		json.Unmarshal(placeholdersJSON, &recipe.Img_placeholders)
+		json.Unmarshal(ingredientsJSON, &recipe.Ingredients)
+		json.Unmarshal(stepsJSON, &recipe.Steps)
+		json.Unmarshal(translationsJSON, &recipe.Translations)
 
-		wg.Add(1)
+		recipes = append(recipes, recipe)
+	}
 
-		go func(recipeId int) {
-			defer wg.Done()
-			reviews, err := p.SelectReviewsByRecipeId(recipeId, log)
-			if err != nil {
-				log.Error("Error with fetching results", sl.Err(err))
-				return
-			}
+	return recipes, rows.Err()
+}
 
-			mu.Lock()
-			recipe.Reviews = reviews
-			mu.Unlock()
-		}(id)
+// SelectRecipeNames returns names of all recipes of the author, drafts and hidden ones too.
+func (p *PostgresDashboardRepository) SelectRecipeNames(ctx context.Context, authorId int, log *slog.Logger) ([]string, error) {
+	ctx, cancel := readTimeout(ctx, p.Timeouts, "SelectRecipeNames")
+	defer cancel()
+
+	rows, err := conn(ctx, p.DB).QueryContext(ctx, "SELECT name FROM recipes WHERE author_id = $1", authorId)
+	if err != nil {
+		log.ErrorContext(ctx, "Error with selecting recipe names", sl.Err(err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	var names []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			log.ErrorContext(ctx, "Error scanning recipe name", sl.Err(err))
+			return nil, err
+		}
+		names = append(names, name)
+	}
+
+	return names, rows.Err()
+}
+
+// SelectDrafts returns unpublished recipes of the author, newest first.
+func (p *PostgresDashboardRepository) SelectDrafts(ctx context.Context, authorId int, log *slog.Logger) ([]structures.Recipes, error) {
+	ctx, cancel := readTimeout(ctx, p.Timeouts, "SelectDrafts")
+	defer cancel()
 
-		wg.Wait()
+	query := `SELECT r.id, r.name, r.descr, r.diff, r.cook_time, r.calories, r.filters, r.imgs, r.img_variants, r.img_placeholders, r.videos, r.author_id,
+				r.ingredients, r.steps, r.lang, r.translations, r.created_at, u.username
+			  FROM recipes r
+			  JOIN users u ON r.author_id = u.id
+			  WHERE r.author_id = $1 AND r.draft
+			  ORDER BY r.id DESC`
+
+	rows, err := conn(ctx, p.DB).QueryContext(ctx, query, authorId)
+	if err != nil {
+		log.ErrorContext(ctx, "Error with selecting drafts", sl.Err(err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	var recipes []structures.Recipes
+	for rows.Next() {
+		recipe := structures.Recipes{Draft: true}
+		var filtersJSON, imgsJSON, variantsJSON, placeholdersJSON, videosJSON, ingredientsJSON, stepsJSON, translationsJSON []byte
+
+		err := rows.Scan(&recipe.Id, &recipe.Name, &recipe.Descr, &recipe.Diff, &recipe.Cook_time, &recipe.Calories,
+			&filtersJSON, &imgsJSON, &variantsJSON, &placeholdersJSON, &videosJSON, &recipe.AuthorID, &ingredientsJSON,
+			&stepsJSON, &recipe.Lang, &translationsJSON, &recipe.Created_at, &recipe.AuthorName)
+		if err != nil {
+			log.ErrorContext(ctx, "Error scanning draft row", sl.Err(err))
+			continue
+		}
 
 		json.Unmarshal(filtersJSON, &recipe.Filters)
 		json.Unmarshal(imgsJSON, &recipe.Imgs)
+		json.Unmarshal(variantsJSON, &recipe.Img_variants)
+		json.Unmarshal(placeholdersJSON, &recipe.Img_placeholders)
+		json.Unmarshal(videosJSON, &recipe.Videos)
 		json.Unmarshal(ingredientsJSON, &recipe.Ingredients)
 		json.Unmarshal(stepsJSON, &recipe.Steps)
-		json.Unmarshal(reviewCount, &recipe.Review_count)
-		json.Unmarshal(avgRating, &recipe.Avg_rating)
+		json.Unmarshal(translationsJSON, &recipe.Translations)
+
+		recipes = append(recipes, recipe)
+	}
+
+	return recipes, nil
+}
+
+// PublishRecipe makes the draft visible to everyone.
+func (p *PostgresDashboardRepository) PublishRecipe(ctx context.Context, recipeId, authorId int, log *slog.Logger) error {
+	ctx, cancel := writeTimeout(ctx, p.Timeouts, "PublishRecipe")
+	defer cancel()
+
+	var recipeAuthorId int
+	var draft bool
+	err := conn(ctx, p.DB).QueryRowContext(ctx, "SELECT author_id, draft FROM recipes WHERE id = $1", recipeId).Scan(&recipeAuthorId, &draft)
+	if errors.Is(err, sql.ErrNoRows) {
+		return repository.ErrRecipeNotFound
+	}
+	if err != nil {
+		log.ErrorContext(ctx, "Error with selecting recipe author", sl.Err(err))
+		return err
+	}
+
+	if recipeAuthorId != authorId {
+		return repository.ErrNotRecipeAuthor
+	}
+	if !draft {
+		return repository.ErrNotDraft
+	}
+
+	tx, err := begin(ctx, p.DB)
+	if err != nil {
+		log.ErrorContext(ctx, "Error with starting transaction", sl.Err(err))
+		return err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, "UPDATE recipes SET draft = false WHERE id = $1 AND draft", recipeId)
+	if err != nil {
+		log.ErrorContext(ctx, "Error with publishing recipe", sl.Err(err))
+		return err
+	}
+	if affected, _ := res.RowsAffected(); affected == 0 {
+		return repository.ErrNotDraft
+	}
+
+	if err := enqueueSearchSync(ctx, tx, recipeId); err != nil {
+		log.ErrorContext(ctx, "Error with queueing recipe for search", sl.Err(err))
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.ErrorContext(ctx, "Error with committing transaction", sl.Err(err))
+		return err
+	}
+
+	log.InfoContext(ctx, "Recipe was published", slog.Int("recipe_id", recipeId))
+	return nil
+}
+
+// AddRecipeImage adds an image to the recipe under the next number.
+func (p *PostgresDashboardRepository) AddRecipeImage(ctx context.Context, recipeId, authorId int, image structures.RecipeMedia, maxImages int, log *slog.Logger) error {
+	ctx, cancel := writeTimeout(ctx, p.Timeouts, "AddRecipeImage")
+	defer cancel()
+
+	tx, err := begin(ctx, p.DB)
+	if err != nil {
+		log.ErrorContext(ctx, "Error with starting transaction", sl.Err(err))
+		return err
+	}
+	defer tx.Rollback()
+
+	var recipeAuthorId int
+	var imgsJSON []byte
+	err = tx.QueryRowContext(ctx, "SELECT author_id, imgs FROM recipes WHERE id = $1 FOR UPDATE", recipeId).Scan(&recipeAuthorId, &imgsJSON)
+	if errors.Is(err, sql.ErrNoRows) {
+		return repository.ErrRecipeNotFound
+	}
+	if err != nil {
+		log.ErrorContext(ctx, "Error with selecting recipe images", sl.Err(err))
+		return err
+	}
+
+	if recipeAuthorId != authorId {
+		return repository.ErrNotRecipeAuthor
+	}
+
+	imgs := make(map[string]string)
+	json.Unmarshal(imgsJSON, &imgs)
+	if len(imgs) >= maxImages {
+		return fmt.Errorf("%w: max %d", repository.ErrTooManyImages, maxImages)
+	}
+
+	next := 1
+	for i := range imgs {
+		if n, err := strconv.Atoi(i); err == nil && n >= next {
+			next = n + 1
+		}
+	}
+	index := strconv.Itoa(next)
+
+	variantsJSON, _ := json.Marshal(image.Variants)
+	placeholderJSON, _ := json.Marshal(image.Placeholder)
+
+	_, err = tx.ExecContext(ctx, `UPDATE recipes
+			  SET imgs = imgs || jsonb_build_object($2::text, $3::text),
+				img_variants = img_variants || jsonb_build_object($2::text, $4::jsonb),
+				img_placeholders = img_placeholders || jsonb_build_object($2::text, $5::jsonb)
+			  WHERE id = $1`,
+		recipeId, index, image.Key, string(variantsJSON), string(placeholderJSON))
+	if err != nil {
+		log.ErrorContext(ctx, "Error with adding recipe image", sl.Err(err))
+		return err
+	}
+
+	if err := changeBlobRefs(ctx, tx, image.Variants.Keys(), 1); err != nil {
+		log.ErrorContext(ctx, "Error with counting recipe images", sl.Err(err))
+		return err
+	}
+
+	if err := enqueueSearchSync(ctx, tx, recipeId); err != nil {
+		log.ErrorContext(ctx, "Error with queueing recipe for search", sl.Err(err))
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.ErrorContext(ctx, "Error with committing transaction", sl.Err(err))
+		return err
+	}
+
+	log.InfoContext(ctx, "Image was added to recipe", slog.Int("recipe_id", recipeId), slog.String("index", index))
+	return nil
+}
+
+func (p *PostgresDashboardRepository) AddRecipeVideo(ctx context.Context, recipeId, authorId int, video structures.RecipeVideo, log *slog.Logger) error {
+	ctx, cancel := writeTimeout(ctx, p.Timeouts, "AddRecipeVideo")
+	defer cancel()
+
+	tx, err := begin(ctx, p.DB)
+	if err != nil {
+		log.ErrorContext(ctx, "Error with starting transaction", sl.Err(err))
+		return err
+	}
+	defer tx.Rollback()
+
+	videoJSON, _ := json.Marshal(video)
+
+	var recipeAuthorId int
+	err = tx.QueryRowContext(ctx, `UPDATE recipes SET videos = videos || jsonb_build_array($2::jsonb)
+			  WHERE id = $1
+			  RETURNING author_id`, recipeId, string(videoJSON)).Scan(&recipeAuthorId)
+	if errors.Is(err, sql.ErrNoRows) {
+		return repository.ErrRecipeNotFound
+	}
+	if err != nil {
+		log.ErrorContext(ctx, "Error with adding recipe video", sl.Err(err))
+		return err
 	}
 
+	if recipeAuthorId != authorId {
+		return repository.ErrNotRecipeAuthor
+	}
+
+	if err := changeBlobRefs(ctx, tx, []string{video.Key}, 1); err != nil {
+		log.ErrorContext(ctx, "Error with counting recipe video", sl.Err(err))
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.ErrorContext(ctx, "Error with committing transaction", sl.Err(err))
+		return err
+	}
+
+	log.InfoContext(ctx, "Video was added to recipe", slog.Int("recipe_id", recipeId))
+	return nil
+}
+
+// IsPrivateImage reports whether the image or video is used only by drafts,
+// hidden recipes and hidden reviews. Files are shared by their content hash,
+// so a file that a published recipe or a visible review uses too is public.
+// Files nobody uses are not private.
+func (p *PostgresDashboardRepository) IsPrivateImage(ctx context.Context, key string, log *slog.Logger) (bool, error) {
+	ctx, cancel := readTimeout(ctx, p.Timeouts, "IsPrivateImage")
+	defer cancel()
+
+	query := `WITH refs AS (
+				SELECT draft OR hidden_at IS NOT NULL AS private FROM recipes
+				WHERE jsonb_path_exists(img_variants, '$.*.* ? (@.key == $k || @.webp == $k)', jsonb_build_object('k', $1::text))
+					OR jsonb_path_exists(videos, '$[*] ? (@.key == $k)', jsonb_build_object('k', $1::text))
+				UNION ALL
+				SELECT rv.hidden_at IS NOT NULL OR r.draft OR r.hidden_at IS NOT NULL FROM review_images ri
+				JOIN reviews rv ON rv.id = ri.review_id
+				JOIN recipes r ON r.id = rv.recipe_id
+				WHERE ri.image_key = $1
+					OR jsonb_path_exists(ri.variants, '$.* ? (@.key == $k || @.webp == $k)', jsonb_build_object('k', $1::text))
+			  )
+			  SELECT COALESCE(bool_and(private), false) FROM refs`
+
+	var private bool
+	if err := conn(ctx, p.DB).QueryRowContext(ctx, query, key).Scan(&private); err != nil {
+		log.ErrorContext(ctx, "Error with checking image access", sl.Err(err))
+		return false, err
+	}
+
+	return private, nil
+}
+
+func (p *PostgresDashboardRepository) SelectRecipeById(ctx context.Context, id int, log *slog.Logger) (structures.Recipes, error) {
+	ctx, cancel := readTimeout(ctx, p.Timeouts, "SelectRecipeById")
+	defer cancel()
+
+	var recipe structures.Recipes
+	var filtersJSON, imgsJSON, variantsJSON, placeholdersJSON, videosJSON, ingredientsJSON, stepsJSON, translationsJSON []byte
+
+	query := `SELECT r.id, r.name, r.descr, r.diff, r.cook_time, r.calories, r.filters, r.imgs, r.img_variants, r.img_placeholders, r.videos, r.author_id,
+				r.ingredients, r.ingredient_names, r.steps, r.lang, r.translations, r.review_count, r.avg_rating, r.created_at, u.username
+		      FROM recipes r
+		      JOIN users u ON r.author_id = u.id
+			  WHERE r.id = $1 AND r.hidden_at IS NULL AND NOT r.draft`
+
+	err := conn(ctx, p.DB).QueryRowContext(ctx, query, id).Scan(&recipe.Id, &recipe.Name, &recipe.Descr, &recipe.Diff, &recipe.Cook_time, &recipe.Calories, &filtersJSON,
+		&imgsJSON, &variantsJSON, &placeholdersJSON, &videosJSON, &recipe.AuthorID, &ingredientsJSON, pq.Array(&recipe.Ingredient_names), &stepsJSON, &recipe.Lang, &translationsJSON,
+		&recipe.Review_count, &recipe.Avg_rating, &recipe.Created_at, &recipe.AuthorName)
+	if errors.Is(err, sql.ErrNoRows) {
+		return recipe, repository.ErrRecipeNotFound
+	}
+	if err != nil {
+		log.ErrorContext(ctx, "error with getting recipe by id", sl.Err(err))
+		return recipe, err
+	}
+
+	json.Unmarshal(filtersJSON, &recipe.Filters)
+	json.Unmarshal(imgsJSON, &recipe.Imgs)
+	json.Unmarshal(variantsJSON, &recipe.Img_variants)
+	json.Unmarshal(placeholdersJSON, &recipe.Img_placeholders)
+	json.Unmarshal(videosJSON, &recipe.Videos)
+	json.Unmarshal(ingredientsJSON, &recipe.Ingredients)
+	json.Unmarshal(stepsJSON, &recipe.Steps)
+	json.Unmarshal(translationsJSON, &recipe.Translations)
+
+	parallel(ctx, func() {
+		reviews, err := p.SelectReviewsByRecipeId(ctx, id, 1, defaultReviewsPageSize, "helpful", log)
+		if err != nil {
+			log.ErrorContext(ctx, "Error with fetching results", sl.Err(err))
+			return
+		}
+		recipe.Reviews = reviews
+	}, func() {
+		distribution, err := p.SelectRatingDistribution(ctx, id, log)
+		if err != nil {
+			log.ErrorContext(ctx, "Error with fetching rating distribution", sl.Err(err))
+			return
+		}
+		recipe.Rating_distribution = distribution
+	}, func() {
+		photos, err := p.SelectRecipePhotos(ctx, id, 1, defaultPhotosPageSize, log)
+		if err != nil {
+			log.ErrorContext(ctx, "Error with fetching community photos", sl.Err(err))
+			return
+		}
+		if len(photos) > 0 && photos[0].Pinned {
+			recipe.Pinned_photo = &photos[0]
+		}
+		recipe.Community_photos = photos
+	})
+
 	return recipe, nil
 }
 
-var reviewQueue = make(chan structures.Review, 100)
+// insertReview saves the review with its photos and the new recipe rating.
+func (p *PostgresDashboardRepository) insertReview(ctx context.Context, review structures.Review, log *slog.Logger) error {
+	tx, err := begin(ctx, p.DB)
+	if err != nil {
+		log.ErrorContext(ctx, "Error with starting transaction", sl.Err(err))
+		return err
+	}
+	defer tx.Rollback()
 
-func (p *PostgresDashboardRepository) StartReviewWorker(log *slog.Logger) {
-	go func() {
-		for review := range reviewQueue {
-			var reviewId int
-			err := p.DB.QueryRow(`
-                INSERT INTO reviews (review_text, rating_value, author_id, recipe_id) 
-                VALUES ($1, $2, $3, $4) RETURNING id
-            `, review.Text, review.Rating_value, review.Reviewed_by, review.Recipe_id).Scan(&reviewId)
+	var reviewId int
+	held := review.Hold_reason != ""
+	err = tx.QueryRowContext(ctx, `
+        INSERT INTO reviews (review_text, rating_value, author_id, recipe_id, hidden_at) 
+        VALUES ($1, $2, $3, $4, CASE WHEN $5 THEN NOW() END)
+        ON CONFLICT (author_id, recipe_id) DO NOTHING
+        RETURNING id
+    `, review.Text, review.Rating_value, review.Reviewed_by, review.Recipe_id, held).Scan(&reviewId)
 
-			if err != nil {
-				log.Error("Error inserting review", sl.Err(err))
-				continue
-			}
+	if errors.Is(err, sql.ErrNoRows) {
+		return repository.ErrReviewExists
+	}
+	if err != nil {
+		log.ErrorContext(ctx, "Error with inserting review", sl.Err(err))
+		return err
+	}
 
-			log.Info("Review successfully inserted", slog.Int("id", review.Recipe_id))
+	if held {
+		if err := insertAutoReport(ctx, tx, structures.TargetReview, reviewId, review.Hold_reason); err != nil {
+			log.ErrorContext(ctx, "Error with sending review to moderation", sl.Err(err))
+			return err
+		}
+	}
 
-			_, err = p.DB.Exec(`
-					UPDATE recipes 
-					SET review_count = (SELECT COUNT(*) FROM reviews WHERE recipe_id = $1),
-						avg_rating = (SELECT COALESCE(AVG(rating_value), 0) FROM reviews WHERE recipe_id = $1)
-					WHERE id = $1
-            `, review.Recipe_id)
+	for _, img := range review.Imgs {
+		variantsJSON, _ := json.Marshal(img.Variants)
+		_, err := tx.ExecContext(ctx, `INSERT INTO review_images (review_id, image_key, variants, blurhash, dominant_color)
+				VALUES ($1, $2, $3, $4, $5)`,
+			reviewId, img.Key, string(variantsJSON), img.Placeholder.BlurHash, img.Placeholder.Color)
+		if err != nil {
+			log.ErrorContext(ctx, "Error inserting review image", slog.String("key", img.Key), sl.Err(err))
+			return err
+		}
 
-			if err != nil {
-				log.Error("Error updating review_count and avg_rating", sl.Err(err))
-			} else {
-				log.Info("Recipe review_count and avg_rating updated")
-			}
+		if err := changeBlobRefs(ctx, tx, img.Variants.Keys(), 1); err != nil {
+			log.ErrorContext(ctx, "Error with counting review images", sl.Err(err))
+			return err
 		}
-	}()
+	}
+
+	if err := recountRecipeRating(ctx, tx, review.Recipe_id); err != nil {
+		log.ErrorContext(ctx, "Error updating review_count and avg_rating", sl.Err(err))
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.ErrorContext(ctx, "Error with committing transaction", sl.Err(err))
+		return err
+	}
+
+	log.InfoContext(ctx, "Review successfully inserted", slog.Int("id", reviewId), slog.Int("recipe_id", review.Recipe_id))
+	return nil
+}
+
+// updateRecipeRatingQuery recalculates review_count and avg_rating of the
+// recipe $1 from its visible reviews.
+const updateRecipeRatingQuery = `
+	UPDATE recipes 
+	SET review_count = (SELECT COUNT(*) FROM reviews WHERE recipe_id = $1 AND hidden_at IS NULL),
+		avg_rating = (SELECT COALESCE(AVG(rating_value), 0) FROM reviews WHERE recipe_id = $1 AND hidden_at IS NULL)
+	WHERE id = $1
+`
+
+// InsertReview checks that the review is allowed and saves it with its photos
+// and the new recipe rating in one transaction.
+func (p *PostgresDashboardRepository) InsertReview(ctx context.Context, review structures.Review, log *slog.Logger) error {
+	ctx, cancel := writeTimeout(ctx, p.Timeouts, "InsertReview")
+	defer cancel()
+
+	db := conn(ctx, p.DB)
+
+	var recipeAuthorId int
+	err := db.QueryRowContext(ctx, "SELECT author_id FROM recipes WHERE id = $1 AND hidden_at IS NULL AND NOT draft", review.Recipe_id).Scan(&recipeAuthorId)
+	if errors.Is(err, sql.ErrNoRows) {
+		return repository.ErrRecipeNotFound
+	}
+	if err != nil {
+		log.ErrorContext(ctx, "Error with selecting recipe author", sl.Err(err))
+		return err
+	}
+
+	if recipeAuthorId == review.Reviewed_by {
+		return repository.ErrOwnRecipeReview
+	}
+
+	var exists bool
+	err = db.QueryRowContext(ctx, "SELECT EXISTS(SELECT 1 FROM reviews WHERE author_id = $1 AND recipe_id = $2)",
+		review.Reviewed_by, review.Recipe_id).Scan(&exists)
+	if err != nil {
+		log.ErrorContext(ctx, "Error with checking review", sl.Err(err))
+		return err
+	}
+
+	if exists {
+		return repository.ErrReviewExists
+	}
+
+	return p.insertReview(ctx, review, log)
+}
+
+func (p *PostgresDashboardRepository) UpdateReview(ctx context.Context, review structures.Review, log *slog.Logger) error {
+	ctx, cancel := writeTimeout(ctx, p.Timeouts, "UpdateReview")
+	defer cancel()
+
+	var recipeId int
+
+	held := review.Hold_reason != ""
+
+	query := `UPDATE reviews SET review_text = $1, rating_value = $2, updated_at = NOW(),
+				hidden_at = CASE WHEN $5 THEN NOW() ELSE hidden_at END
+			  WHERE id = $3 AND author_id = $4
+			  RETURNING recipe_id`
+
+	tx, err := begin(ctx, p.DB)
+	if err != nil {
+		log.ErrorContext(ctx, "Error with starting transaction", sl.Err(err))
+		return err
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRowContext(ctx, query, review.Text, review.Rating_value, review.Id, review.Reviewed_by, held).Scan(&recipeId)
+	if errors.Is(err, sql.ErrNoRows) {
+		return repository.ErrReviewNotFound
+	}
+	if err != nil {
+		log.ErrorContext(ctx, "Error with updating review", sl.Err(err))
+		return err
+	}
+
+	if held {
+		if err := insertAutoReport(ctx, tx, structures.TargetReview, review.Id, review.Hold_reason); err != nil {
+			log.ErrorContext(ctx, "Error with sending review to moderation", sl.Err(err))
+			return err
+		}
+	}
+
+	if err := recountRecipeRating(ctx, tx, recipeId); err != nil {
+		log.ErrorContext(ctx, "Error updating review_count and avg_rating", sl.Err(err))
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.ErrorContext(ctx, "Error with committing transaction", sl.Err(err))
+		return err
+	}
+
+	return nil
 }
 
-func (p *PostgresDashboardRepository) InsertReview(review structures.Review, log *slog.Logger) error {
-	reviewQueue <- review
+// DeleteReview removes the review with its photos and releases their
+// references, the files are removed by the images gc.
+func (p *PostgresDashboardRepository) DeleteReview(ctx context.Context, reviewId, authorId int, log *slog.Logger) error {
+	ctx, cancel := writeTimeout(ctx, p.Timeouts, "DeleteReview")
+	defer cancel()
+
+	var recipeId int
+	var keys []string
+
+	tx, err := begin(ctx, p.DB)
+	if err != nil {
+		log.ErrorContext(ctx, "Error with starting transaction", sl.Err(err))
+		return err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, `SELECT ri.variants FROM review_images ri
+			  JOIN reviews r ON ri.review_id = r.id
+			  WHERE r.id = $1 AND r.author_id = $2`, reviewId, authorId)
+	if err != nil {
+		log.ErrorContext(ctx, "Error with selecting review images", sl.Err(err))
+		return err
+	}
+	for rows.Next() {
+		var variantsJSON []byte
+		if err := rows.Scan(&variantsJSON); err != nil {
+			log.ErrorContext(ctx, "Error scanning review image row", sl.Err(err))
+			continue
+		}
+
+		var variants structures.ImageVariants
+		json.Unmarshal(variantsJSON, &variants)
+		keys = append(keys, variants.Keys()...)
+	}
+	rows.Close()
+
+	err = tx.QueryRowContext(ctx, "DELETE FROM reviews WHERE id = $1 AND author_id = $2 RETURNING recipe_id",
+		reviewId, authorId).Scan(&recipeId)
+	if errors.Is(err, sql.ErrNoRows) {
+		return repository.ErrReviewNotFound
+	}
+	if err != nil {
+		log.ErrorContext(ctx, "Error with deleting review", sl.Err(err))
+		return err
+	}
+
+	// files themselves are removed by the images gc when nothing uses them
+	if err := changeBlobRefs(ctx, tx, keys, -1); err != nil {
+		log.ErrorContext(ctx, "Error with releasing review images", sl.Err(err))
+		return err
+	}
+
+	if err := recountRecipeRating(ctx, tx, recipeId); err != nil {
+		log.ErrorContext(ctx, "Error updating review_count and avg_rating", sl.Err(err))
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.ErrorContext(ctx, "Error with committing transaction", sl.Err(err))
+		return err
+	}
+
 	return nil
 }
 
-func (p *PostgresDashboardRepository) SelectReviewsByRecipeId(recipeId int, log *slog.Logger) ([]structures.Review, error) {
-	query := `SELECT id, review_text, rating_value, author_id, 
-				recipe_id FROM reviews 
-			  WHERE recipe_id = $1`
-	rows, err := p.DB.Query(query, recipeId)
+const (
+	defaultReviewsPageSize = 10
+	defaultPhotosPageSize  = 12
+)
+
+var reviewSortOrders = map[string]string{
+	"helpful": "(helpful_up - helpful_down) DESC, created_at DESC",
+	"newest":  "created_at DESC",
+	"rating":  "rating_value DESC, created_at DESC",
+}
+
+// SelectReviewsByRecipeId returns one page of recipe reviews ordered by sort:
+// "helpful" (default), "newest" or "rating".
+func (p *PostgresDashboardRepository) SelectReviewsByRecipeId(ctx context.Context, recipeId, page, pageSize int, sort string, log *slog.Logger) ([]structures.Review, error) {
+	ctx, cancel := readTimeout(ctx, p.Timeouts, "SelectReviewsByRecipeId")
+	defer cancel()
+
+	order, ok := reviewSortOrders[sort]
+	if !ok {
+		order = reviewSortOrders["helpful"]
+	}
+
+	offset := (page - 1) * pageSize
+
+	query := fmt.Sprintf(`SELECT id, review_text, rating_value, author_id, 
+				recipe_id, helpful_up, helpful_down, created_at FROM reviews 
+			  WHERE recipe_id = $1 AND hidden_at IS NULL
+			  ORDER BY %s, id DESC
+			  LIMIT $2 OFFSET $3`, order)
+	rows, err := conn(ctx, p.DB).QueryContext(ctx, query, recipeId, pageSize, offset)
 	if err != nil {
 		return nil, err
 	}
@@ -224,15 +813,228 @@ func (p *PostgresDashboardRepository) SelectReviewsByRecipeId(recipeId int, log
 	var reviews []structures.Review
 	for rows.Next() {
 		var review structures.Review
-		err := rows.Scan(&review.Id, &review.Text, &review.Rating_value, &review.Recipe_id, &review.Reviewed_by)
+		err := rows.Scan(&review.Id, &review.Text, &review.Rating_value, &review.Reviewed_by, &review.Recipe_id,
+			&review.Helpful_up, &review.Helpful_down, &review.Created_at)
 		if err != nil {
-			log.Error("Error scanning review row", sl.Err(err))
+			log.ErrorContext(ctx, "Error scanning review row", sl.Err(err))
 			continue
 		}
 		reviews = append(reviews, review)
 	}
 
-	log.Info("Fetched reviews", slog.Any("reviews", reviews))
+	if len(reviews) > 0 {
+		reviewIds := make([]int, len(reviews))
+		for i, review := range reviews {
+			reviewIds[i] = review.Id
+		}
+
+		images, err := p.selectReviewImages(ctx, reviewIds, log)
+		if err != nil {
+			log.ErrorContext(ctx, "Error with fetching review images", sl.Err(err))
+		}
+
+		for i := range reviews {
+			reviews[i].Imgs = images[reviews[i].Id]
+		}
+	}
+
+	log.InfoContext(ctx, "Fetched reviews", slog.Any("reviews", reviews))
 
 	return reviews, nil
 }
+
+func (p *PostgresDashboardRepository) selectReviewImages(ctx context.Context, reviewIds []int, log *slog.Logger) (map[int][]structures.ReviewImage, error) {
+	images := make(map[int][]structures.ReviewImage)
+
+	rows, err := conn(ctx, p.DB).QueryContext(ctx, `SELECT id, review_id, image_key, variants, blurhash, dominant_color, created_at FROM review_images
+			  WHERE review_id = ANY($1)
+			  ORDER BY id`, pq.Array(reviewIds))
+	if err != nil {
+		return images, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var image structures.ReviewImage
+		var variantsJSON []byte
+		if err := rows.Scan(&image.Id, &image.ReviewId, &image.Key, &variantsJSON,
+			&image.Placeholder.BlurHash, &image.Placeholder.Color, &image.Created_at); err != nil {
+			log.ErrorContext(ctx, "Error scanning review image row", sl.Err(err))
+			continue
+		}
+		json.Unmarshal(variantsJSON, &image.Variants)
+		images[image.ReviewId] = append(images[image.ReviewId], image)
+	}
+
+	return images, nil
+}
+
+// SelectRecipePhotos returns community photos from the recipe reviews,
+// the photo pinned by the recipe author goes first.
+func (p *PostgresDashboardRepository) SelectRecipePhotos(ctx context.Context, recipeId, page, pageSize int, log *slog.Logger) ([]structures.ReviewImage, error) {
+	ctx, cancel := readTimeout(ctx, p.Timeouts, "SelectRecipePhotos")
+	defer cancel()
+
+	offset := (page - 1) * pageSize
+
+	query := `SELECT ri.id, ri.review_id, r.author_id, ri.image_key, ri.variants, ri.blurhash, ri.dominant_color, ri.created_at,
+				COALESCE(ri.id = rc.pinned_photo_id, false)
+			  FROM review_images ri
+			  JOIN reviews r ON ri.review_id = r.id
+			  JOIN recipes rc ON r.recipe_id = rc.id
+			  WHERE rc.id = $1 AND r.hidden_at IS NULL
+			  ORDER BY 9 DESC, ri.created_at DESC, ri.id DESC
+			  LIMIT $2 OFFSET $3`
+
+	rows, err := conn(ctx, p.DB).QueryContext(ctx, query, recipeId, pageSize, offset)
+	if err != nil {
+		log.ErrorContext(ctx, "Error with selecting recipe photos", sl.Err(err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	var photos []structures.ReviewImage
+	for rows.Next() {
+		var photo structures.ReviewImage
+		var variantsJSON []byte
+		err := rows.Scan(&photo.Id, &photo.ReviewId, &photo.AuthorId, &photo.Key, &variantsJSON,
+			&photo.Placeholder.BlurHash, &photo.Placeholder.Color, &photo.Created_at, &photo.Pinned)
+		if err != nil {
+			log.ErrorContext(ctx, "Error scanning photo row", sl.Err(err))
+			continue
+		}
+		json.Unmarshal(variantsJSON, &photo.Variants)
+		photos = append(photos, photo)
+	}
+
+	return photos, nil
+}
+
+// PinRecipePhoto lets the recipe author pin a community photo of the recipe.
+// Photo id 0 unpins the current photo.
+func (p *PostgresDashboardRepository) PinRecipePhoto(ctx context.Context, recipeId, authorId, photoId int, log *slog.Logger) error {
+	ctx, cancel := writeTimeout(ctx, p.Timeouts, "PinRecipePhoto")
+	defer cancel()
+
+	db := conn(ctx, p.DB)
+
+	var recipeAuthorId int
+	err := db.QueryRowContext(ctx, "SELECT author_id FROM recipes WHERE id = $1", recipeId).Scan(&recipeAuthorId)
+	if errors.Is(err, sql.ErrNoRows) {
+		return repository.ErrRecipeNotFound
+	}
+	if err != nil {
+		log.ErrorContext(ctx, "Error with selecting recipe author", sl.Err(err))
+		return err
+	}
+
+	if recipeAuthorId != authorId {
+		return repository.ErrNotRecipeAuthor
+	}
+
+	if photoId == 0 {
+		_, err = db.ExecContext(ctx, "UPDATE recipes SET pinned_photo_id = NULL WHERE id = $1", recipeId)
+		return err
+	}
+
+	res, err := db.ExecContext(ctx, `UPDATE recipes SET pinned_photo_id = $1
+			  WHERE id = $2 AND EXISTS(
+				SELECT 1 FROM review_images ri
+				JOIN reviews r ON ri.review_id = r.id
+				WHERE ri.id = $1 AND r.recipe_id = $2)`, photoId, recipeId)
+	if err != nil {
+		log.ErrorContext(ctx, "Error with pinning photo", sl.Err(err))
+		return err
+	}
+
+	if affected, _ := res.RowsAffected(); affected == 0 {
+		return repository.ErrPhotoNotFound
+	}
+
+	return nil
+}
+
+// SelectRatingDistribution returns how many reviews of every rating value
+// from 1 to 5 the recipe has.
+func (p *PostgresDashboardRepository) SelectRatingDistribution(ctx context.Context, recipeId int, log *slog.Logger) (map[int]int, error) {
+	ctx, cancel := readTimeout(ctx, p.Timeouts, "SelectRatingDistribution")
+	defer cancel()
+
+	distribution := map[int]int{1: 0, 2: 0, 3: 0, 4: 0, 5: 0}
+
+	rows, err := conn(ctx, p.DB).QueryContext(ctx, `SELECT rating_value, COUNT(*) FROM reviews
+			  WHERE recipe_id = $1 AND hidden_at IS NULL
+			  GROUP BY rating_value`, recipeId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var rating, count int
+		if err := rows.Scan(&rating, &count); err != nil {
+			log.ErrorContext(ctx, "Error scanning rating row", sl.Err(err))
+			continue
+		}
+		distribution[rating] = count
+	}
+
+	return distribution, nil
+}
+
+// VoteReview stores the user's helpfulness vote for the review and refreshes
+// the review counters. Value 0 removes the vote.
+func (p *PostgresDashboardRepository) VoteReview(ctx context.Context, vote structures.ReviewVote, log *slog.Logger) (structures.Review, error) {
+	ctx, cancel := writeTimeout(ctx, p.Timeouts, "VoteReview")
+	defer cancel()
+
+	var review structures.Review
+
+	tx, err := begin(ctx, p.DB)
+	if err != nil {
+		log.ErrorContext(ctx, "Error with starting transaction", sl.Err(err))
+		return review, err
+	}
+	defer tx.Rollback()
+
+	var reviewAuthorId int
+	err = tx.QueryRowContext(ctx, "SELECT author_id FROM reviews WHERE id = $1", vote.ReviewId).Scan(&reviewAuthorId)
+	if errors.Is(err, sql.ErrNoRows) {
+		return review, repository.ErrReviewNotFound
+	}
+	if err != nil {
+		log.ErrorContext(ctx, "Error with selecting review", sl.Err(err))
+		return review, err
+	}
+
+	if reviewAuthorId == vote.UserId {
+		return review, repository.ErrOwnReviewVote
+	}
+
+	if vote.Value == 0 {
+		_, err = tx.ExecContext(ctx, "DELETE FROM review_votes WHERE review_id = $1 AND user_id = $2", vote.ReviewId, vote.UserId)
+	} else {
+		_, err = tx.ExecContext(ctx, `INSERT INTO review_votes (review_id, user_id, value) VALUES ($1, $2, $3)
+				ON CONFLICT (review_id, user_id) DO UPDATE SET value = EXCLUDED.value`,
+			vote.ReviewId, vote.UserId, vote.Value)
+	}
+	if err != nil {
+		log.ErrorContext(ctx, "Error with saving review vote", sl.Err(err))
+		return review, err
+	}
+
+	err = tx.QueryRowContext(ctx, `
+			UPDATE reviews
+			SET helpful_up = (SELECT COUNT(*) FROM review_votes WHERE review_id = $1 AND value = 1),
+				helpful_down = (SELECT COUNT(*) FROM review_votes WHERE review_id = $1 AND value = -1)
+			WHERE id = $1
+			RETURNING id, review_text, rating_value, author_id, recipe_id, helpful_up, helpful_down, created_at
+	`, vote.ReviewId).Scan(&review.Id, &review.Text, &review.Rating_value, &review.Reviewed_by, &review.Recipe_id,
+		&review.Helpful_up, &review.Helpful_down, &review.Created_at)
+	if err != nil {
+		log.ErrorContext(ctx, "Error with updating review votes", sl.Err(err))
+		return review, err
+	}
+
+	return review, tx.Commit()
+}
@@ -0,0 +1,166 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/qwaq-dev/culina/pkg/logger/sl"
+	"github.com/qwaq-dev/culina/structures"
+)
+
+type PostgresImageRepository struct {
+	DB *sql.DB
+}
+
+// TouchBlob registers the uploaded blob or marks the existing one as just used,
+// so the images gc doesn't remove it before the recipe or review is saved.
+// Returns true if the blob is new, and the time it was touched.
+func (p *PostgresImageRepository) TouchBlob(blob structures.ImageBlob, log *slog.Logger) (bool, time.Time, error) {
+	var inserted bool
+	var touchedAt time.Time
+	err := p.DB.QueryRow(`INSERT INTO image_blobs (key, size, content_type) VALUES ($1, $2, $3)
+			  ON CONFLICT (key) DO UPDATE SET updated_at = NOW()
+			  RETURNING xmax = 0, updated_at`, blob.Key, blob.Size, blob.Content_type).Scan(&inserted, &touchedAt)
+	if err != nil {
+		log.Error("Error with saving image blob", slog.String("key", blob.Key), sl.Err(err))
+		return false, touchedAt, err
+	}
+
+	return inserted, touchedAt, nil
+}
+
+func (p *PostgresImageRepository) SelectBlobs(log *slog.Logger) ([]structures.ImageBlob, error) {
+	rows, err := p.DB.Query("SELECT key, size, content_type, ref_count, updated_at FROM image_blobs ORDER BY key")
+	if err != nil {
+		log.Error("Error with selecting image blobs", sl.Err(err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	var blobs []structures.ImageBlob
+	for rows.Next() {
+		var blob structures.ImageBlob
+		if err := rows.Scan(&blob.Key, &blob.Size, &blob.Content_type, &blob.Ref_count, &blob.Updated_at); err != nil {
+			log.Error("Error scanning image blob row", sl.Err(err))
+			continue
+		}
+		blobs = append(blobs, blob)
+	}
+
+	return blobs, nil
+}
+
+// selectImageRefsQuery counts how many times every file is used by recipe
+// images and videos and review photos. Images uploaded before variants
+// existed are counted by recipes.imgs and review_images.image_key.
+const selectImageRefsQuery = `
+	SELECT key, COUNT(*) FROM (
+		SELECT v.value->>'key' AS key FROM recipes r, jsonb_each(r.img_variants) i, jsonb_each(i.value) v
+		UNION ALL
+		SELECT v.value->>'webp' FROM recipes r, jsonb_each(r.img_variants) i, jsonb_each(i.value) v
+		UNION ALL
+		SELECT i.value FROM recipes r, jsonb_each_text(r.imgs) i WHERE r.img_variants = '{}'
+		UNION ALL
+		SELECT v->>'key' FROM recipes r, jsonb_array_elements(r.videos) v
+		UNION ALL
+		SELECT v.value->>'key' FROM review_images ri, jsonb_each(ri.variants) v
+		UNION ALL
+		SELECT v.value->>'webp' FROM review_images ri, jsonb_each(ri.variants) v
+		UNION ALL
+		SELECT image_key FROM review_images WHERE variants = '{}'
+	) refs
+	WHERE key IS NOT NULL AND key <> ''
+	GROUP BY key
+`
+
+// SelectImageRefs returns the number of references of every used file.
+func (p *PostgresImageRepository) SelectImageRefs(log *slog.Logger) (map[string]int, error) {
+	rows, err := p.DB.Query(selectImageRefsQuery)
+	if err != nil {
+		log.Error("Error with selecting image references", sl.Err(err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	refs := make(map[string]int)
+	for rows.Next() {
+		var key string
+		var count int
+		if err := rows.Scan(&key, &count); err != nil {
+			log.Error("Error scanning image reference row", sl.Err(err))
+			continue
+		}
+		refs[key] = count
+	}
+
+	return refs, nil
+}
+
+// UpdateBlobRefs fixes the reference count, unless it was changed since it was read.
+func (p *PostgresImageRepository) UpdateBlobRefs(key string, from, to int, log *slog.Logger) error {
+	_, err := p.DB.Exec("UPDATE image_blobs SET ref_count = $3 WHERE key = $1 AND ref_count = $2", key, from, to)
+	if err != nil {
+		log.Error("Error with updating image blob references", slog.String("key", key), sl.Err(err))
+		return err
+	}
+
+	return nil
+}
+
+// DeleteBlob removes the blob if it is still unreferenced and wasn't used
+// after olderThan. The row stays locked while remove deletes the file, so
+// an upload of the same image waits and stores the file again.
+func (p *PostgresImageRepository) DeleteBlob(key string, olderThan time.Time, remove func(key string) error, log *slog.Logger) (bool, error) {
+	tx, err := p.DB.Begin()
+	if err != nil {
+		log.Error("Error with starting transaction", sl.Err(err))
+		return false, err
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRow(`SELECT key FROM image_blobs
+			  WHERE key = $1 AND ref_count = 0 AND updated_at < $2
+			  FOR UPDATE SKIP LOCKED`, key, olderThan).Scan(&key)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		log.Error("Error with locking image blob", slog.String("key", key), sl.Err(err))
+		return false, err
+	}
+
+	if err := remove(key); err != nil {
+		log.Error("Error with removing image blob", slog.String("key", key), sl.Err(err))
+		return false, err
+	}
+
+	if _, err := tx.Exec("DELETE FROM image_blobs WHERE key = $1", key); err != nil {
+		log.Error("Error with deleting image blob", slog.String("key", key), sl.Err(err))
+		return false, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Error("Error with committing transaction", sl.Err(err))
+		return false, err
+	}
+
+	return true, nil
+}
+
+// changeBlobRefs adds delta to the reference counts of the stored files,
+// keys used several times are counted several times.
+func changeBlobRefs(ctx context.Context, db execer, keys []string, delta int) error {
+	if len(keys) == 0 {
+		return nil
+	}
+
+	_, err := db.ExecContext(ctx, `UPDATE image_blobs b
+			  SET ref_count = GREATEST(b.ref_count + $2 * k.n, 0), updated_at = NOW()
+			  FROM (SELECT key, COUNT(*) AS n FROM unnest($1::text[]) AS key GROUP BY key) k
+			  WHERE b.key = k.key`, pq.Array(keys), delta)
+	return err
+}
@@ -0,0 +1,68 @@
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+
+	"github.com/lib/pq"
+	"github.com/qwaq-dev/culina/pkg/logger/sl"
+)
+
+// SelectUnnormalizedIngredients returns ingredients of up to limit recipes
+// which have no normalized names yet, by recipe id.
+func (p *PostgresDashboardRepository) SelectUnnormalizedIngredients(limit int, log *slog.Logger) (map[int]map[string]string, error) {
+	rows, err := p.DB.Query("SELECT id, ingredients FROM recipes WHERE ingredient_names IS NULL ORDER BY id LIMIT $1", limit)
+	if err != nil {
+		log.Error("Error with selecting unnormalized ingredients", sl.Err(err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	ingredients := make(map[int]map[string]string)
+	for rows.Next() {
+		var id int
+		var ingredientsJSON []byte
+		if err := rows.Scan(&id, &ingredientsJSON); err != nil {
+			log.Error("Error scanning row", sl.Err(err))
+			return nil, err
+		}
+
+		recipeIngredients := make(map[string]string)
+		json.Unmarshal(ingredientsJSON, &recipeIngredients)
+		ingredients[id] = recipeIngredients
+	}
+
+	return ingredients, rows.Err()
+}
+
+// UpdateIngredientNames saves normalized names by recipe id and queues
+// the recipes for the search index.
+func (p *PostgresDashboardRepository) UpdateIngredientNames(names map[int][]string, log *slog.Logger) error {
+	tx, err := p.DB.Begin()
+	if err != nil {
+		log.Error("Error with starting transaction", sl.Err(err))
+		return err
+	}
+	defer tx.Rollback()
+
+	ids := make([]int, 0, len(names))
+	for id, recipeNames := range names {
+		if recipeNames == nil {
+			// NULL means not normalized yet
+			recipeNames = []string{}
+		}
+		if _, err := tx.Exec("UPDATE recipes SET ingredient_names = $1 WHERE id = $2", pq.Array(recipeNames), id); err != nil {
+			log.Error("Error with updating ingredient names", slog.Int("id", id), sl.Err(err))
+			return err
+		}
+		ids = append(ids, id)
+	}
+
+	if err := enqueueSearchSync(context.Background(), tx, ids...); err != nil {
+		log.Error("Error with enqueuing search sync", sl.Err(err))
+		return err
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,582 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/qwaq-dev/culina/internal/repository"
+	"github.com/qwaq-dev/culina/pkg/logger/sl"
+	"github.com/qwaq-dev/culina/structures"
+)
+
+type PostgresModerationRepository struct {
+	DB *sql.DB
+	// Content is hidden automatically when it gets this many unresolved reports.
+	AutoHideReports int
+}
+
+var targetTables = map[string]string{
+	structures.TargetRecipe:  "recipes",
+	structures.TargetReview:  "reviews",
+	structures.TargetComment: "comments",
+	structures.TargetUser:    "users",
+}
+
+var moderatorRoles = map[string]bool{
+	"Moderator": true,
+	"Admin":     true,
+}
+
+// InsertReport saves the report and hides the target when it reaches the
+// auto hide threshold. Returns true if the target was hidden by this report.
+func (r *PostgresModerationRepository) InsertReport(report structures.Report, log *slog.Logger) (structures.Report, bool, error) {
+	table, ok := targetTables[report.Target_type]
+	if !ok {
+		return report, false, repository.ErrInvalidTarget
+	}
+
+	tx, err := r.DB.Begin()
+	if err != nil {
+		log.Error("Error with starting transaction", sl.Err(err))
+		return report, false, err
+	}
+	defer tx.Rollback()
+
+	var exists bool
+	err = tx.QueryRow(fmt.Sprintf("SELECT EXISTS(SELECT 1 FROM %s WHERE id = $1)", table), report.Target_id).Scan(&exists)
+	if err != nil {
+		log.Error("Error with checking report target", sl.Err(err))
+		return report, false, err
+	}
+	if !exists {
+		return report, false, repository.ErrInvalidTarget
+	}
+
+	err = tx.QueryRow(`INSERT INTO reports (target_type, target_id, reporter_id, reason, details)
+			  VALUES ($1, $2, $3, $4, $5)
+			  ON CONFLICT (target_type, target_id, reporter_id) DO NOTHING
+			  RETURNING id, status, created_at`,
+		report.Target_type, report.Target_id, report.Reporter_id, report.Reason, report.Details).
+		Scan(&report.Id, &report.Status, &report.Created_at)
+	if errors.Is(err, sql.ErrNoRows) {
+		return report, false, repository.ErrReportExists
+	}
+	if err != nil {
+		log.Error("Error with inserting report", sl.Err(err))
+		return report, false, err
+	}
+
+	err = tx.QueryRow(`SELECT COUNT(*) FROM reports
+			  WHERE target_type = $1 AND target_id = $2 AND status <> 'resolved'`,
+		report.Target_type, report.Target_id).Scan(&report.Reports)
+	if err != nil {
+		log.Error("Error with counting reports", sl.Err(err))
+		return report, false, err
+	}
+
+	hidden := false
+	if r.AutoHideReports > 0 && report.Reports >= r.AutoHideReports && report.Target_type != structures.TargetUser {
+		hidden, err = hideTarget(context.Background(), tx, report.Target_type, report.Target_id)
+		if err != nil {
+			log.Error("Error with hiding reported content", sl.Err(err))
+			return report, false, err
+		}
+
+		if hidden {
+			action := structures.ModerationAction{
+				Report_id:   &report.Id,
+				Target_type: report.Target_type,
+				Target_id:   report.Target_id,
+				Action:      structures.ActionAutoHide,
+				Note:        fmt.Sprintf("%d reports", report.Reports),
+			}
+			if err := insertModerationAction(tx, action); err != nil {
+				log.Error("Error with saving moderation action", sl.Err(err))
+				return report, false, err
+			}
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Error("Error with committing transaction", sl.Err(err))
+		return report, false, err
+	}
+
+	if hidden {
+		log.Info("Content was hidden automatically", slog.String("type", report.Target_type), slog.Int("id", report.Target_id))
+	}
+
+	return report, hidden, nil
+}
+
+// SelectReportQueue returns reports with the given status, oldest first.
+func (r *PostgresModerationRepository) SelectReportQueue(moderatorId int, status string, page, pageSize int, log *slog.Logger) ([]structures.Report, error) {
+	if err := r.checkModerator(moderatorId, log); err != nil {
+		return nil, err
+	}
+
+	offset := (page - 1) * pageSize
+
+	query := `SELECT r.id, r.target_type, r.target_id, COALESCE(r.reporter_id, 0), r.reason, COALESCE(r.details, ''),
+				r.status, r.moderator_id, r.created_at,
+				(SELECT COUNT(*) FROM reports o
+				 WHERE o.target_type = r.target_type AND o.target_id = r.target_id AND o.status <> 'resolved')
+			  FROM reports r
+			  WHERE r.status = $1
+			  ORDER BY r.created_at, r.id
+			  LIMIT $2 OFFSET $3`
+
+	rows, err := r.DB.Query(query, status, pageSize, offset)
+	if err != nil {
+		log.Error("Error with selecting reports", sl.Err(err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	var reports []structures.Report
+	for rows.Next() {
+		var report structures.Report
+		var moderatorId sql.NullInt64
+
+		err := rows.Scan(&report.Id, &report.Target_type, &report.Target_id, &report.Reporter_id, &report.Reason,
+			&report.Details, &report.Status, &moderatorId, &report.Created_at, &report.Reports)
+		if err != nil {
+			log.Error("Error scanning report row", sl.Err(err))
+			continue
+		}
+
+		if moderatorId.Valid {
+			id := int(moderatorId.Int64)
+			report.Moderator_id = &id
+		}
+
+		reports = append(reports, report)
+	}
+
+	return reports, nil
+}
+
+func (r *PostgresModerationRepository) ClaimReport(reportId, moderatorId int, log *slog.Logger) error {
+	if err := r.checkModerator(moderatorId, log); err != nil {
+		return err
+	}
+
+	var status string
+	var claimedBy sql.NullInt64
+	err := r.DB.QueryRow("SELECT status, moderator_id FROM reports WHERE id = $1", reportId).Scan(&status, &claimedBy)
+	if errors.Is(err, sql.ErrNoRows) {
+		return repository.ErrReportNotFound
+	}
+	if err != nil {
+		log.Error("Error with selecting report", sl.Err(err))
+		return err
+	}
+
+	switch {
+	case status == "resolved":
+		return repository.ErrReportResolved
+	case status == "claimed" && claimedBy.Int64 != int64(moderatorId):
+		return repository.ErrReportClaimed
+	}
+
+	res, err := r.DB.Exec(`UPDATE reports SET status = 'claimed', moderator_id = $1, claimed_at = NOW()
+			  WHERE id = $2 AND (status = 'open' OR moderator_id = $1)`, moderatorId, reportId)
+	if err != nil {
+		log.Error("Error with claiming report", sl.Err(err))
+		return err
+	}
+
+	if affected, _ := res.RowsAffected(); affected == 0 {
+		return repository.ErrReportClaimed
+	}
+
+	return nil
+}
+
+// ResolveReport applies the moderator action to the reported content and
+// resolves all unresolved reports on the same target.
+func (r *PostgresModerationRepository) ResolveReport(reportId, moderatorId int, action, note string, log *slog.Logger) (structures.Report, error) {
+	var report structures.Report
+
+	if err := r.checkModerator(moderatorId, log); err != nil {
+		return report, err
+	}
+
+	tx, err := r.DB.Begin()
+	if err != nil {
+		log.Error("Error with starting transaction", sl.Err(err))
+		return report, err
+	}
+	defer tx.Rollback()
+
+	var claimedBy sql.NullInt64
+	err = tx.QueryRow(`SELECT id, target_type, target_id, COALESCE(reporter_id, 0), reason, status, moderator_id
+			  FROM reports WHERE id = $1 FOR UPDATE`, reportId).
+		Scan(&report.Id, &report.Target_type, &report.Target_id, &report.Reporter_id, &report.Reason, &report.Status, &claimedBy)
+	if errors.Is(err, sql.ErrNoRows) {
+		return report, repository.ErrReportNotFound
+	}
+	if err != nil {
+		log.Error("Error with selecting report", sl.Err(err))
+		return report, err
+	}
+
+	if report.Status == "resolved" {
+		return report, repository.ErrReportResolved
+	}
+	if report.Status == "claimed" && claimedBy.Int64 != int64(moderatorId) {
+		return report, repository.ErrReportClaimed
+	}
+
+	if err := applyModerationAction(context.Background(), tx, report, moderatorId, action); err != nil {
+		if !errors.Is(err, repository.ErrInvalidAction) && !errors.Is(err, repository.ErrReviewNotFound) {
+			log.Error("Error with applying moderation action", slog.String("action", action), sl.Err(err))
+		}
+		return report, err
+	}
+
+	_, err = tx.Exec(`UPDATE reports SET status = 'resolved', moderator_id = $1, resolved_at = NOW()
+			  WHERE target_type = $2 AND target_id = $3 AND status <> 'resolved'`,
+		moderatorId, report.Target_type, report.Target_id)
+	if err != nil {
+		log.Error("Error with resolving reports", sl.Err(err))
+		return report, err
+	}
+
+	err = insertModerationAction(tx, structures.ModerationAction{
+		Report_id:    &report.Id,
+		Moderator_id: &moderatorId,
+		Target_type:  report.Target_type,
+		Target_id:    report.Target_id,
+		Action:       action,
+		Note:         note,
+	})
+	if err != nil {
+		log.Error("Error with saving moderation action", sl.Err(err))
+		return report, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Error("Error with committing transaction", sl.Err(err))
+		return report, err
+	}
+
+	report.Status = "resolved"
+	report.Moderator_id = &moderatorId
+
+	log.Info("Report was resolved", slog.Int("id", report.Id), slog.String("action", action),
+		slog.Int("moderator_id", moderatorId))
+
+	return report, nil
+}
+
+// SelectModerationActions returns the audit trail, filtered by target when targetType is set.
+func (r *PostgresModerationRepository) SelectModerationActions(moderatorId int, targetType string, targetId int, log *slog.Logger) ([]structures.ModerationAction, error) {
+	if err := r.checkModerator(moderatorId, log); err != nil {
+		return nil, err
+	}
+
+	query := `SELECT id, report_id, moderator_id, target_type, target_id, action, COALESCE(note, ''), created_at
+			  FROM moderation_actions
+			  WHERE ($1 = '' OR (target_type = $1 AND target_id = $2))
+			  ORDER BY created_at DESC, id DESC
+			  LIMIT 200`
+
+	rows, err := r.DB.Query(query, targetType, targetId)
+	if err != nil {
+		log.Error("Error with selecting moderation actions", sl.Err(err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	var actions []structures.ModerationAction
+	for rows.Next() {
+		var action structures.ModerationAction
+		var reportId, actionModeratorId sql.NullInt64
+
+		err := rows.Scan(&action.Id, &reportId, &actionModeratorId, &action.Target_type, &action.Target_id,
+			&action.Action, &action.Note, &action.Created_at)
+		if err != nil {
+			log.Error("Error scanning moderation action row", sl.Err(err))
+			continue
+		}
+
+		if reportId.Valid {
+			id := int(reportId.Int64)
+			action.Report_id = &id
+		}
+		if actionModeratorId.Valid {
+			id := int(actionModeratorId.Int64)
+			action.Moderator_id = &id
+		}
+
+		actions = append(actions, action)
+	}
+
+	return actions, nil
+}
+
+func (r *PostgresModerationRepository) checkModerator(userId int, log *slog.Logger) error {
+	var role string
+	err := r.DB.QueryRow("SELECT role FROM users WHERE id = $1 AND banned_at IS NULL", userId).Scan(&role)
+	if errors.Is(err, sql.ErrNoRows) {
+		return repository.ErrNotModerator
+	}
+	if err != nil {
+		log.Error("Error with selecting user role", sl.Err(err))
+		return err
+	}
+
+	if !moderatorRoles[role] {
+		return repository.ErrNotModerator
+	}
+
+	return nil
+}
+
+func applyModerationAction(ctx context.Context, tx *sql.Tx, report structures.Report, moderatorId int, action string) error {
+	table := targetTables[report.Target_type]
+
+	switch action {
+	case structures.ActionDismiss:
+		return nil
+
+	case structures.ActionApprove:
+		if report.Target_type == structures.TargetUser {
+			return repository.ErrInvalidAction
+		}
+		return unhideTarget(ctx, tx, report.Target_type, report.Target_id)
+
+	case structures.ActionHide:
+		if report.Target_type == structures.TargetUser {
+			return repository.ErrInvalidAction
+		}
+		_, err := hideTarget(ctx, tx, report.Target_type, report.Target_id)
+		return err
+
+	case structures.ActionDelete:
+		keys, err := targetBlobKeys(ctx, tx, report.Target_type, report.Target_id)
+		if err != nil {
+			return err
+		}
+
+		if report.Target_type == structures.TargetReview {
+			var recipeId int
+			err := tx.QueryRowContext(ctx, "DELETE FROM reviews WHERE id = $1 RETURNING recipe_id", report.Target_id).Scan(&recipeId)
+			if errors.Is(err, sql.ErrNoRows) {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			if err := changeBlobRefs(ctx, tx, keys, -1); err != nil {
+				return err
+			}
+			return recountRecipeRating(ctx, tx, recipeId)
+		}
+		if err := enqueueTargetRecipes(ctx, tx, report.Target_type, report.Target_id); err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, fmt.Sprintf("DELETE FROM %s WHERE id = $1", table), report.Target_id); err != nil {
+			return err
+		}
+		// files themselves are removed by the images gc when nothing uses them
+		return changeBlobRefs(ctx, tx, keys, -1)
+
+	case structures.ActionWarn, structures.ActionBan:
+		userId, recipeId, commentId, err := targetAuthor(tx, report.Target_type, report.Target_id)
+		if err != nil {
+			return err
+		}
+
+		if action == structures.ActionBan {
+			_, err = tx.ExecContext(ctx, "UPDATE users SET banned_at = NOW() WHERE id = $1 AND banned_at IS NULL", userId)
+			return err
+		}
+
+		_, err = tx.ExecContext(ctx, "UPDATE users SET warnings_count = warnings_count + 1 WHERE id = $1", userId)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.ExecContext(ctx, `INSERT INTO notifications (user_id, kind, actor_id, recipe_id, comment_id)
+				  VALUES ($1, 'warning', $2, $3, $4)`, userId, moderatorId, recipeId, commentId)
+		return err
+	}
+
+	return repository.ErrInvalidAction
+}
+
+// hideTarget marks the content as hidden, returns false if it was already hidden.
+func hideTarget(ctx context.Context, tx *sql.Tx, targetType string, targetId int) (bool, error) {
+	table := targetTables[targetType]
+
+	if targetType == structures.TargetReview {
+		var recipeId int
+		err := tx.QueryRowContext(ctx, `UPDATE reviews SET hidden_at = NOW()
+				  WHERE id = $1 AND hidden_at IS NULL
+				  RETURNING recipe_id`, targetId).Scan(&recipeId)
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, nil
+		}
+		if err != nil {
+			return false, err
+		}
+
+		err = recountRecipeRating(ctx, tx, recipeId)
+		return err == nil, err
+	}
+
+	res, err := tx.ExecContext(ctx, fmt.Sprintf("UPDATE %s SET hidden_at = NOW() WHERE id = $1 AND hidden_at IS NULL", table), targetId)
+	if err != nil {
+		return false, err
+	}
+
+	affected, _ := res.RowsAffected()
+	if affected == 0 {
+		return false, nil
+	}
+
+	return true, enqueueTargetRecipes(ctx, tx, targetType, targetId)
+}
+
+func unhideTarget(ctx context.Context, tx *sql.Tx, targetType string, targetId int) error {
+	if targetType == structures.TargetReview {
+		var recipeId int
+		err := tx.QueryRowContext(ctx, "UPDATE reviews SET hidden_at = NULL WHERE id = $1 RETURNING recipe_id", targetId).Scan(&recipeId)
+		if errors.Is(err, sql.ErrNoRows) {
+			return repository.ErrReviewNotFound
+		}
+		if err != nil {
+			return err
+		}
+
+		return recountRecipeRating(ctx, tx, recipeId)
+	}
+
+	_, err := tx.ExecContext(ctx, fmt.Sprintf("UPDATE %s SET hidden_at = NULL WHERE id = $1", targetTables[targetType]), targetId)
+	if err != nil {
+		return err
+	}
+
+	return enqueueTargetRecipes(ctx, tx, targetType, targetId)
+}
+
+// enqueueTargetRecipes queues the recipe itself, or all recipes of the user
+// because deleting the user deletes them too. Comments don't change the index.
+func enqueueTargetRecipes(ctx context.Context, tx *sql.Tx, targetType string, targetId int) error {
+	switch targetType {
+	case structures.TargetRecipe:
+		return enqueueSearchSync(ctx, tx, targetId)
+	case structures.TargetUser:
+		_, err := tx.ExecContext(ctx, "INSERT INTO search_outbox (recipe_id) SELECT id FROM recipes WHERE author_id = $1", targetId)
+		return err
+	}
+	return nil
+}
+
+// targetBlobKeysQuery lists the files used by the recipes and reviews which
+// are deleted with the content, counted the same way as selectImageRefsQuery.
+// $1 is a recipe, $2 a review and $3 a user whose recipes and reviews go too.
+const targetBlobKeysQuery = `
+	WITH rs AS (
+		SELECT id, imgs, img_variants, videos FROM recipes WHERE id = $1 OR author_id = $3
+	), rv AS (
+		SELECT id FROM reviews WHERE id = $2 OR author_id = $3 OR recipe_id IN (SELECT id FROM rs)
+	)
+	SELECT key FROM (
+		SELECT v.value->>'key' AS key FROM rs r, jsonb_each(r.img_variants) i, jsonb_each(i.value) v
+		UNION ALL
+		SELECT v.value->>'webp' FROM rs r, jsonb_each(r.img_variants) i, jsonb_each(i.value) v
+		UNION ALL
+		SELECT i.value FROM rs r, jsonb_each_text(r.imgs) i WHERE r.img_variants = '{}'
+		UNION ALL
+		SELECT v->>'key' FROM rs r, jsonb_array_elements(r.videos) v
+		UNION ALL
+		SELECT v.value->>'key' FROM review_images ri, jsonb_each(ri.variants) v WHERE ri.review_id IN (SELECT id FROM rv)
+		UNION ALL
+		SELECT v.value->>'webp' FROM review_images ri, jsonb_each(ri.variants) v WHERE ri.review_id IN (SELECT id FROM rv)
+		UNION ALL
+		SELECT image_key FROM review_images WHERE variants = '{}' AND review_id IN (SELECT id FROM rv)
+	) refs
+	WHERE key IS NOT NULL AND key <> ''
+`
+
+// targetBlobKeys returns keys of the files whose references go away when the
+// content is deleted. Comments have no files.
+func targetBlobKeys(ctx context.Context, tx *sql.Tx, targetType string, targetId int) ([]string, error) {
+	var recipeId, reviewId, userId int
+	switch targetType {
+	case structures.TargetRecipe:
+		recipeId = targetId
+	case structures.TargetReview:
+		reviewId = targetId
+	case structures.TargetUser:
+		userId = targetId
+	default:
+		return nil, nil
+	}
+
+	rows, err := tx.QueryContext(ctx, targetBlobKeysQuery, recipeId, reviewId, userId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var keys []string
+	for rows.Next() {
+		var key string
+		if err := rows.Scan(&key); err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+
+	return keys, rows.Err()
+}
+
+// targetAuthor returns the user responsible for the content with the recipe
+// and comment it belongs to, if any.
+func targetAuthor(tx *sql.Tx, targetType string, targetId int) (int, sql.NullInt64, sql.NullInt64, error) {
+	var userId int
+	var recipeId, commentId sql.NullInt64
+	var err error
+
+	switch targetType {
+	case structures.TargetRecipe:
+		err = tx.QueryRow("SELECT author_id, id FROM recipes WHERE id = $1", targetId).Scan(&userId, &recipeId)
+	case structures.TargetReview:
+		err = tx.QueryRow("SELECT author_id, recipe_id FROM reviews WHERE id = $1", targetId).Scan(&userId, &recipeId)
+	case structures.TargetComment:
+		err = tx.QueryRow("SELECT author_id, recipe_id, id FROM comments WHERE id = $1", targetId).Scan(&userId, &recipeId, &commentId)
+	case structures.TargetUser:
+		err = tx.QueryRow("SELECT id FROM users WHERE id = $1", targetId).Scan(&userId)
+	}
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, recipeId, commentId, repository.ErrInvalidTarget
+	}
+
+	return userId, recipeId, commentId, err
+}
+
+func insertModerationAction(tx *sql.Tx, action structures.ModerationAction) error {
+	_, err := tx.Exec(`INSERT INTO moderation_actions (report_id, moderator_id, target_type, target_id, action, note)
+			  VALUES ($1, $2, $3, $4, $5, $6)`,
+		action.Report_id, action.Moderator_id, action.Target_type, action.Target_id, action.Action, action.Note)
+	return err
+}
+
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+// insertAutoReport puts content held by the text filter into the moderation queue.
+func insertAutoReport(ctx context.Context, db execer, targetType string, targetId int, details string) error {
+	_, err := db.ExecContext(ctx, `INSERT INTO reports (target_type, target_id, reason, details)
+			  VALUES ($1, $2, 'auto_filter', $3)`, targetType, targetId, details)
+	return err
+}
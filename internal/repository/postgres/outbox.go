@@ -0,0 +1,98 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/qwaq-dev/culina/pkg/logger/sl"
+	"github.com/qwaq-dev/culina/structures"
+)
+
+type PostgresOutboxRepository struct {
+	DB *sql.DB
+}
+
+// enqueueSearchSync asks the relay to update search documents of the recipes.
+// It must be called in the transaction that changes them.
+func enqueueSearchSync(ctx context.Context, db execer, recipeIds ...int) error {
+	if len(recipeIds) == 0 {
+		return nil
+	}
+
+	_, err := db.ExecContext(ctx, "INSERT INTO search_outbox (recipe_id) SELECT unnest($1::int[])", pq.Array(recipeIds))
+	return err
+}
+
+// recountRecipeRating recalculates the rating after a review change and
+// queues the recipe for the search index, which shows the rating.
+func recountRecipeRating(ctx context.Context, db execer, recipeId int) error {
+	if _, err := db.ExecContext(ctx, updateRecipeRatingQuery, recipeId); err != nil {
+		return err
+	}
+
+	return enqueueSearchSync(ctx, db, recipeId)
+}
+
+func (r *PostgresOutboxRepository) InsertSearchEvents(recipeIds []int, log *slog.Logger) error {
+	if err := enqueueSearchSync(context.Background(), r.DB, recipeIds...); err != nil {
+		log.Error("Error with inserting search events", sl.Err(err))
+		return err
+	}
+
+	return nil
+}
+
+// ClaimSearchEvents returns up to limit due events and hides them from other
+// relays for lease. Events not removed or retried before that are claimed again.
+func (r *PostgresOutboxRepository) ClaimSearchEvents(limit int, lease time.Duration, log *slog.Logger) ([]structures.SearchEvent, error) {
+	rows, err := r.DB.Query(`UPDATE search_outbox
+			  SET attempts = attempts + 1, available_at = NOW() + $2 * INTERVAL '1 second'
+			  WHERE id IN (SELECT id FROM search_outbox
+				WHERE available_at <= NOW()
+				ORDER BY id
+				LIMIT $1
+				FOR UPDATE SKIP LOCKED)
+			  RETURNING id, recipe_id, attempts, COALESCE(last_error, '')`, limit, int(lease.Seconds()))
+	if err != nil {
+		log.Error("Error with claiming search events", sl.Err(err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	var events []structures.SearchEvent
+	for rows.Next() {
+		var event structures.SearchEvent
+		if err := rows.Scan(&event.Id, &event.Recipe_id, &event.Attempts, &event.Last_error); err != nil {
+			log.Error("Error scanning search event row", sl.Err(err))
+			return nil, err
+		}
+		events = append(events, event)
+	}
+
+	return events, rows.Err()
+}
+
+func (r *PostgresOutboxRepository) DeleteSearchEvents(ids []int64, log *slog.Logger) error {
+	if _, err := r.DB.Exec("DELETE FROM search_outbox WHERE id = ANY($1)", pq.Array(ids)); err != nil {
+		log.Error("Error with deleting search events", sl.Err(err))
+		return err
+	}
+
+	return nil
+}
+
+// RetrySearchEvent makes the event due again after delay.
+func (r *PostgresOutboxRepository) RetrySearchEvent(id int64, delay time.Duration, cause string, log *slog.Logger) error {
+	_, err := r.DB.Exec(`UPDATE search_outbox
+			  SET available_at = NOW() + $2 * INTERVAL '1 second', last_error = $3
+			  WHERE id = $1`, id, int(delay.Seconds()), cause)
+	if err != nil {
+		log.Error("Error with retrying search event", sl.Err(err))
+		return err
+	}
+
+	return nil
+}
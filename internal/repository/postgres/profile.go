@@ -1,15 +1,20 @@
 package postgres
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"log/slog"
 
+	"github.com/lib/pq"
+	"github.com/qwaq-dev/culina/pkg/config"
+	"github.com/qwaq-dev/culina/pkg/logger/sl"
 	"github.com/qwaq-dev/culina/structures"
 )
 
 type PostgresProfileRepository struct {
-	DB *sql.DB
+	DB       *sql.DB
+	Timeouts config.QueryTimeouts
 }
 
 var allowedColumns = map[string]string{
@@ -18,7 +23,10 @@ var allowedColumns = map[string]string{
 	"password": "password",
 }
 
-func (r *PostgresProfileRepository) ChangeProfileData(column, newData, username string, log *slog.Logger) (*structures.User, error) {
+func (r *PostgresProfileRepository) ChangeProfileData(ctx context.Context, column, newData, username string, log *slog.Logger) (*structures.User, error) {
+	ctx, cancel := writeTimeout(ctx, r.Timeouts, "ChangeProfileData")
+	defer cancel()
+
 	user := new(structures.User)
 
 	col, ok := allowedColumns[column]
@@ -26,16 +34,68 @@ func (r *PostgresProfileRepository) ChangeProfileData(column, newData, username
 		return nil, fmt.Errorf("invalid column name")
 	}
 
-	query := fmt.Sprintf("UPDATE users SET %s = $1 WHERE username = $2 RETURNING *", col)
-	err := r.DB.QueryRow(query, newData, username).Scan(&user.Id, &user.Email, &user.Username, &user.Password, &user.Role, &user.Sex, &user.Recipes_count)
+	query := fmt.Sprintf(`UPDATE users SET %s = $1 WHERE username = $2
+			RETURNING id, email, username, password, role, sex, recipes_count, banned_at IS NOT NULL`, col)
+	err := conn(ctx, r.DB).QueryRowContext(ctx, query, newData, username).Scan(&user.Id, &user.Email, &user.Username, &user.Password, &user.Role, &user.Sex, &user.Recipes_count, &user.Banned)
 	if err != nil {
-		log.Error("Error with updating user data")
+		log.ErrorContext(ctx, "Error with updating user data")
 		return nil, err
 	}
 
 	return user, nil
 }
 
-func (r *PostgresProfileRepository) InsertUserRecipes(user *structures.User, log *slog.Logger) (*structures.Recipes, error) {
+func (r *PostgresProfileRepository) InsertUserRecipes(ctx context.Context, user *structures.User, log *slog.Logger) (*structures.Recipes, error) {
 	return nil, nil
 }
+
+func (r *PostgresProfileRepository) SelectNotifications(ctx context.Context, userId int, unreadOnly bool, log *slog.Logger) ([]structures.Notification, error) {
+	ctx, cancel := readTimeout(ctx, r.Timeouts, "SelectNotifications")
+	defer cancel()
+
+	query := `SELECT n.id, n.user_id, n.kind, COALESCE(n.actor_id, 0), COALESCE(u.username, ''),
+				COALESCE(n.recipe_id, 0), COALESCE(n.comment_id, 0), n.read_at IS NOT NULL, n.created_at
+			  FROM notifications n
+			  LEFT JOIN users u ON n.actor_id = u.id
+			  WHERE n.user_id = $1 AND (NOT $2 OR n.read_at IS NULL)
+			  ORDER BY n.created_at DESC
+			  LIMIT 100`
+
+	rows, err := conn(ctx, r.DB).QueryContext(ctx, query, userId, unreadOnly)
+	if err != nil {
+		log.ErrorContext(ctx, "Error with selecting notifications", sl.Err(err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	var notifications []structures.Notification
+	for rows.Next() {
+		var n structures.Notification
+		err := rows.Scan(&n.Id, &n.User_id, &n.Kind, &n.Actor_id, &n.ActorName, &n.Recipe_id,
+			&n.Comment_id, &n.Read, &n.Created_at)
+		if err != nil {
+			log.ErrorContext(ctx, "Error scanning notification row", sl.Err(err))
+			continue
+		}
+		notifications = append(notifications, n)
+	}
+
+	return notifications, nil
+}
+
+// MarkNotificationsRead marks given notifications of the user as read,
+// all of them when ids is empty.
+func (r *PostgresProfileRepository) MarkNotificationsRead(ctx context.Context, userId int, ids []int, log *slog.Logger) error {
+	ctx, cancel := writeTimeout(ctx, r.Timeouts, "MarkNotificationsRead")
+	defer cancel()
+
+	_, err := conn(ctx, r.DB).ExecContext(ctx, `UPDATE notifications SET read_at = NOW()
+			  WHERE user_id = $1 AND read_at IS NULL
+				AND (cardinality($2::int[]) = 0 OR id = ANY($2))`, userId, pq.Array(ids))
+	if err != nil {
+		log.ErrorContext(ctx, "Error with marking notifications", sl.Err(err))
+		return err
+	}
+
+	return nil
+}
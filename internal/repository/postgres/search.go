@@ -0,0 +1,210 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/lib/pq"
+	"github.com/qwaq-dev/culina/pkg/logger/sl"
+	"github.com/qwaq-dev/culina/structures"
+)
+
+// PostgresSearchIndex searches recipes by the generated recipes.search_vector
+// column. The column follows the recipe, so there is nothing to index.
+type PostgresSearchIndex struct {
+	DB  *sql.DB
+	Log *slog.Logger
+}
+
+const searchColumns = `r.id, r.name, r.descr, r.diff, r.cook_time, r.calories, r.filters, r.imgs, r.img_placeholders,
+	r.author_id, r.ingredients, r.ingredient_names, r.steps, r.lang, r.translations, r.review_count, r.avg_rating`
+
+func (p *PostgresSearchIndex) IndexRecipe(recipe structures.Recipes) error { return nil }
+
+func (p *PostgresSearchIndex) IndexRecipeById(id int) error { return nil }
+
+func (p *PostgresSearchIndex) DeleteRecipe(id int) error { return nil }
+
+func (p *PostgresSearchIndex) Init() error { return nil }
+
+func (p *PostgresSearchIndex) Reindex() error { return nil }
+
+// SyncRules does nothing, synonyms, overrides and stop words are only used by Typesense.
+func (p *PostgresSearchIndex) SyncRules() error { return nil }
+
+func (p *PostgresSearchIndex) Healthy(ctx context.Context) bool {
+	return p.DB.PingContext(ctx) == nil
+}
+
+// maxFacetValues limits values counted per facet, as in Typesense.
+const maxFacetValues = 50
+
+// searchFilter collects WHERE conditions with their positional arguments.
+type searchFilter struct {
+	conditions []string
+	args       []any
+}
+
+// add appends the condition, %s in it is replaced by the placeholder of arg.
+func (f *searchFilter) add(condition string, arg any) {
+	f.args = append(f.args, arg)
+	f.conditions = append(f.conditions, fmt.Sprintf(condition, "$"+strconv.Itoa(len(f.args))))
+}
+
+func newSearchFilter(query structures.SearchQuery) *searchFilter {
+	f := &searchFilter{conditions: []string{"r.hidden_at IS NULL", "NOT r.draft"}}
+
+	if strings.TrimSpace(query.Q) != "" {
+		f.add("r.search_vector @@ (websearch_to_tsquery('russian', %[1]s) || websearch_to_tsquery('english', %[1]s))", query.Q)
+	}
+	if len(query.Filters) > 0 {
+		if query.Match == structures.MatchAny {
+			f.add("r.filters ?| %s", pq.Array(query.Filters))
+		} else {
+			filtersJSON, _ := json.Marshal(query.Filters)
+			f.add("r.filters @> %s::jsonb", string(filtersJSON))
+		}
+	}
+	if len(query.Diff) > 0 {
+		f.add("r.diff = ANY(%s)", pq.Array(query.Diff))
+	}
+	if len(query.With) > 0 {
+		f.add("r.ingredient_names @> %s", pq.Array(query.With))
+	}
+	if len(query.Without) > 0 {
+		f.add("NOT COALESCE(r.ingredient_names && %s, false)", pq.Array(query.Without))
+	}
+	if query.AuthorId > 0 {
+		f.add("r.author_id = %s", query.AuthorId)
+	}
+	if query.MinRating > 0 {
+		f.add("r.avg_rating >= %s", query.MinRating)
+	}
+	if query.MaxTime > 0 {
+		f.add("r.cook_time BETWEEN 1 AND %s", query.MaxTime)
+	}
+	if query.MinCalories > 0 {
+		f.add("r.calories >= %s", query.MinCalories)
+	}
+	if query.MaxCalories > 0 {
+		f.add("r.calories BETWEEN 1 AND %s", query.MaxCalories)
+	}
+
+	return f
+}
+
+func (f *searchFilter) where() string {
+	return strings.Join(f.conditions, " AND ")
+}
+
+// Search matches the query against names and descriptions, the best ranked
+// first, or the newest first without a text query.
+func (p *PostgresSearchIndex) Search(query structures.SearchQuery) (structures.SearchResult, error) {
+	result := structures.SearchResult{Page: query.Page, Recipes: []structures.TypesenseRecipe{}}
+	filter := newSearchFilter(query)
+	where := filter.where()
+
+	if err := p.DB.QueryRow("SELECT COUNT(*) FROM recipes r WHERE "+where, filter.args...).Scan(&result.Found); err != nil {
+		p.Log.Error("Error with counting found recipes", sl.Err(err))
+		return result, err
+	}
+
+	args := slices.Clone(filter.args)
+	order := "r.id DESC"
+	if strings.TrimSpace(query.Q) != "" {
+		// recipes in the user's language or translated to it go first,
+		// the text query is always the first argument
+		args = append(args, query.Lang)
+		order = fmt.Sprintf(`r.lang = $%[1]d OR r.translations ? $%[1]d DESC,
+			ts_rank(r.search_vector, websearch_to_tsquery('russian', $1) || websearch_to_tsquery('english', $1)) DESC, r.id DESC`, len(args))
+	}
+
+	args = append(args, query.PerPage, (query.Page-1)*query.PerPage)
+	rows, err := p.DB.Query(fmt.Sprintf(`SELECT %s FROM recipes r WHERE %s ORDER BY %s LIMIT $%d OFFSET $%d`,
+		searchColumns, where, order, len(args)-1, len(args)), args...)
+	if err != nil {
+		p.Log.Error("Error with searching recipes", sl.Err(err))
+		return result, err
+	}
+	defer rows.Close()
+
+	if result.Recipes, err = p.scanRecipes(rows, query.Lang); err != nil {
+		return result, err
+	}
+
+	result.Facets = make(map[string][]structures.FacetCount)
+	facetQueries := map[string]string{
+		"filters":          "SELECT f, COUNT(*) FROM recipes r, jsonb_array_elements_text(r.filters) f WHERE %s GROUP BY f ORDER BY 2 DESC, 1 LIMIT %d",
+		"diff":             "SELECT r.diff, COUNT(*) FROM recipes r WHERE %s GROUP BY r.diff ORDER BY 2 DESC, 1 LIMIT %d",
+		"ingredient_names": "SELECT i, COUNT(*) FROM recipes r, unnest(r.ingredient_names) i WHERE %s GROUP BY i ORDER BY 2 DESC, 1 LIMIT %d",
+	}
+	for field, facetQuery := range facetQueries {
+		counts, err := p.selectFacet(fmt.Sprintf(facetQuery, where, maxFacetValues), filter.args)
+		if err != nil {
+			return result, err
+		}
+		result.Facets[field] = counts
+	}
+
+	return result, nil
+}
+
+func (p *PostgresSearchIndex) selectFacet(query string, args []any) ([]structures.FacetCount, error) {
+	rows, err := p.DB.Query(query, args...)
+	if err != nil {
+		p.Log.Error("Error with counting facet", sl.Err(err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := []structures.FacetCount{}
+	for rows.Next() {
+		var count structures.FacetCount
+		if err := rows.Scan(&count.Value, &count.Count); err != nil {
+			p.Log.Error("Error scanning facet row", sl.Err(err))
+			return nil, err
+		}
+		counts = append(counts, count)
+	}
+
+	return counts, rows.Err()
+}
+
+// scanRecipes converts rows to the Typesense documents translated to lang,
+// so results don't depend on which index answered.
+func (p *PostgresSearchIndex) scanRecipes(rows *sql.Rows, lang string) ([]structures.TypesenseRecipe, error) {
+	recipes := []structures.TypesenseRecipe{}
+	for rows.Next() {
+		var recipe structures.Recipes
+		var filtersJSON, imgsJSON, placeholdersJSON, ingredientsJSON, stepsJSON, translationsJSON []byte
+
+		err := rows.Scan(&recipe.Id, &recipe.Name, &recipe.Descr, &recipe.Diff, &recipe.Cook_time, &recipe.Calories, &filtersJSON, &imgsJSON,
+			&placeholdersJSON, &recipe.AuthorID, &ingredientsJSON, pq.Array(&recipe.Ingredient_names), &stepsJSON, &recipe.Lang, &translationsJSON, &recipe.Review_count, &recipe.Avg_rating)
+		if err != nil {
+			p.Log.Error("Error scanning recipe row", sl.Err(err))
+			continue
+		}
+
+		json.Unmarshal(filtersJSON, &recipe.Filters)
+		json.Unmarshal(imgsJSON, &recipe.Imgs)
+		json.Unmarshal(placeholdersJSON, &recipe.Img_placeholders)
+		json.Unmarshal(ingredientsJSON, &recipe.Ingredients)
+		json.Unmarshal(stepsJSON, &recipe.Steps)
+		json.Unmarshal(translationsJSON, &recipe.Translations)
+
+		doc, err := recipe.ToTypesense()
+		if err != nil {
+			p.Log.Error("Error converting recipe", sl.Err(err))
+			continue
+		}
+		recipes = append(recipes, doc.Localized(lang))
+	}
+
+	return recipes, rows.Err()
+}
@@ -0,0 +1,259 @@
+package postgres
+
+import (
+	"database/sql"
+	"errors"
+	"log/slog"
+
+	"github.com/lib/pq"
+	"github.com/qwaq-dev/culina/internal/repository"
+	"github.com/qwaq-dev/culina/pkg/logger/sl"
+	"github.com/qwaq-dev/culina/structures"
+)
+
+type PostgresSearchRulesRepository struct {
+	DB *sql.DB
+}
+
+const (
+	synonymColumns  = "id, COALESCE(root, ''), synonyms, COALESCE(updated_by, 0), updated_at"
+	overrideColumns = "id, query, match, pinned, hidden, COALESCE(updated_by, 0), updated_at"
+)
+
+func (r *PostgresSearchRulesRepository) CheckAdmin(userId int, log *slog.Logger) error {
+	return checkAdmin(r.DB, userId, log)
+}
+
+func checkAdmin(db *sql.DB, userId int, log *slog.Logger) error {
+	var role string
+	err := db.QueryRow("SELECT role FROM users WHERE id = $1 AND banned_at IS NULL", userId).Scan(&role)
+	if errors.Is(err, sql.ErrNoRows) {
+		return repository.ErrNotAdmin
+	}
+	if err != nil {
+		log.Error("Error with selecting user role", sl.Err(err))
+		return err
+	}
+
+	if role != "Admin" {
+		return repository.ErrNotAdmin
+	}
+
+	return nil
+}
+
+func (r *PostgresSearchRulesRepository) SelectSynonyms(log *slog.Logger) ([]structures.SearchSynonym, error) {
+	rows, err := r.DB.Query("SELECT " + synonymColumns + " FROM search_synonyms ORDER BY id")
+	if err != nil {
+		log.Error("Error with selecting synonyms", sl.Err(err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	synonyms := []structures.SearchSynonym{}
+	for rows.Next() {
+		synonym, err := scanSynonym(rows)
+		if err != nil {
+			log.Error("Error scanning synonym row", sl.Err(err))
+			return nil, err
+		}
+		synonyms = append(synonyms, synonym)
+	}
+
+	return synonyms, rows.Err()
+}
+
+func (r *PostgresSearchRulesRepository) InsertSynonym(synonym structures.SearchSynonym, log *slog.Logger) (structures.SearchSynonym, error) {
+	row := r.DB.QueryRow(`INSERT INTO search_synonyms (root, synonyms, updated_by)
+			  VALUES (NULLIF($1, ''), $2, $3)
+			  RETURNING `+synonymColumns,
+		synonym.Root, pq.Array(synonym.Synonyms), synonym.Updated_by)
+
+	created, err := scanSynonym(row)
+	if err != nil {
+		log.Error("Error with inserting synonym", sl.Err(err))
+		return created, err
+	}
+
+	return created, nil
+}
+
+func (r *PostgresSearchRulesRepository) UpdateSynonym(synonym structures.SearchSynonym, log *slog.Logger) (structures.SearchSynonym, error) {
+	row := r.DB.QueryRow(`UPDATE search_synonyms
+			  SET root = NULLIF($2, ''), synonyms = $3, updated_by = $4, updated_at = NOW()
+			  WHERE id = $1
+			  RETURNING `+synonymColumns,
+		synonym.Id, synonym.Root, pq.Array(synonym.Synonyms), synonym.Updated_by)
+
+	updated, err := scanSynonym(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return updated, repository.ErrSynonymNotFound
+	}
+	if err != nil {
+		log.Error("Error with updating synonym", sl.Err(err))
+		return updated, err
+	}
+
+	return updated, nil
+}
+
+func (r *PostgresSearchRulesRepository) DeleteSynonym(id int, log *slog.Logger) error {
+	return r.deleteRule("search_synonyms", id, repository.ErrSynonymNotFound, log)
+}
+
+func (r *PostgresSearchRulesRepository) SelectOverrides(log *slog.Logger) ([]structures.SearchOverride, error) {
+	rows, err := r.DB.Query("SELECT " + overrideColumns + " FROM search_overrides ORDER BY id")
+	if err != nil {
+		log.Error("Error with selecting overrides", sl.Err(err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	overrides := []structures.SearchOverride{}
+	for rows.Next() {
+		override, err := scanOverride(rows)
+		if err != nil {
+			log.Error("Error scanning override row", sl.Err(err))
+			return nil, err
+		}
+		overrides = append(overrides, override)
+	}
+
+	return overrides, rows.Err()
+}
+
+func (r *PostgresSearchRulesRepository) InsertOverride(override structures.SearchOverride, log *slog.Logger) (structures.SearchOverride, error) {
+	row := r.DB.QueryRow(`INSERT INTO search_overrides (query, match, pinned, hidden, updated_by)
+			  VALUES ($1, $2, $3, $4, $5)
+			  RETURNING `+overrideColumns,
+		override.Query, override.Match, pq.Array(toInt64s(override.Pinned)), pq.Array(toInt64s(override.Hidden)), override.Updated_by)
+
+	created, err := scanOverride(row)
+	if err != nil {
+		log.Error("Error with inserting override", sl.Err(err))
+		return created, err
+	}
+
+	return created, nil
+}
+
+func (r *PostgresSearchRulesRepository) UpdateOverride(override structures.SearchOverride, log *slog.Logger) (structures.SearchOverride, error) {
+	row := r.DB.QueryRow(`UPDATE search_overrides
+			  SET query = $2, match = $3, pinned = $4, hidden = $5, updated_by = $6, updated_at = NOW()
+			  WHERE id = $1
+			  RETURNING `+overrideColumns,
+		override.Id, override.Query, override.Match, pq.Array(toInt64s(override.Pinned)), pq.Array(toInt64s(override.Hidden)),
+		override.Updated_by)
+
+	updated, err := scanOverride(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return updated, repository.ErrOverrideNotFound
+	}
+	if err != nil {
+		log.Error("Error with updating override", sl.Err(err))
+		return updated, err
+	}
+
+	return updated, nil
+}
+
+func (r *PostgresSearchRulesRepository) DeleteOverride(id int, log *slog.Logger) error {
+	return r.deleteRule("search_overrides", id, repository.ErrOverrideNotFound, log)
+}
+
+func (r *PostgresSearchRulesRepository) SelectStopwords(log *slog.Logger) ([]string, error) {
+	words := []string{}
+	rows, err := r.DB.Query("SELECT word FROM search_stopwords ORDER BY word")
+	if err != nil {
+		log.Error("Error with selecting stop words", sl.Err(err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var word string
+		if err := rows.Scan(&word); err != nil {
+			log.Error("Error scanning stop word row", sl.Err(err))
+			return nil, err
+		}
+		words = append(words, word)
+	}
+
+	return words, rows.Err()
+}
+
+// ReplaceStopwords makes words the only stop words.
+func (r *PostgresSearchRulesRepository) ReplaceStopwords(words []string, adminId int, log *slog.Logger) error {
+	tx, err := r.DB.Begin()
+	if err != nil {
+		log.Error("Error with starting transaction", sl.Err(err))
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DELETE FROM search_stopwords WHERE word <> ALL($1)", pq.Array(words)); err != nil {
+		log.Error("Error with deleting stop words", sl.Err(err))
+		return err
+	}
+
+	_, err = tx.Exec(`INSERT INTO search_stopwords (word, updated_by)
+			  SELECT unnest($1::text[]), $2
+			  ON CONFLICT (word) DO NOTHING`, pq.Array(words), adminId)
+	if err != nil {
+		log.Error("Error with inserting stop words", sl.Err(err))
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// deleteRule deletes the row by id from one of the rule tables.
+func (r *PostgresSearchRulesRepository) deleteRule(table string, id int, notFound error, log *slog.Logger) error {
+	res, err := r.DB.Exec("DELETE FROM "+table+" WHERE id = $1", id)
+	if err != nil {
+		log.Error("Error with deleting search rule", slog.String("table", table), sl.Err(err))
+		return err
+	}
+
+	if n, _ := res.RowsAffected(); n == 0 {
+		return notFound
+	}
+	return nil
+}
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanSynonym(row scanner) (structures.SearchSynonym, error) {
+	var synonym structures.SearchSynonym
+	err := row.Scan(&synonym.Id, &synonym.Root, pq.Array(&synonym.Synonyms), &synonym.Updated_by, &synonym.Updated_at)
+	return synonym, err
+}
+
+func scanOverride(row scanner) (structures.SearchOverride, error) {
+	var override structures.SearchOverride
+	var pinned, hidden []int64
+
+	err := row.Scan(&override.Id, &override.Query, &override.Match, pq.Array(&pinned), pq.Array(&hidden),
+		&override.Updated_by, &override.Updated_at)
+	override.Pinned = toInts(pinned)
+	override.Hidden = toInts(hidden)
+	return override, err
+}
+
+func toInt64s(values []int) []int64 {
+	converted := make([]int64, len(values))
+	for i, v := range values {
+		converted[i] = int64(v)
+	}
+	return converted
+}
+
+func toInts(values []int64) []int {
+	converted := make([]int, len(values))
+	for i, v := range values {
+		converted[i] = int(v)
+	}
+	return converted
+}
@@ -0,0 +1,55 @@
+package postgres
+
+import (
+	"database/sql"
+	"log/slog"
+
+	"github.com/qwaq-dev/culina/pkg/logger/sl"
+	"github.com/qwaq-dev/culina/structures"
+)
+
+type PostgresSuggestRepository struct {
+	DB *sql.DB
+}
+
+// maxQueryLength is the longest logged query suggested.
+const maxQueryLength = 100
+
+// SelectSuggestionTerms returns names of published recipes with their
+// translations, their ingredients and tags, and logged queries which found
+// recipes at least minQueryCount times.
+func (r *PostgresSuggestRepository) SelectSuggestionTerms(minQueryCount int, log *slog.Logger) ([]structures.Suggestion, error) {
+	rows, err := r.DB.Query(`
+		WITH published AS (
+			SELECT * FROM recipes WHERE hidden_at IS NULL AND NOT draft
+		)
+		SELECT n, $2::text, MAX(review_count) + 1
+			FROM published p, LATERAL (SELECT p.name UNION SELECT t.value->>'name' FROM jsonb_each(p.translations) t) names(n)
+			WHERE n <> '' GROUP BY n
+		UNION ALL
+		SELECT i, $3::text, COUNT(*) FROM published p, unnest(p.ingredient_names) i GROUP BY i
+		UNION ALL
+		SELECT f, $4::text, COUNT(*) FROM published p, jsonb_array_elements_text(p.filters) f GROUP BY f
+		UNION ALL
+		SELECT query, $5::text, COUNT(*) FROM search_log
+			WHERE found > 0 AND query <> '' AND char_length(query) <= $6
+			GROUP BY query HAVING COUNT(*) >= $1`,
+		minQueryCount, structures.SuggestRecipe, structures.SuggestIngredient, structures.SuggestTag, structures.SuggestQuery, maxQueryLength)
+	if err != nil {
+		log.Error("Error with selecting suggestion terms", sl.Err(err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	var terms []structures.Suggestion
+	for rows.Next() {
+		var term structures.Suggestion
+		if err := rows.Scan(&term.Text, &term.Kind, &term.Weight); err != nil {
+			log.Error("Error scanning suggestion term", sl.Err(err))
+			return nil, err
+		}
+		terms = append(terms, term)
+	}
+
+	return terms, rows.Err()
+}
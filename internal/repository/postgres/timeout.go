@@ -0,0 +1,28 @@
+package postgres
+
+import (
+	"context"
+	"time"
+
+	"github.com/qwaq-dev/culina/pkg/config"
+)
+
+// readTimeout bounds a read operation, the deadline of ctx still applies
+// when it is closer. No timeout is set when it isn't configured.
+func readTimeout(ctx context.Context, timeouts config.QueryTimeouts, op string) (context.Context, context.CancelFunc) {
+	return withTimeout(ctx, timeouts, op, timeouts.Read)
+}
+
+func writeTimeout(ctx context.Context, timeouts config.QueryTimeouts, op string) (context.Context, context.CancelFunc) {
+	return withTimeout(ctx, timeouts, op, timeouts.Write)
+}
+
+func withTimeout(ctx context.Context, timeouts config.QueryTimeouts, op string, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if t, ok := timeouts.Operations[op]; ok {
+		timeout = t
+	}
+	if timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, timeout)
+}
@@ -0,0 +1,166 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"sync"
+
+	"github.com/qwaq-dev/culina/internal/repository"
+	"github.com/qwaq-dev/culina/pkg/logger/sl"
+)
+
+type PostgresUnitOfWork struct {
+	DB  *sql.DB
+	Log *slog.Logger
+}
+
+type unitKey struct{}
+
+// unit is the transaction of a unit of work carried by the context.
+type unit struct {
+	tx         *sql.Tx
+	mu         sync.Mutex
+	savepoints int
+}
+
+func (u *PostgresUnitOfWork) Do(ctx context.Context, fn func(ctx context.Context) error) (err error) {
+	if _, ok := ctx.Value(unitKey{}).(*unit); ok {
+		return fn(ctx)
+	}
+
+	tx, err := u.DB.BeginTx(ctx, nil)
+	if err != nil {
+		u.Log.ErrorContext(ctx, "Error with starting transaction", sl.Err(err))
+		// side effects done before Do are undone like on a rollback
+		_, compensations := repository.WithCompensations(ctx)
+		compensations.Run(ctx, u.Log)
+		return err
+	}
+
+	ctx, compensations := repository.WithCompensations(context.WithValue(ctx, unitKey{}, &unit{tx: tx}))
+	rollback := func() {
+		tx.Rollback()
+		compensations.Run(ctx, u.Log)
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			rollback()
+			panic(p)
+		}
+	}()
+
+	if err := fn(ctx); err != nil {
+		rollback()
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		u.Log.ErrorContext(ctx, "Error with committing transaction", sl.Err(err))
+		compensations.Run(ctx, u.Log)
+		return err
+	}
+	return nil
+}
+
+// unitTx is a transaction of a repository method. Inside a unit of work it
+// is a savepoint of the unit's transaction: Commit releases the savepoint
+// and Rollback undoes only the changes of the method.
+type unitTx struct {
+	*sql.Tx
+	ctx       context.Context
+	savepoint string
+	done      bool
+}
+
+// begin starts a transaction, or a savepoint when ctx has a unit of work.
+func begin(ctx context.Context, db *sql.DB) (*unitTx, error) {
+	u, ok := ctx.Value(unitKey{}).(*unit)
+	if !ok {
+		tx, err := db.BeginTx(ctx, nil)
+		if err != nil {
+			return nil, err
+		}
+		return &unitTx{Tx: tx}, nil
+	}
+
+	u.mu.Lock()
+	u.savepoints++
+	savepoint := fmt.Sprintf("unit_%d", u.savepoints)
+	u.mu.Unlock()
+
+	if _, err := u.tx.ExecContext(ctx, "SAVEPOINT "+savepoint); err != nil {
+		return nil, err
+	}
+	return &unitTx{Tx: u.tx, ctx: ctx, savepoint: savepoint}, nil
+}
+
+func (t *unitTx) Commit() error {
+	if t.savepoint == "" {
+		return t.Tx.Commit()
+	}
+	if t.done {
+		return sql.ErrTxDone
+	}
+
+	t.done = true
+	_, err := t.Tx.ExecContext(t.ctx, "RELEASE SAVEPOINT "+t.savepoint)
+	return err
+}
+
+func (t *unitTx) Rollback() error {
+	if t.savepoint == "" {
+		return t.Tx.Rollback()
+	}
+	if t.done {
+		return sql.ErrTxDone
+	}
+
+	t.done = true
+	_, err := t.Tx.ExecContext(t.ctx, "ROLLBACK TO SAVEPOINT "+t.savepoint)
+	return err
+}
+
+// querier runs single statements of repository methods.
+type querier interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// conn returns the transaction of the unit of work of ctx, or db outside of one.
+func conn(ctx context.Context, db *sql.DB) querier {
+	if u, ok := ctx.Value(unitKey{}).(*unit); ok {
+		return u.tx
+	}
+	return db
+}
+
+// inUnit reports whether ctx has a unit of work.
+func inUnit(ctx context.Context) bool {
+	_, ok := ctx.Value(unitKey{}).(*unit)
+	return ok
+}
+
+// parallel runs the reads at once. Inside a unit of work they share the
+// connection of its transaction, so they run one by one.
+func parallel(ctx context.Context, reads ...func()) {
+	if inUnit(ctx) {
+		for _, read := range reads {
+			read()
+		}
+		return
+	}
+
+	var wg sync.WaitGroup
+	for _, read := range reads {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			read()
+		}()
+	}
+	wg.Wait()
+}
@@ -0,0 +1,152 @@
+package postgres
+
+import (
+	"database/sql"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/qwaq-dev/culina/internal/repository"
+	"github.com/qwaq-dev/culina/pkg/logger/sl"
+	"github.com/qwaq-dev/culina/structures"
+)
+
+type PostgresUploadRepository struct {
+	DB *sql.DB
+}
+
+const uploadColumns = `id, author_id, filename, size, upload_offset, hash_state,
+	COALESCE(checksum, ''), expires_at, created_at`
+
+func scanUpload(row interface{ Scan(dest ...any) error }) (structures.UploadSession, error) {
+	var session structures.UploadSession
+	err := row.Scan(&session.Id, &session.Author_id, &session.Filename, &session.Size, &session.Offset,
+		&session.Hash_state, &session.Checksum, &session.Expires_at, &session.Created_at)
+	return session, err
+}
+
+// InsertUpload starts the session, it expires after ttl without new chunks.
+func (p *PostgresUploadRepository) InsertUpload(session structures.UploadSession, ttl time.Duration, log *slog.Logger) (structures.UploadSession, error) {
+	row := p.DB.QueryRow(`INSERT INTO upload_sessions (id, author_id, filename, size, expires_at)
+			  VALUES ($1, $2, $3, $4, NOW() + $5 * INTERVAL '1 second')
+			  RETURNING `+uploadColumns,
+		session.Id, session.Author_id, session.Filename, session.Size, int(ttl.Seconds()))
+
+	session, err := scanUpload(row)
+	if err != nil {
+		log.Error("Error with inserting upload session", sl.Err(err))
+		return session, err
+	}
+
+	return session, nil
+}
+
+func (p *PostgresUploadRepository) SelectUpload(id string, log *slog.Logger) (structures.UploadSession, error) {
+	row := p.DB.QueryRow(`SELECT `+uploadColumns+` FROM upload_sessions
+			  WHERE id = $1 AND expires_at > NOW()`, id)
+
+	session, err := scanUpload(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return session, repository.ErrUploadNotFound
+	}
+	if err != nil {
+		log.Error("Error with selecting upload session", sl.Err(err))
+		return session, err
+	}
+
+	return session, nil
+}
+
+// InsertChunk saves the chunk and moves the session to the new state.
+// The chunk must start where the stored session ends, so concurrent
+// requests with the same chunk can't both be applied.
+func (p *PostgresUploadRepository) InsertChunk(session structures.UploadSession, chunk structures.UploadChunk, ttl time.Duration, log *slog.Logger) (structures.UploadSession, error) {
+	tx, err := p.DB.Begin()
+	if err != nil {
+		log.Error("Error with starting transaction", sl.Err(err))
+		return session, err
+	}
+	defer tx.Rollback()
+
+	row := tx.QueryRow(`UPDATE upload_sessions
+			  SET upload_offset = $3, hash_state = $4, checksum = NULLIF($5, ''),
+				expires_at = NOW() + $6 * INTERVAL '1 second'
+			  WHERE id = $1 AND upload_offset = $2 AND expires_at > NOW()
+			  RETURNING `+uploadColumns,
+		session.Id, chunk.Offset, session.Offset, session.Hash_state, session.Checksum, int(ttl.Seconds()))
+
+	updated, err := scanUpload(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return session, repository.ErrOffsetMismatch
+	}
+	if err != nil {
+		log.Error("Error with updating upload session", sl.Err(err))
+		return session, err
+	}
+
+	_, err = tx.Exec("INSERT INTO upload_chunks (upload_id, chunk_offset, size, key) VALUES ($1, $2, $3, $4)",
+		session.Id, chunk.Offset, chunk.Size, chunk.Key)
+	if err != nil {
+		log.Error("Error with inserting upload chunk", sl.Err(err))
+		return session, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Error("Error with committing transaction", sl.Err(err))
+		return session, err
+	}
+
+	return updated, nil
+}
+
+func (p *PostgresUploadRepository) SelectChunks(id string, log *slog.Logger) ([]structures.UploadChunk, error) {
+	rows, err := p.DB.Query(`SELECT chunk_offset, size, key FROM upload_chunks
+			  WHERE upload_id = $1 ORDER BY chunk_offset`, id)
+	if err != nil {
+		log.Error("Error with selecting upload chunks", sl.Err(err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	var chunks []structures.UploadChunk
+	for rows.Next() {
+		var chunk structures.UploadChunk
+		if err := rows.Scan(&chunk.Offset, &chunk.Size, &chunk.Key); err != nil {
+			log.Error("Error scanning upload chunk row", sl.Err(err))
+			return nil, err
+		}
+		chunks = append(chunks, chunk)
+	}
+
+	return chunks, nil
+}
+
+func (p *PostgresUploadRepository) DeleteUpload(id string, log *slog.Logger) error {
+	if _, err := p.DB.Exec("DELETE FROM upload_sessions WHERE id = $1", id); err != nil {
+		log.Error("Error with deleting upload session", sl.Err(err))
+		return err
+	}
+
+	return nil
+}
+
+func (p *PostgresUploadRepository) SelectExpiredUploads(log *slog.Logger) ([]string, error) {
+	rows, err := p.DB.Query("SELECT id FROM upload_sessions WHERE expires_at <= NOW()")
+	if err != nil {
+		log.Error("Error with selecting expired uploads", sl.Err(err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			log.Error("Error scanning upload row", sl.Err(err))
+			continue
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, nil
+}
@@ -1,22 +1,28 @@
 package postgres
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 	"log/slog"
 
+	"github.com/qwaq-dev/culina/pkg/config"
 	"github.com/qwaq-dev/culina/pkg/logger/sl"
 	"github.com/qwaq-dev/culina/structures"
 )
 
 type PostgresUserRepository struct {
-	DB *sql.DB
+	DB       *sql.DB
+	Timeouts config.QueryTimeouts
 }
 
-func (r *PostgresUserRepository) InsertUser(user *structures.User, log *slog.Logger) (int, error) {
+func (r *PostgresUserRepository) InsertUser(ctx context.Context, user *structures.User, log *slog.Logger) (int, error) {
+	ctx, cancel := writeTimeout(ctx, r.Timeouts, "InsertUser")
+	defer cancel()
+
 	var userID int
 
-	err := r.DB.QueryRow("INSERT INTO users (email, username, password) VALUES ($1, $2, $3) RETURNING id",
+	err := conn(ctx, r.DB).QueryRowContext(ctx, "INSERT INTO users (email, username, password) VALUES ($1, $2, $3) RETURNING id",
 		user.Email, user.Username, user.Password).Scan(&userID)
 
 	if err != nil {
@@ -26,17 +32,32 @@ func (r *PostgresUserRepository) InsertUser(user *structures.User, log *slog.Log
 	return userID, nil
 }
 
-func (r *PostgresUserRepository) SelectUser(username string, log *slog.Logger) (*structures.User, error) {
+func (r *PostgresUserRepository) SelectUser(ctx context.Context, username string, log *slog.Logger) (*structures.User, error) {
+	ctx, cancel := readTimeout(ctx, r.Timeouts, "SelectUser")
+	defer cancel()
+
 	user := new(structures.User)
 
-	err := r.DB.QueryRow("SELECT id, email, username, password, role, sex, recipes_count FROM users WHERE username=$1", username).
-		Scan(&user.Id, &user.Email, &user.Username, &user.Password, &user.Role, &user.Sex, &user.Recipes_count)
+	err := conn(ctx, r.DB).QueryRowContext(ctx, "SELECT id, email, username, password, role, sex, recipes_count, banned_at IS NOT NULL FROM users WHERE username=$1", username).
+		Scan(&user.Id, &user.Email, &user.Username, &user.Password, &user.Role, &user.Sex, &user.Recipes_count, &user.Banned)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, nil
 		}
-		log.Error("Error with selecting user", sl.Err(err))
+		log.ErrorContext(ctx, "Error with selecting user", sl.Err(err))
 		return nil, err
 	}
 	return user, nil
 }
+
+func (r *PostgresUserRepository) UpdateUserRole(ctx context.Context, userId int, role string, log *slog.Logger) error {
+	ctx, cancel := writeTimeout(ctx, r.Timeouts, "UpdateUserRole")
+	defer cancel()
+
+	_, err := conn(ctx, r.DB).ExecContext(ctx, "UPDATE users SET role = $2 WHERE id = $1", userId, role)
+	if err != nil {
+		log.ErrorContext(ctx, "Error with updating user role", sl.Err(err))
+		return err
+	}
+	return nil
+}
@@ -1,24 +1,164 @@
 package repository
 
 import (
+	"context"
+	"io"
 	"log/slog"
+	"time"
 
 	"github.com/qwaq-dev/culina/structures"
 )
 
 type UserRepository interface {
-	InsertUser(user *structures.User, log *slog.Logger) (int, error)
-	SelectUser(username string, log *slog.Logger) (*structures.User, error)
+	InsertUser(ctx context.Context, user *structures.User, log *slog.Logger) (int, error)
+	SelectUser(ctx context.Context, username string, log *slog.Logger) (*structures.User, error)
+	UpdateUserRole(ctx context.Context, userId int, role string, log *slog.Logger) error
 }
 
 type DashboardRepository interface {
-	InsertRecipe(recipe structures.Recipes, log *slog.Logger) (int, error)
-	SelectAllRecipes(page, pageSize int, log *slog.Logger) ([]structures.Recipes, error)
-	SelectRecipeById(id int, log *slog.Logger) (structures.Recipes, error)
-	InsertReview(review structures.Review, log *slog.Logger) error
+	InsertRecipe(ctx context.Context, recipe structures.Recipes, log *slog.Logger) (int, error)
+	SelectAllRecipes(ctx context.Context, page, pageSize int, log *slog.Logger) ([]structures.Recipes, error)
+	SelectRecipesForIndex(ctx context.Context, afterId, limit int, log *slog.Logger) ([]structures.Recipes, error)
+	SelectRecipeById(ctx context.Context, id int, log *slog.Logger) (structures.Recipes, error)
+	InsertReview(ctx context.Context, review structures.Review, log *slog.Logger) error
+	UpdateReview(ctx context.Context, review structures.Review, log *slog.Logger) error
+	DeleteReview(ctx context.Context, reviewId, authorId int, log *slog.Logger) error
+	SelectReviewsByRecipeId(ctx context.Context, recipeId, page, pageSize int, sort string, log *slog.Logger) ([]structures.Review, error)
+	SelectRatingDistribution(ctx context.Context, recipeId int, log *slog.Logger) (map[int]int, error)
+	VoteReview(ctx context.Context, vote structures.ReviewVote, log *slog.Logger) (structures.Review, error)
+	SelectRecipePhotos(ctx context.Context, recipeId, page, pageSize int, log *slog.Logger) ([]structures.ReviewImage, error)
+	PinRecipePhoto(ctx context.Context, recipeId, authorId, photoId int, log *slog.Logger) error
+	SelectDrafts(ctx context.Context, authorId int, log *slog.Logger) ([]structures.Recipes, error)
+	SelectRecipeNames(ctx context.Context, authorId int, log *slog.Logger) ([]string, error)
+	PublishRecipe(ctx context.Context, recipeId, authorId int, log *slog.Logger) error
+	IsPrivateImage(ctx context.Context, key string, log *slog.Logger) (bool, error)
+	AddRecipeImage(ctx context.Context, recipeId, authorId int, image structures.RecipeMedia, maxImages int, log *slog.Logger) error
+	AddRecipeVideo(ctx context.Context, recipeId, authorId int, video structures.RecipeVideo, log *slog.Logger) error
 }
 
 type ProfileRepository interface {
-	ChangeProfileData(column, newData, userId string, log *slog.Logger) (*structures.User, error)
-	InsertUserRecipes(user *structures.User, log *slog.Logger) (*structures.Recipes, error)
+	ChangeProfileData(ctx context.Context, column, newData, userId string, log *slog.Logger) (*structures.User, error)
+	InsertUserRecipes(ctx context.Context, user *structures.User, log *slog.Logger) (*structures.Recipes, error)
+	SelectNotifications(ctx context.Context, userId int, unreadOnly bool, log *slog.Logger) ([]structures.Notification, error)
+	MarkNotificationsRead(ctx context.Context, userId int, ids []int, log *slog.Logger) error
+}
+
+type CommentRepository interface {
+	InsertComment(comment structures.Comment, log *slog.Logger) (structures.Comment, error)
+	UpdateComment(comment structures.Comment, log *slog.Logger) (structures.Comment, error)
+	DeleteComment(commentId, authorId int, log *slog.Logger) error
+	SelectComments(recipeId, page, pageSize int, log *slog.Logger) ([]structures.Comment, error)
+}
+
+type ModerationRepository interface {
+	InsertReport(report structures.Report, log *slog.Logger) (structures.Report, bool, error)
+	SelectReportQueue(moderatorId int, status string, page, pageSize int, log *slog.Logger) ([]structures.Report, error)
+	ClaimReport(reportId, moderatorId int, log *slog.Logger) error
+	ResolveReport(reportId, moderatorId int, action, note string, log *slog.Logger) (structures.Report, error)
+	SelectModerationActions(moderatorId int, targetType string, targetId int, log *slog.Logger) ([]structures.ModerationAction, error)
+}
+
+// ImageRepository keeps reference counts of stored image blobs.
+type ImageRepository interface {
+	TouchBlob(blob structures.ImageBlob, log *slog.Logger) (bool, time.Time, error)
+	SelectBlobs(log *slog.Logger) ([]structures.ImageBlob, error)
+	SelectImageRefs(log *slog.Logger) (map[string]int, error)
+	UpdateBlobRefs(key string, from, to int, log *slog.Logger) error
+	DeleteBlob(key string, olderThan time.Time, remove func(key string) error, log *slog.Logger) (bool, error)
+}
+
+// UploadRepository keeps sessions of resumable uploads.
+type UploadRepository interface {
+	InsertUpload(session structures.UploadSession, ttl time.Duration, log *slog.Logger) (structures.UploadSession, error)
+	SelectUpload(id string, log *slog.Logger) (structures.UploadSession, error)
+	InsertChunk(session structures.UploadSession, chunk structures.UploadChunk, ttl time.Duration, log *slog.Logger) (structures.UploadSession, error)
+	SelectChunks(id string, log *slog.Logger) ([]structures.UploadChunk, error)
+	DeleteUpload(id string, log *slog.Logger) error
+	SelectExpiredUploads(log *slog.Logger) ([]string, error)
+}
+
+// OutboxRepository keeps recipes waiting to be synced to the search index.
+type OutboxRepository interface {
+	InsertSearchEvents(recipeIds []int, log *slog.Logger) error
+	ClaimSearchEvents(limit int, lease time.Duration, log *slog.Logger) ([]structures.SearchEvent, error)
+	DeleteSearchEvents(ids []int64, log *slog.Logger) error
+	RetrySearchEvent(id int64, delay time.Duration, cause string, log *slog.Logger) error
+}
+
+// IngredientRepository keeps normalized ingredient names of recipes.
+type IngredientRepository interface {
+	SelectUnnormalizedIngredients(limit int, log *slog.Logger) (map[int]map[string]string, error)
+	UpdateIngredientNames(names map[int][]string, log *slog.Logger) error
+}
+
+// SearchRulesRepository keeps synonyms, overrides and stop words of the search.
+type SearchRulesRepository interface {
+	CheckAdmin(userId int, log *slog.Logger) error
+	SelectSynonyms(log *slog.Logger) ([]structures.SearchSynonym, error)
+	InsertSynonym(synonym structures.SearchSynonym, log *slog.Logger) (structures.SearchSynonym, error)
+	UpdateSynonym(synonym structures.SearchSynonym, log *slog.Logger) (structures.SearchSynonym, error)
+	DeleteSynonym(id int, log *slog.Logger) error
+	SelectOverrides(log *slog.Logger) ([]structures.SearchOverride, error)
+	InsertOverride(override structures.SearchOverride, log *slog.Logger) (structures.SearchOverride, error)
+	UpdateOverride(override structures.SearchOverride, log *slog.Logger) (structures.SearchOverride, error)
+	DeleteOverride(id int, log *slog.Logger) error
+	SelectStopwords(log *slog.Logger) ([]string, error)
+	ReplaceStopwords(words []string, adminId int, log *slog.Logger) error
+}
+
+// AnalyticsRepository keeps searches and clicks on found recipes.
+type AnalyticsRepository interface {
+	CheckAdmin(userId int, log *slog.Logger) error
+	InsertSearches(entries []structures.SearchLogEntry, log *slog.Logger) error
+	InsertClicks(clicks []structures.SearchClick, log *slog.Logger) error
+	// DeleteSearchesBefore removes searches and clicks logged before the time.
+	DeleteSearchesBefore(before time.Time, log *slog.Logger) (int64, error)
+	SelectTopQueries(from, to time.Time, limit int, log *slog.Logger) ([]structures.QueryStats, error)
+	SelectZeroResultQueries(from, to time.Time, limit int, log *slog.Logger) ([]structures.QueryStats, error)
+	SelectQueryCtr(from, to time.Time, minSearches, limit int, log *slog.Logger) ([]structures.QueryStats, error)
+}
+
+// SuggestRepository collects the terms suggestions are built from.
+type SuggestRepository interface {
+	SelectSuggestionTerms(minQueryCount int, log *slog.Logger) ([]structures.Suggestion, error)
+}
+
+// BlobStore keeps uploaded files. Keys are slash separated relative names
+// like "12/1700000000_photo.jpg", they are what gets saved in the database.
+type BlobStore interface {
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+	Get(ctx context.Context, key string) (io.ReadCloser, BlobInfo, error)
+	// GetRange reads bytes from start to end inclusive.
+	GetRange(ctx context.Context, key string, start, end int64) (io.ReadCloser, error)
+	Stat(ctx context.Context, key string) (BlobInfo, error)
+	Delete(ctx context.Context, key string) error
+	List(ctx context.Context, prefix string) ([]BlobInfo, error)
+}
+
+type BlobInfo struct {
+	Key          string
+	Size         int64
+	ContentType  string
+	LastModified time.Time
+}
+
+// SearchIndex finds published recipes, drafts and hidden recipes are never in the index.
+type SearchIndex interface {
+	IndexRecipe(recipe structures.Recipes) error
+	IndexRecipeById(id int) error
+	DeleteRecipe(id int) error
+	Search(query structures.SearchQuery) (structures.SearchResult, error)
+	// Init prepares the index at startup, Reindex rebuilds it from the database.
+	Init() error
+	Reindex() error
+	// SyncRules pushes synonyms, overrides and stop words from the database.
+	SyncRules() error
+	Healthy(ctx context.Context) bool
+}
+
+// Suggester completes search prefixes. RebuildSuggestions replaces all
+// suggestions with the given terms.
+type Suggester interface {
+	Suggest(prefix string, limit int) ([]structures.Suggestion, error)
+	RebuildSuggestions(terms []structures.Suggestion) error
 }
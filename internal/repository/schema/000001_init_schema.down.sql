@@ -1,5 +1,6 @@
-DROP TABLE users;
+-- reviews and recipes reference users, they are dropped first
+DROP TABLE IF EXISTS reviews;
 
-DROP TABLE recipes;
+DROP TABLE IF EXISTS recipes;
 
-DROP TABLE reviews;
\ No newline at end of file
+DROP TABLE IF EXISTS users;
@@ -0,0 +1,3 @@
+ALTER TABLE reviews DROP COLUMN IF EXISTS updated_at;
+
+ALTER TABLE reviews DROP CONSTRAINT IF EXISTS reviews_author_recipe_unique;
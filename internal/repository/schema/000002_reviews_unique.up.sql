@@ -0,0 +1,14 @@
+DELETE FROM reviews a
+    USING reviews b
+    WHERE a.author_id = b.author_id
+      AND a.recipe_id = b.recipe_id
+      AND a.id < b.id;
+
+ALTER TABLE reviews
+    ADD CONSTRAINT reviews_author_recipe_unique UNIQUE (author_id, recipe_id);
+
+ALTER TABLE reviews ADD COLUMN updated_at TIMESTAMP;
+
+UPDATE recipes
+SET review_count = (SELECT COUNT(*) FROM reviews WHERE recipe_id = recipes.id),
+    avg_rating = (SELECT COALESCE(AVG(rating_value), 0) FROM reviews WHERE recipe_id = recipes.id);
@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS reviews_recipe_id_idx;
+
+DROP TABLE IF EXISTS review_votes;
+
+ALTER TABLE reviews
+    DROP COLUMN IF EXISTS helpful_up,
+    DROP COLUMN IF EXISTS helpful_down;
@@ -0,0 +1,13 @@
+ALTER TABLE reviews
+    ADD COLUMN helpful_up INT DEFAULT 0,
+    ADD COLUMN helpful_down INT DEFAULT 0;
+
+CREATE TABLE review_votes (
+    review_id INTEGER REFERENCES reviews(id) ON DELETE CASCADE,
+    user_id INTEGER REFERENCES users(id) ON DELETE CASCADE,
+    value SMALLINT NOT NULL CHECK (value IN (-1, 1)),
+    created_at TIMESTAMP DEFAULT NOW(),
+    PRIMARY KEY (review_id, user_id)
+);
+
+CREATE INDEX reviews_recipe_id_idx ON reviews (recipe_id);
@@ -0,0 +1,3 @@
+ALTER TABLE recipes DROP COLUMN IF EXISTS pinned_photo_id;
+
+DROP TABLE IF EXISTS review_images;
@@ -0,0 +1,11 @@
+CREATE TABLE review_images (
+    id SERIAL PRIMARY KEY,
+    review_id INTEGER REFERENCES reviews(id) ON DELETE CASCADE,
+    path VARCHAR(255) NOT NULL,
+    created_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE INDEX review_images_review_id_idx ON review_images (review_id);
+
+ALTER TABLE recipes
+    ADD COLUMN pinned_photo_id INTEGER REFERENCES review_images(id) ON DELETE SET NULL;
@@ -0,0 +1,10 @@
+DROP TABLE IF EXISTS moderation_actions;
+
+DROP TABLE IF EXISTS reports;
+
+ALTER TABLE users
+    DROP COLUMN IF EXISTS banned_at,
+    DROP COLUMN IF EXISTS warnings_count;
+ALTER TABLE comments DROP COLUMN IF EXISTS hidden_at;
+ALTER TABLE reviews DROP COLUMN IF EXISTS hidden_at;
+ALTER TABLE recipes DROP COLUMN IF EXISTS hidden_at;
@@ -0,0 +1,37 @@
+ALTER TABLE recipes ADD COLUMN hidden_at TIMESTAMP;
+ALTER TABLE reviews ADD COLUMN hidden_at TIMESTAMP;
+ALTER TABLE comments ADD COLUMN hidden_at TIMESTAMP;
+ALTER TABLE users
+    ADD COLUMN banned_at TIMESTAMP,
+    ADD COLUMN warnings_count INT DEFAULT 0;
+
+CREATE TABLE reports (
+    id SERIAL PRIMARY KEY,
+    target_type VARCHAR(20) NOT NULL CHECK (target_type IN ('recipe', 'review', 'comment', 'user')),
+    target_id INTEGER NOT NULL,
+    reporter_id INTEGER REFERENCES users(id) ON DELETE CASCADE,
+    reason VARCHAR(50) NOT NULL,
+    details TEXT,
+    status VARCHAR(20) NOT NULL DEFAULT 'open' CHECK (status IN ('open', 'claimed', 'resolved')),
+    moderator_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    claimed_at TIMESTAMP,
+    resolved_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT NOW(),
+    UNIQUE (target_type, target_id, reporter_id)
+);
+
+CREATE INDEX reports_status_idx ON reports (status, created_at);
+CREATE INDEX reports_target_idx ON reports (target_type, target_id);
+
+CREATE TABLE moderation_actions (
+    id SERIAL PRIMARY KEY,
+    report_id INTEGER REFERENCES reports(id) ON DELETE SET NULL,
+    moderator_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    target_type VARCHAR(20) NOT NULL,
+    target_id INTEGER NOT NULL,
+    action VARCHAR(20) NOT NULL,
+    note TEXT,
+    created_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE INDEX moderation_actions_target_idx ON moderation_actions (target_type, target_id);
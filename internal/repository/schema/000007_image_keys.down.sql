@@ -0,0 +1,12 @@
+UPDATE review_images
+SET image_key = './uploads/' || image_key
+WHERE image_key NOT LIKE './uploads/%';
+
+ALTER TABLE review_images RENAME COLUMN image_key TO path;
+
+UPDATE recipes
+SET imgs = (
+    SELECT COALESCE(jsonb_object_agg(key, './uploads/' || value), '{}'::jsonb)
+    FROM jsonb_each_text(imgs)
+)
+WHERE imgs::text NOT LIKE '%./uploads/%';
@@ -0,0 +1,13 @@
+-- images are referenced by blob store keys instead of ./uploads paths
+UPDATE recipes
+SET imgs = (
+    SELECT COALESCE(jsonb_object_agg(key, regexp_replace(value, '^(\./)?uploads/', '')), '{}'::jsonb)
+    FROM jsonb_each_text(imgs)
+)
+WHERE imgs::text LIKE '%uploads/%';
+
+ALTER TABLE review_images RENAME COLUMN path TO image_key;
+
+UPDATE review_images
+SET image_key = regexp_replace(image_key, '^(\./)?uploads/', '')
+WHERE image_key LIKE '%uploads/%';
@@ -0,0 +1,3 @@
+ALTER TABLE review_images DROP COLUMN IF EXISTS variants;
+
+ALTER TABLE recipes DROP COLUMN IF EXISTS img_variants;
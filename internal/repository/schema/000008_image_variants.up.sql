@@ -0,0 +1,3 @@
+ALTER TABLE recipes ADD COLUMN img_variants JSONB NOT NULL DEFAULT '{}';
+
+ALTER TABLE review_images ADD COLUMN variants JSONB NOT NULL DEFAULT '{}';
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS recipes_private_idx;
+
+ALTER TABLE recipes DROP COLUMN IF EXISTS draft;
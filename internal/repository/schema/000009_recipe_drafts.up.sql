@@ -0,0 +1,4 @@
+ALTER TABLE recipes ADD COLUMN draft BOOLEAN NOT NULL DEFAULT false;
+
+-- images of these recipes are served only by signed urls
+CREATE INDEX recipes_private_idx ON recipes (id) WHERE draft OR hidden_at IS NOT NULL;
@@ -0,0 +1,11 @@
+-- Uploaded files are stored by content hash, so equal images share one blob.
+CREATE TABLE image_blobs (
+    key VARCHAR(255) PRIMARY KEY,
+    size BIGINT NOT NULL,
+    content_type VARCHAR(50) NOT NULL,
+    ref_count INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMP DEFAULT NOW(),
+    updated_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE INDEX image_blobs_unreferenced_idx ON image_blobs (updated_at) WHERE ref_count = 0;
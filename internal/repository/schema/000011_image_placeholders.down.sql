@@ -0,0 +1,5 @@
+ALTER TABLE review_images
+    DROP COLUMN IF EXISTS dominant_color,
+    DROP COLUMN IF EXISTS blurhash;
+
+ALTER TABLE recipes DROP COLUMN IF EXISTS img_placeholders;
@@ -0,0 +1,5 @@
+ALTER TABLE recipes ADD COLUMN img_placeholders JSONB NOT NULL DEFAULT '{}';
+
+ALTER TABLE review_images
+    ADD COLUMN blurhash VARCHAR(64) NOT NULL DEFAULT '',
+    ADD COLUMN dominant_color VARCHAR(7) NOT NULL DEFAULT '';
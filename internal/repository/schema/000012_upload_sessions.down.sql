@@ -0,0 +1,4 @@
+ALTER TABLE recipes DROP COLUMN IF EXISTS videos;
+
+DROP TABLE IF EXISTS upload_chunks;
+DROP TABLE IF EXISTS upload_sessions;
@@ -0,0 +1,23 @@
+CREATE TABLE upload_sessions (
+    id VARCHAR(32) PRIMARY KEY,
+    author_id INTEGER REFERENCES users(id) ON DELETE CASCADE,
+    filename VARCHAR(255) NOT NULL,
+    size BIGINT NOT NULL,
+    upload_offset BIGINT NOT NULL DEFAULT 0,
+    hash_state BYTEA,           -- sha256 state of the received bytes
+    checksum VARCHAR(64),       -- sha256 of the whole file, set when it is received
+    expires_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE INDEX upload_sessions_expires_at_idx ON upload_sessions (expires_at);
+
+CREATE TABLE upload_chunks (
+    upload_id VARCHAR(32) REFERENCES upload_sessions(id) ON DELETE CASCADE,
+    chunk_offset BIGINT NOT NULL,
+    size BIGINT NOT NULL,
+    key VARCHAR(255) NOT NULL,
+    PRIMARY KEY (upload_id, chunk_offset)
+);
+
+ALTER TABLE recipes ADD COLUMN videos JSONB NOT NULL DEFAULT '[]';
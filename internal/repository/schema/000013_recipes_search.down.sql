@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS recipes_filters_idx;
+DROP INDEX IF EXISTS recipes_search_vector_idx;
+
+ALTER TABLE recipes DROP COLUMN IF EXISTS search_vector;
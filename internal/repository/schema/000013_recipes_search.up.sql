@@ -0,0 +1,11 @@
+-- Full-text search used when Typesense is off or down. Russian and English
+-- configs are both applied, recipes are written in either language.
+ALTER TABLE recipes ADD COLUMN search_vector tsvector GENERATED ALWAYS AS (
+    setweight(to_tsvector('russian', name), 'A') ||
+    setweight(to_tsvector('english', name), 'A') ||
+    setweight(to_tsvector('russian', descr), 'B') ||
+    setweight(to_tsvector('english', descr), 'B')
+) STORED;
+
+CREATE INDEX recipes_search_vector_idx ON recipes USING GIN (search_vector);
+CREATE INDEX recipes_filters_idx ON recipes USING GIN (filters);
@@ -0,0 +1,12 @@
+-- Recipes whose search documents have to be updated. Rows are written in the
+-- same transaction as the change and removed by the relay once applied.
+CREATE TABLE search_outbox (
+    id BIGSERIAL PRIMARY KEY,
+    recipe_id INTEGER NOT NULL,
+    attempts INT NOT NULL DEFAULT 0,
+    last_error TEXT,
+    available_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    created_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE INDEX search_outbox_available_at_idx ON search_outbox (available_at);
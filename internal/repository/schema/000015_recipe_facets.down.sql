@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS recipes_diff_idx;
+
+ALTER TABLE recipes
+    DROP COLUMN IF EXISTS calories,
+    DROP COLUMN IF EXISTS cook_time;
@@ -0,0 +1,6 @@
+-- Minutes and kilocalories per serving, 0 when the author didn't set them.
+ALTER TABLE recipes
+    ADD COLUMN cook_time INT NOT NULL DEFAULT 0 CHECK (cook_time >= 0),
+    ADD COLUMN calories INT NOT NULL DEFAULT 0 CHECK (calories >= 0);
+
+CREATE INDEX recipes_diff_idx ON recipes (diff);
@@ -0,0 +1,9 @@
+-- Search queries which found something, counted to suggest popular queries.
+-- Queries are normalized (lower case, single spaces) before they are counted.
+CREATE TABLE search_queries (
+    query TEXT PRIMARY KEY,
+    count BIGINT NOT NULL DEFAULT 0,
+    last_searched_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX search_queries_count_idx ON search_queries (count DESC);
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS recipes_ingredient_names_idx;
+
+ALTER TABLE recipes DROP COLUMN IF EXISTS ingredient_names;
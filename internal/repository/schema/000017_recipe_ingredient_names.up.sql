@@ -0,0 +1,6 @@
+-- Normalized ingredient names, synonyms replaced by one name, for ingredient
+-- search. NULL until the recipe is normalized, existing recipes are
+-- normalized by the server in the background.
+ALTER TABLE recipes ADD COLUMN ingredient_names TEXT[];
+
+CREATE INDEX recipes_ingredient_names_idx ON recipes USING GIN (ingredient_names);
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS search_stopwords;
+DROP TABLE IF EXISTS search_overrides;
+DROP TABLE IF EXISTS search_synonyms;
@@ -0,0 +1,29 @@
+-- Search tuning managed by admins. Postgres is the source of truth, the
+-- rules are pushed to Typesense after every change and every reindex.
+CREATE TABLE search_synonyms (
+    id SERIAL PRIMARY KEY,
+    root TEXT, -- one-way synonym when set: the synonyms find the root, not the other way
+    synonyms TEXT[] NOT NULL,
+    updated_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP DEFAULT NOW(),
+    updated_at TIMESTAMP DEFAULT NOW()
+);
+
+-- Curation of the results of a query: pinned recipes go first in the
+-- given order, hidden ones are never shown.
+CREATE TABLE search_overrides (
+    id SERIAL PRIMARY KEY,
+    query TEXT NOT NULL,
+    match VARCHAR(10) NOT NULL DEFAULT 'exact' CHECK (match IN ('exact', 'contains')),
+    pinned INTEGER[] NOT NULL DEFAULT '{}',
+    hidden INTEGER[] NOT NULL DEFAULT '{}',
+    updated_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP DEFAULT NOW(),
+    updated_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE TABLE search_stopwords (
+    word TEXT PRIMARY KEY,
+    updated_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP DEFAULT NOW()
+);
@@ -0,0 +1,14 @@
+ALTER TABLE recipes DROP COLUMN IF EXISTS search_vector;
+
+ALTER TABLE recipes ADD COLUMN search_vector tsvector GENERATED ALWAYS AS (
+    setweight(to_tsvector('russian', name), 'A') ||
+    setweight(to_tsvector('english', name), 'A') ||
+    setweight(to_tsvector('russian', descr), 'B') ||
+    setweight(to_tsvector('english', descr), 'B')
+) STORED;
+
+CREATE INDEX recipes_search_vector_idx ON recipes USING GIN (search_vector);
+
+ALTER TABLE recipes
+    DROP COLUMN IF EXISTS translations,
+    DROP COLUMN IF EXISTS lang;
@@ -0,0 +1,17 @@
+-- Language of the recipe and its translations by language code:
+-- {"en": {"name": "", "descr": "", "ingredients": {}, "steps": {}}}
+ALTER TABLE recipes
+    ADD COLUMN lang VARCHAR(8) NOT NULL DEFAULT 'ru',
+    ADD COLUMN translations JSONB NOT NULL DEFAULT '{}';
+
+-- Translations are searched too, each language with its own config.
+ALTER TABLE recipes DROP COLUMN search_vector;
+
+ALTER TABLE recipes ADD COLUMN search_vector tsvector GENERATED ALWAYS AS (
+    setweight(to_tsvector('russian', name || ' ' || COALESCE(translations->'ru'->>'name', '')), 'A') ||
+    setweight(to_tsvector('english', name || ' ' || COALESCE(translations->'en'->>'name', '')), 'A') ||
+    setweight(to_tsvector('russian', descr || ' ' || COALESCE(translations->'ru'->>'descr', '')), 'B') ||
+    setweight(to_tsvector('english', descr || ' ' || COALESCE(translations->'en'->>'descr', '')), 'B')
+) STORED;
+
+CREATE INDEX recipes_search_vector_idx ON recipes USING GIN (search_vector);
@@ -0,0 +1,25 @@
+-- Every search and every click on a found recipe, written in batches.
+-- Clicks have no foreign key, a search may be dropped or not flushed yet.
+CREATE TABLE search_log (
+    id VARCHAR(32) PRIMARY KEY,
+    query TEXT NOT NULL, -- normalized, '' when only filters were used
+    params JSONB NOT NULL DEFAULT '{}', -- filters, page and language of the search
+    found INT NOT NULL,
+    latency_ms INT NOT NULL,
+    user_id INTEGER,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX search_log_created_at_idx ON search_log (created_at);
+CREATE INDEX search_log_query_idx ON search_log (query);
+
+CREATE TABLE search_clicks (
+    id BIGSERIAL PRIMARY KEY,
+    search_id VARCHAR(32) NOT NULL,
+    recipe_id INTEGER NOT NULL,
+    position INT NOT NULL, -- 1 based, across pages
+    user_id INTEGER,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX search_clicks_search_id_idx ON search_clicks (search_id);
@@ -0,0 +1 @@
+DROP INDEX IF EXISTS search_clicks_created_at_idx;
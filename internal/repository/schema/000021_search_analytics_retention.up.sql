@@ -0,0 +1,2 @@
+-- Clicks are removed by age together with the searches.
+CREATE INDEX search_clicks_created_at_idx ON search_clicks (created_at);
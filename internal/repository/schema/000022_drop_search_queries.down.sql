@@ -0,0 +1,7 @@
+CREATE TABLE search_queries (
+    query TEXT PRIMARY KEY,
+    count BIGINT NOT NULL DEFAULT 0,
+    last_searched_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX search_queries_count_idx ON search_queries (count DESC);
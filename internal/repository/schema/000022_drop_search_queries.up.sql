@@ -0,0 +1,2 @@
+-- Popular queries are counted from search_log.
+DROP TABLE IF EXISTS search_queries;
@@ -0,0 +1,9 @@
+// Package schema embeds the database migrations, so the binary can apply
+// them without the source tree. Files are named in the golang-migrate way:
+// <version>_<name>.up.sql and <version>_<name>.down.sql.
+package schema
+
+import "embed"
+
+//go:embed *.sql
+var Migrations embed.FS
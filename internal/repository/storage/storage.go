@@ -0,0 +1,20 @@
+package storage
+
+import (
+	"fmt"
+
+	"github.com/qwaq-dev/culina/internal/repository"
+	"github.com/qwaq-dev/culina/pkg/config"
+)
+
+// New creates the blob store selected by the config.
+func New(cfg config.Storage) (repository.BlobStore, error) {
+	switch cfg.Backend {
+	case "", "local":
+		return NewLocalStore(cfg.LocalDir)
+	case "s3":
+		return NewS3Store(cfg.S3)
+	}
+
+	return nil, fmt.Errorf("unknown storage backend %q", cfg.Backend)
+}
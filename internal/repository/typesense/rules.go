@@ -0,0 +1,156 @@
+package typesense
+
+import (
+	"context"
+	"log/slog"
+	"strconv"
+	"strings"
+
+	"github.com/qwaq-dev/culina/pkg/logger/sl"
+	"github.com/qwaq-dev/culina/structures"
+	"github.com/typesense/typesense-go/v3/typesense/api"
+	"github.com/typesense/typesense-go/v3/typesense/api/pointer"
+)
+
+// stopwordsSet is the name of the stop words set used by every search.
+const stopwordsSet = "recipes"
+
+// Ids of rules in Typesense are made of the database ids.
+const (
+	synonymPrefix  = "synonym-"
+	overridePrefix = "override-"
+)
+
+// SyncRules pushes synonyms, overrides and stop words from the database
+// to the current collection, rules deleted from the database are deleted.
+func (t *Typesense) SyncRules() error {
+	ctx := context.Background()
+
+	current, err := t.currentCollection(ctx)
+	if err != nil {
+		t.log.Error("Error with getting Typesense alias", sl.Err(err))
+		return err
+	}
+	if current == "" {
+		// nothing is indexed yet, the reindex applies the rules
+		return nil
+	}
+
+	return t.applyRules(ctx, current)
+}
+
+func (t *Typesense) applyRules(ctx context.Context, collection string) error {
+	synonyms, err := t.rulesRepo.SelectSynonyms(t.log)
+	if err != nil {
+		return err
+	}
+	if err := t.applySynonyms(ctx, collection, synonyms); err != nil {
+		t.log.Error("Error with pushing synonyms to Typesense", slog.String("collection", collection), sl.Err(err))
+		return err
+	}
+
+	overrides, err := t.rulesRepo.SelectOverrides(t.log)
+	if err != nil {
+		return err
+	}
+	if err := t.applyOverrides(ctx, collection, overrides); err != nil {
+		t.log.Error("Error with pushing overrides to Typesense", slog.String("collection", collection), sl.Err(err))
+		return err
+	}
+
+	stopwords, err := t.rulesRepo.SelectStopwords(t.log)
+	if err != nil {
+		return err
+	}
+	_, err = t.client.Stopwords().Upsert(ctx, stopwordsSet, &api.StopwordsSetUpsertSchema{Stopwords: stopwords})
+	if err != nil {
+		t.log.Error("Error with pushing stop words to Typesense", sl.Err(err))
+		return err
+	}
+
+	t.log.Info("Search rules were pushed to Typesense", slog.String("collection", collection),
+		slog.Int("synonyms", len(synonyms)), slog.Int("overrides", len(overrides)), slog.Int("stopwords", len(stopwords)))
+	return nil
+}
+
+func (t *Typesense) applySynonyms(ctx context.Context, collection string, synonyms []structures.SearchSynonym) error {
+	keep := make(map[string]bool)
+	for _, synonym := range synonyms {
+		id := synonymPrefix + strconv.Itoa(synonym.Id)
+		keep[id] = true
+
+		schema := &api.SearchSynonymSchema{Synonyms: synonym.Synonyms}
+		if synonym.Root != "" {
+			schema.Root = pointer.String(synonym.Root)
+		}
+		if _, err := t.client.Collection(collection).Synonyms().Upsert(ctx, id, schema); err != nil {
+			return err
+		}
+	}
+
+	existing, err := t.client.Collection(collection).Synonyms().Retrieve(ctx)
+	if err != nil {
+		return err
+	}
+	for _, synonym := range existing {
+		if synonym.Id == nil || keep[*synonym.Id] || !strings.HasPrefix(*synonym.Id, synonymPrefix) {
+			continue
+		}
+		if _, err := t.client.Collection(collection).Synonym(*synonym.Id).Delete(ctx); err != nil && !isNotFound(err) {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (t *Typesense) applyOverrides(ctx context.Context, collection string, overrides []structures.SearchOverride) error {
+	keep := make(map[string]bool)
+	for _, override := range overrides {
+		id := overridePrefix + strconv.Itoa(override.Id)
+		keep[id] = true
+
+		if _, err := t.client.Collection(collection).Overrides().Upsert(ctx, id, overrideSchema(override)); err != nil {
+			return err
+		}
+	}
+
+	existing, err := t.client.Collection(collection).Overrides().Retrieve(ctx)
+	if err != nil {
+		return err
+	}
+	for _, override := range existing {
+		if override.Id == nil || keep[*override.Id] || !strings.HasPrefix(*override.Id, overridePrefix) {
+			continue
+		}
+		if _, err := t.client.Collection(collection).Override(*override.Id).Delete(ctx); err != nil && !isNotFound(err) {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func overrideSchema(override structures.SearchOverride) *api.SearchOverrideSchema {
+	match := api.Exact
+	if override.Match == structures.OverrideContains {
+		match = api.Contains
+	}
+
+	includes := make([]api.SearchOverrideInclude, len(override.Pinned))
+	for i, id := range override.Pinned {
+		includes[i] = api.SearchOverrideInclude{Id: strconv.Itoa(id), Position: i + 1}
+	}
+	excludes := make([]api.SearchOverrideExclude, len(override.Hidden))
+	for i, id := range override.Hidden {
+		excludes[i] = api.SearchOverrideExclude{Id: strconv.Itoa(id)}
+	}
+
+	return &api.SearchOverrideSchema{
+		Rule:     api.SearchOverrideRule{Query: pointer.String(override.Query), Match: &match},
+		Includes: &includes,
+		Excludes: &excludes,
+		// keep applying other overrides matching the query
+		StopProcessing: pointer.False(),
+	}
+}
@@ -0,0 +1,259 @@
+package typesense
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/qwaq-dev/culina/pkg/logger/sl"
+	"github.com/typesense/typesense-go/v3/typesense"
+	"github.com/typesense/typesense-go/v3/typesense/api"
+	"github.com/typesense/typesense-go/v3/typesense/api/pointer"
+)
+
+// schemaVersion must be bumped on every change of recipeFields,
+// the next start builds a new collection with the changed schema.
+const schemaVersion = 4
+
+// aliasName is what all requests use. It points to the current
+// recipes_v<version>_<unix time> collection.
+const aliasName = "recipes"
+
+// importBatchSize is the number of recipes sent in one import request.
+const importBatchSize = 500
+
+var recipeFields = []api.Field{
+	{Name: "id", Type: "string"},
+	// name and descr are shown, the texts are searched by the fields
+	// of their language, each tokenized by its own locale
+	{Name: "name", Type: "string", Index: pointer.False(), Optional: pointer.True()},
+	{Name: "descr", Type: "string", Index: pointer.False(), Optional: pointer.True()},
+	{Name: "name_ru", Type: "string", Locale: pointer.String("ru"), Optional: pointer.True()},
+	{Name: "descr_ru", Type: "string", Locale: pointer.String("ru"), Optional: pointer.True()},
+	{Name: "name_en", Type: "string", Optional: pointer.True()}, // the default locale is English
+	{Name: "descr_en", Type: "string", Optional: pointer.True()},
+	{Name: "lang", Type: "string", Facet: pointer.True()},
+	{Name: "translations", Type: "string", Index: pointer.False(), Optional: pointer.True()},
+	{Name: "diff", Type: "string", Facet: pointer.True()},
+	{Name: "cook_time", Type: "int32"},
+	{Name: "calories", Type: "int32"},
+	{Name: "filters", Type: "string[]", Facet: pointer.True()},
+	{Name: "imgs", Type: "string"},
+	{Name: "img_placeholders", Type: "string", Index: pointer.False(), Optional: pointer.True()},
+	{Name: "authorid", Type: "string"},
+	{Name: "ingredients", Type: "string"},
+	{Name: "ingredient_names", Type: "string[]", Facet: pointer.True()},
+	{Name: "steps", Type: "string"},
+	{Name: "review_count", Type: "int32"},
+	{Name: "avg_rating", Type: "float"},
+}
+
+// Init prepares the index at startup. The recipes are reindexed only if the
+// collection doesn't exist yet or was built with another schema version,
+// the search rules are pushed every time.
+func (t *Typesense) Init() error {
+	current, err := t.currentCollection(context.Background())
+	if err != nil {
+		t.log.Error("Error with getting Typesense alias", sl.Err(err))
+		return err
+	}
+
+	if collectionVersion(current) == schemaVersion {
+		t.log.Info("Typesense collection is up to date", slog.String("collection", current))
+		// rules may have changed while the server was down
+		return t.applyRules(context.Background(), current)
+	}
+
+	t.log.Info("Typesense schema changed, reindexing", slog.String("collection", current), slog.Int("version", schemaVersion))
+	return t.Reindex()
+}
+
+// Reindex builds a new collection with all published recipes and then
+// points the alias to it, so searches keep working during the reindex.
+func (t *Typesense) Reindex() error {
+	ctx := context.Background()
+	name := fmt.Sprintf("%s_v%d_%d", aliasName, schemaVersion, time.Now().Unix())
+
+	_, err := t.client.Collections().Create(ctx, &api.CollectionSchema{Name: name, Fields: recipeFields})
+	if err != nil {
+		t.log.Error("Error with creating Typesense collection", slog.String("collection", name), sl.Err(err))
+		return err
+	}
+
+	imported, failed, err := t.importRecipes(ctx, name)
+	if err != nil {
+		t.log.Error("Error with importing recipes into Typesense", slog.String("collection", name), sl.Err(err))
+		t.dropCollection(ctx, name)
+		return err
+	}
+
+	if err := t.applyRules(ctx, name); err != nil {
+		t.dropCollection(ctx, name)
+		return err
+	}
+
+	current, err := t.currentCollection(ctx)
+	if err != nil {
+		t.dropCollection(ctx, name)
+		return err
+	}
+	if current == "" {
+		// before versioning the collection itself was called recipes,
+		// it has to go before an alias with the same name is created
+		t.dropCollection(ctx, aliasName)
+	}
+
+	if _, err := t.client.Aliases().Upsert(ctx, aliasName, &api.CollectionAliasSchema{CollectionName: name}); err != nil {
+		t.log.Error("Error with updating Typesense alias", slog.String("collection", name), sl.Err(err))
+		t.dropCollection(ctx, name)
+		return err
+	}
+
+	t.log.Info("Typesense collection was rebuilt", slog.String("collection", name),
+		slog.Int("imported", imported), slog.Int("failed", failed))
+
+	t.dropOldCollections(ctx, aliasName, name)
+	return nil
+}
+
+// importRecipes sends recipes to the collection by the JSONL import API.
+// Documents Typesense rejects are logged and counted as failed.
+func (t *Typesense) importRecipes(ctx context.Context, collection string) (int, int, error) {
+	var imported, failed int
+	params := &api.ImportDocumentsParams{Action: pointer.Any(api.Create), ReturnId: pointer.True()}
+
+	afterId := 0
+	for {
+		recipes, err := t.dashboardRepo.SelectRecipesForIndex(ctx, afterId, importBatchSize, t.log)
+		if err != nil {
+			return imported, failed, err
+		}
+		if len(recipes) == 0 {
+			return imported, failed, nil
+		}
+		afterId = recipes[len(recipes)-1].Id
+
+		var body bytes.Buffer
+		enc := json.NewEncoder(&body)
+		for _, recipe := range recipes {
+			doc, err := recipe.ToTypesense()
+			if err != nil {
+				t.log.Error("Error converting recipe to Typesense format", slog.Int("id", recipe.Id), sl.Err(err))
+				failed++
+				continue
+			}
+			if err := enc.Encode(doc); err != nil {
+				return imported, failed, err
+			}
+		}
+
+		res, err := t.client.Collection(collection).Documents().ImportJsonl(ctx, &body, params)
+		if err != nil {
+			return imported, failed, err
+		}
+
+		ok, rejected, err := readImportResult(res, t.log)
+		res.Close()
+		if err != nil {
+			return imported, failed, err
+		}
+		imported += ok
+		failed += rejected
+	}
+}
+
+// readImportResult counts the per-document lines of an import response.
+func readImportResult(r io.Reader, log *slog.Logger) (int, int, error) {
+	var ok, failed int
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		var line struct {
+			Success bool   `json:"success"`
+			Error   string `json:"error"`
+			Id      string `json:"id"`
+		}
+		if err := json.Unmarshal(scanner.Bytes(), &line); err != nil {
+			return ok, failed, err
+		}
+
+		if line.Success {
+			ok++
+			continue
+		}
+		failed++
+		log.Error("Typesense rejected recipe", slog.String("id", line.Id), slog.String("error", line.Error))
+	}
+
+	return ok, failed, scanner.Err()
+}
+
+// currentCollection returns the collection the alias points to, or "" without the alias.
+func (t *Typesense) currentCollection(ctx context.Context) (string, error) {
+	alias, err := t.client.Alias(aliasName).Retrieve(ctx)
+	if isNotFound(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	return alias.CollectionName, nil
+}
+
+// dropOldCollections removes collections of previous reindexes of the alias,
+// including ones left by reindexes which failed halfway.
+func (t *Typesense) dropOldCollections(ctx context.Context, alias, keep string) {
+	collections, err := t.client.Collections().Retrieve(ctx)
+	if err != nil {
+		t.log.Error("Error with listing Typesense collections", sl.Err(err))
+		return
+	}
+
+	for _, collection := range collections {
+		if collection.Name != keep && strings.HasPrefix(collection.Name, alias+"_v") {
+			t.dropCollection(ctx, collection.Name)
+		}
+	}
+}
+
+func (t *Typesense) dropCollection(ctx context.Context, name string) {
+	_, err := t.client.Collection(name).Delete(ctx)
+	if err != nil && !isNotFound(err) {
+		t.log.Error("Error with deleting Typesense collection", slog.String("collection", name), sl.Err(err))
+		return
+	}
+	if err == nil {
+		t.log.Info("Typesense collection was deleted", slog.String("collection", name))
+	}
+}
+
+// collectionVersion parses the schema version from a recipes_v<version>_<time> name.
+func collectionVersion(name string) int {
+	rest, ok := strings.CutPrefix(name, aliasName+"_v")
+	if !ok {
+		return 0
+	}
+	version, _, _ := strings.Cut(rest, "_")
+
+	v, err := strconv.Atoi(version)
+	if err != nil {
+		return 0
+	}
+	return v
+}
+
+func isNotFound(err error) bool {
+	var httpErr *typesense.HTTPError
+	return errors.As(err, &httpErr) && httpErr.Status == http.StatusNotFound
+}
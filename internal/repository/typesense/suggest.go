@@ -0,0 +1,129 @@
+package typesense
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"github.com/qwaq-dev/culina/pkg/logger/sl"
+	"github.com/qwaq-dev/culina/structures"
+	"github.com/typesense/typesense-go/v3/typesense/api"
+	"github.com/typesense/typesense-go/v3/typesense/api/pointer"
+)
+
+// suggestAlias points to the suggestions_v<version>_<unix time> collection,
+// it is rebuilt from scratch every time, so the version never changes.
+const suggestAlias = "suggestions"
+
+const suggestSchemaVersion = 1
+
+var suggestFields = []api.Field{
+	{Name: "text", Type: "string", Locale: pointer.String("Ru")},
+	{Name: "kind", Type: "string", Facet: pointer.True()},
+	{Name: "weight", Type: "int32"},
+}
+
+type suggestDocument struct {
+	Id     string `json:"id"`
+	Text   string `json:"text"`
+	Kind   string `json:"kind"`
+	Weight int    `json:"weight"`
+}
+
+// Suggest completes the prefix with any word of the suggestions. Without
+// a prefix the most popular queries are returned.
+func (t *Typesense) Suggest(prefix string, limit int) ([]structures.Suggestion, error) {
+	params := &api.SearchCollectionParams{
+		Q:       pointer.String(prefix),
+		QueryBy: pointer.String("text"),
+		Prefix:  pointer.String("true"),
+		SortBy:  pointer.String("_text_match:desc,weight:desc"),
+		PerPage: pointer.Int(limit),
+	}
+	if prefix == "" {
+		params.Q = pointer.String("*")
+		params.FilterBy = pointer.String("kind:=" + structures.SuggestQuery)
+		params.SortBy = pointer.String("weight:desc")
+	}
+
+	res, err := t.client.Collection(suggestAlias).Documents().Search(context.Background(), params)
+	if err != nil {
+		t.log.Error("Error searching suggestions in Typesense", sl.Err(err))
+		return nil, err
+	}
+
+	suggestions := []structures.Suggestion{}
+	if res.Hits != nil {
+		for _, hit := range *res.Hits {
+			if hit.Document == nil {
+				continue
+			}
+			suggestions = append(suggestions, structures.Suggestion{
+				Text:   getString(*hit.Document, "text"),
+				Kind:   getString(*hit.Document, "kind"),
+				Weight: getInt(*hit.Document, "weight"),
+			})
+		}
+	}
+
+	return suggestions, nil
+}
+
+// RebuildSuggestions imports the terms into a new collection and points
+// the suggestions alias to it.
+func (t *Typesense) RebuildSuggestions(terms []structures.Suggestion) error {
+	ctx := context.Background()
+	name := fmt.Sprintf("%s_v%d_%d", suggestAlias, suggestSchemaVersion, time.Now().Unix())
+
+	schema := &api.CollectionSchema{Name: name, Fields: suggestFields, DefaultSortingField: pointer.String("weight")}
+	if _, err := t.client.Collections().Create(ctx, schema); err != nil {
+		t.log.Error("Error with creating Typesense collection", slog.String("collection", name), sl.Err(err))
+		return err
+	}
+
+	if err := t.importSuggestions(ctx, name, terms); err != nil {
+		t.log.Error("Error with importing suggestions into Typesense", slog.String("collection", name), sl.Err(err))
+		t.dropCollection(ctx, name)
+		return err
+	}
+
+	if _, err := t.client.Aliases().Upsert(ctx, suggestAlias, &api.CollectionAliasSchema{CollectionName: name}); err != nil {
+		t.log.Error("Error with updating Typesense alias", slog.String("collection", name), sl.Err(err))
+		t.dropCollection(ctx, name)
+		return err
+	}
+
+	t.dropOldCollections(ctx, suggestAlias, name)
+	return nil
+}
+
+func (t *Typesense) importSuggestions(ctx context.Context, collection string, terms []structures.Suggestion) error {
+	params := &api.ImportDocumentsParams{Action: pointer.Any(api.Create), ReturnId: pointer.True()}
+
+	for start := 0; start < len(terms); start += importBatchSize {
+		var body bytes.Buffer
+		enc := json.NewEncoder(&body)
+		for i, term := range terms[start:min(start+importBatchSize, len(terms))] {
+			doc := suggestDocument{Id: strconv.Itoa(start + i), Text: term.Text, Kind: term.Kind, Weight: term.Weight}
+			if err := enc.Encode(doc); err != nil {
+				return err
+			}
+		}
+
+		res, err := t.client.Collection(collection).Documents().ImportJsonl(ctx, &body, params)
+		if err != nil {
+			return err
+		}
+		_, _, err = readImportResult(res, t.log)
+		res.Close()
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -3,10 +3,14 @@ package typesense
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"log/slog"
+	"slices"
+	"strconv"
 	"strings"
+	"time"
 
-	"github.com/gofiber/fiber/v2/log"
+	"github.com/qwaq-dev/culina/internal/repository"
 	"github.com/qwaq-dev/culina/internal/repository/postgres"
 	"github.com/qwaq-dev/culina/pkg/config"
 	"github.com/qwaq-dev/culina/pkg/logger/sl"
@@ -16,211 +20,279 @@ import (
 	"github.com/typesense/typesense-go/v3/typesense/api/pointer"
 )
 
+// healthTimeout bounds the health check, a slow Typesense is as bad as a dead one.
+const healthTimeout = 2 * time.Second
+
 type Typesense struct {
 	dashboardRepo postgres.PostgresDashboardRepository
+	rulesRepo     repository.SearchRulesRepository
+	client        *typesense.Client
 	log           *slog.Logger
 	cfg           config.Typesense
 }
 
-func NewTypesense(repo postgres.PostgresDashboardRepository, log *slog.Logger, cfg config.Typesense) *Typesense {
+func NewTypesense(repo postgres.PostgresDashboardRepository, rulesRepo repository.SearchRulesRepository, log *slog.Logger, cfg config.Typesense) *Typesense {
 	return &Typesense{
 		dashboardRepo: repo,
-		log:           log,
-		cfg:           cfg,
+		rulesRepo:     rulesRepo,
+		client: typesense.NewClient(
+			typesense.WithServer(cfg.Host),
+			typesense.WithAPIKey(cfg.APIKey),
+		),
+		log: log,
+		cfg: cfg,
 	}
 }
 
-func (t *Typesense) ConnectToTypesense() error {
-	client := typesense.NewClient(
-		typesense.WithServer(t.cfg.Host),
-		typesense.WithAPIKey(t.cfg.APIKey),
-	)
-
-	schema := &api.CollectionSchema{
-		Name: "recipes",
-		Fields: []api.Field{
-			{Name: "id", Type: "string"},
-			{Name: "name", Type: "string", Index: pointer.True(), Locale: pointer.String("Ru")},
-			{Name: "descr", Type: "string", Index: pointer.True(), Locale: pointer.String("Ru")},
-			{Name: "diff", Type: "string"},
-			{Name: "filters", Type: "string[]", Facet: pointer.True()},
-			{Name: "imgs", Type: "string"},
-			{Name: "authorid", Type: "string"},
-			{Name: "ingredients", Type: "string"},
-			{Name: "steps", Type: "string"},
-			{Name: "review_count", Type: "int32"},
-			{Name: "avg_rating", Type: "float"},
-		},
-	}
-
-	_, err := client.Collections().Create(context.Background(), schema)
-	if err != nil {
-		client.Collection("recipes").Delete(context.Background())
-	}
+func (t *Typesense) IndexRecipe(recipe structures.Recipes) error {
+	client := t.client
 
-	collections, err := client.Collections().Create(context.Background(), schema)
-	if err != nil {
-		return err
-	}
-	collectionsJSON, err := json.MarshalIndent(collections.Name, "", "  ")
+	typesenseRecipe, err := recipe.ToTypesense()
 	if err != nil {
-		log.Error("Error marshalling collections:", sl.Err(err))
 		return err
 	}
 
-	t.log.Info("Collection", slog.String("Name:", string(collectionsJSON)))
-
-	recipes, err := t.dashboardRepo.SelectAllRecipes(1, 10000000000, t.log)
+	// upsert, the recipe may be indexed again after it was restored or got new images
+	res, err := client.Collection(aliasName).Documents().Upsert(context.Background(), typesenseRecipe, &api.DocumentIndexParameters{})
 	if err != nil {
-		t.log.Error("Error with selecting recipes")
+		t.log.Error("Error inserting recipe into Typesense", sl.Err(err))
 		return err
 	}
 
-	for _, recipe := range recipes {
-		typesenseRecipe, err := recipe.ToTypesense()
-		if err != nil {
-			t.log.Error("Error converting recipe to Typesense format", sl.Err(err))
-			continue
-		}
-
-		res, err := client.Collection("recipes").Documents().Create(context.Background(), typesenseRecipe, &api.DocumentIndexParameters{})
-		if err != nil {
-			t.log.Error("Error inserting recipe into Typesense", sl.Err(err))
-		} else {
-			jsonRes, _ := json.MarshalIndent(res, "", "  ")
-			t.log.Info("Successfully inserted recipe into Typesense", slog.String("response", string(jsonRes)))
-		}
-	}
+	jsonRes, _ := json.MarshalIndent(res, "", "  ")
+	t.log.Info("Successfully inserted recipe into Typesense", slog.String("response", string(jsonRes)))
 
 	return nil
 }
 
-func (t *Typesense) AddRecipeToTypesense(recipe structures.Recipes) error {
-	client := typesense.NewClient(
-		typesense.WithServer(t.cfg.Host),
-		typesense.WithAPIKey(t.cfg.APIKey),
-	)
-
-	typesenseRecipe, err := recipe.ToTypesense()
+// IndexRecipeById loads the recipe from the database and puts it into the index.
+func (t *Typesense) IndexRecipeById(id int) error {
+	recipe, err := t.dashboardRepo.SelectRecipeById(context.Background(), id, t.log)
 	if err != nil {
+		t.log.Error("Error with selecting recipe", slog.Int("id", id), sl.Err(err))
 		return err
 	}
 
-	res, err := client.Collection("recipes").Documents().Create(context.Background(), typesenseRecipe, &api.DocumentIndexParameters{})
+	return t.IndexRecipe(recipe)
+}
+
+func (t *Typesense) DeleteRecipe(id int) error {
+	client := t.client
+
+	_, err := client.Collection(aliasName).Document(strconv.Itoa(id)).Delete(context.Background())
+	if isNotFound(err) {
+		return nil
+	}
 	if err != nil {
-		t.log.Error("Error inserting recipe into Typesense", sl.Err(err))
-	} else {
-		jsonRes, _ := json.MarshalIndent(res, "", "  ")
-		t.log.Info("Successfully inserted recipe into Typesense", slog.String("response", string(jsonRes)))
+		t.log.Error("Error deleting recipe from Typesense", slog.Int("id", id), sl.Err(err))
+		return err
 	}
 
+	t.log.Info("Recipe was deleted from Typesense", slog.Int("id", id))
 	return nil
 }
 
-func (t *Typesense) SearchWithTypesense(query string) ([]structures.TypesenseRecipe, error) {
-	client := typesense.NewClient(
-		typesense.WithServer(t.cfg.Host),
-		typesense.WithAPIKey(t.cfg.APIKey),
-	)
+// facetFields are counted for every search.
+const facetFields = "filters,diff,ingredient_names"
+
+// Search finds recipes by name and description, filtered by the structured
+// filters. Without a text query all recipes matching the filters are returned.
+func (t *Typesense) Search(query structures.SearchQuery) (structures.SearchResult, error) {
+	result := structures.SearchResult{Page: query.Page, Recipes: []structures.TypesenseRecipe{}}
 
+	q := query.Q
+	if strings.TrimSpace(q) == "" {
+		q = "*"
+	}
+
+	queryBy, queryByWeights := searchFields(query.Lang)
 	searchParameters := &api.SearchCollectionParams{
-		Q:       pointer.String(query),
-		QueryBy: pointer.String("name,descr"),
+		Q:              pointer.String(q),
+		QueryBy:        pointer.String(queryBy),
+		QueryByWeights: pointer.String(queryByWeights),
+		FacetBy:        pointer.String(facetFields),
+		Stopwords:      pointer.String(stopwordsSet),
+		MaxFacetValues: pointer.Int(50),
+		Page:           pointer.Int(query.Page),
+		PerPage:        pointer.Int(query.PerPage),
+	}
+	if filterBy := buildFilterBy(query); filterBy != "" {
+		searchParameters.FilterBy = pointer.String(filterBy)
 	}
 
-	res, err := client.Collection("recipes").Documents().Search(context.Background(), searchParameters)
+	res, err := t.client.Collection(aliasName).Documents().Search(context.Background(), searchParameters)
 	if err != nil {
 		t.log.Error("Error searching in Typesense", sl.Err(err))
-		return nil, err
+		return result, err
 	}
 
-	if res.Hits == nil {
-		t.log.Warn("No results found in Typesense")
-		return []structures.TypesenseRecipe{}, nil
+	if res.Found != nil {
+		result.Found = *res.Found
 	}
 
-	// Разыменовываем указатель
-	hits := *res.Hits
-
-	recipes := make([]structures.TypesenseRecipe, len(hits))
-	for i, hit := range hits {
-		if hit.Document == nil {
-			t.log.Error("Search result document is nil")
-			continue
+	if res.Hits != nil {
+		for _, hit := range *res.Hits {
+			if hit.Document == nil {
+				t.log.Error("Search result document is nil")
+				continue
+			}
+			result.Recipes = append(result.Recipes, documentToRecipe(*hit.Document).Localized(query.Lang))
 		}
+	}
 
-		doc := *hit.Document // Разыменовываем указатель
-
-		recipe := structures.TypesenseRecipe{
-			Id:           getString(doc, "id"),
-			Name:         getString(doc, "name"),
-			Descr:        getString(doc, "descr"),
-			Diff:         getString(doc, "diff"),
-			Filters:      toStringSlice(doc["filters"]),
-			Imgs:         getString(doc, "imgs"),
-			AuthorID:     getString(doc, "authorid"),
-			Ingredients:  getString(doc, "ingredients"),
-			Steps:        getString(doc, "steps"),
-			Review_count: getInt(doc, "review_count"),
-			Avg_rating:   getFloat(doc, "avg_rating"),
+	result.Facets = make(map[string][]structures.FacetCount)
+	if res.FacetCounts != nil {
+		for _, facet := range *res.FacetCounts {
+			if facet.FieldName == nil || facet.Counts == nil {
+				continue
+			}
+
+			counts := []structures.FacetCount{}
+			for _, count := range *facet.Counts {
+				if count.Value == nil || count.Count == nil {
+					continue
+				}
+				counts = append(counts, structures.FacetCount{Value: *count.Value, Count: *count.Count})
+			}
+			result.Facets[*facet.FieldName] = counts
 		}
+	}
 
-		recipes[i] = recipe
+	return result, nil
+}
+
+// searchFields returns the text fields of all languages, the fields of lang
+// first and weighted higher, so recipes in the user's language rank first.
+func searchFields(lang string) (string, string) {
+	languages := []string{}
+	if slices.Contains(structures.Languages, lang) {
+		languages = append(languages, lang)
+	}
+	for _, l := range structures.Languages {
+		if l != lang {
+			languages = append(languages, l)
+		}
 	}
 
-	log.Info(recipes)
+	var fields, weights []string
+	for i, l := range languages {
+		weight := 1
+		if i == 0 && l == lang {
+			weight = 2
+		}
+		fields = append(fields, "name_"+l, "descr_"+l)
+		weights = append(weights, strconv.Itoa(2*weight), strconv.Itoa(weight))
+	}
 
-	return recipes, nil
+	return strings.Join(fields, ","), strings.Join(weights, ",")
 }
 
-func (t *Typesense) FilterByTypesense(filters []string) ([]structures.TypesenseRecipe, error) {
-	client := typesense.NewClient(
-		typesense.WithServer(t.cfg.Host),
-		typesense.WithAPIKey(t.cfg.APIKey),
-	)
+// buildFilterBy translates the query filters to the Typesense filter_by syntax.
+func buildFilterBy(query structures.SearchQuery) string {
+	var conditions []string
 
-	searchParameters := &api.SearchCollectionParams{
-		Q:       pointer.String(strings.Join(filters, " ")), // Поиск всех элементов
-		QueryBy: pointer.String("filters"),
+	if len(query.Filters) > 0 {
+		if query.Match == structures.MatchAny {
+			conditions = append(conditions, "filters:="+filterValues(query.Filters))
+		} else {
+			for _, filter := range query.Filters {
+				conditions = append(conditions, "filters:="+filterValues([]string{filter}))
+			}
+		}
 	}
-
-	res, err := client.Collection("recipes").Documents().Search(context.Background(), searchParameters)
-	if err != nil {
-		t.log.Error("Ошибка при фильтрации в Typesense", sl.Err(err))
-		return nil, err
+	if len(query.Diff) > 0 {
+		conditions = append(conditions, "diff:="+filterValues(query.Diff))
+	}
+	for _, name := range query.With {
+		conditions = append(conditions, "ingredient_names:="+filterValues([]string{name}))
+	}
+	if len(query.Without) > 0 {
+		conditions = append(conditions, "ingredient_names:!="+filterValues(query.Without))
+	}
+	if query.AuthorId > 0 {
+		conditions = append(conditions, "authorid:="+filterValues([]string{strconv.Itoa(query.AuthorId)}))
+	}
+	if query.MinRating > 0 {
+		conditions = append(conditions, "avg_rating:>="+strconv.FormatFloat(float64(query.MinRating), 'f', -1, 32))
+	}
+	if query.MaxTime > 0 {
+		conditions = append(conditions, fmt.Sprintf("cook_time:[1..%d]", query.MaxTime))
+	}
+	if query.MinCalories > 0 {
+		conditions = append(conditions, fmt.Sprintf("calories:>=%d", query.MinCalories))
+	}
+	if query.MaxCalories > 0 {
+		conditions = append(conditions, fmt.Sprintf("calories:[1..%d]", query.MaxCalories))
 	}
 
-	if res.Hits == nil {
-		t.log.Warn("Фильтр не дал результатов")
-		return nil, nil
+	return strings.Join(conditions, " && ")
+}
+
+// filterValues quotes values with backticks, so commas and brackets
+// in them don't break the filter.
+func filterValues(values []string) string {
+	quoted := make([]string, len(values))
+	for i, value := range values {
+		quoted[i] = "`" + strings.ReplaceAll(value, "`", "") + "`"
 	}
+	return "[" + strings.Join(quoted, ",") + "]"
+}
 
-	recipes := make([]structures.TypesenseRecipe, len(*res.Hits))
-	for i, hit := range *res.Hits {
-		if hit.Document == nil {
-			t.log.Error("Документ в результате поиска пуст")
-			continue
-		}
+func documentToRecipe(doc map[string]interface{}) structures.TypesenseRecipe {
+	return structures.TypesenseRecipe{
+		Id:               getString(doc, "id"),
+		Name:             getString(doc, "name"),
+		Descr:            getString(doc, "descr"),
+		Diff:             getString(doc, "diff"),
+		Cook_time:        getInt(doc, "cook_time"),
+		Calories:         getInt(doc, "calories"),
+		Filters:          toStringSlice(doc["filters"]),
+		Imgs:             getString(doc, "imgs"),
+		Placeholders:     getString(doc, "img_placeholders"),
+		AuthorID:         getString(doc, "authorid"),
+		Ingredients:      getString(doc, "ingredients"),
+		Ingredient_names: toStringSlice(doc["ingredient_names"]),
+		Lang:             getString(doc, "lang"),
+		Name_ru:          getString(doc, "name_ru"),
+		Descr_ru:         getString(doc, "descr_ru"),
+		Name_en:          getString(doc, "name_en"),
+		Descr_en:         getString(doc, "descr_en"),
+		Translations:     getString(doc, "translations"),
+		Steps:            getString(doc, "steps"),
+		Review_count:     getInt(doc, "review_count"),
+		Avg_rating:       getFloat(doc, "avg_rating"),
+	}
+}
 
-		doc := *hit.Document
-		recipes[i] = structures.TypesenseRecipe{
-			Id:           getString(doc, "id"),
-			Name:         getString(doc, "name"),
-			Descr:        getString(doc, "descr"),
-			Diff:         getString(doc, "diff"),
-			Filters:      toStringSlice(doc["filters"]),
-			Imgs:         getString(doc, "imgs"),
-			AuthorID:     getString(doc, "authorid"),
-			Ingredients:  getString(doc, "ingredients"),
-			Steps:        getString(doc, "steps"),
-			Review_count: getInt(doc, "review_count"),
-			Avg_rating:   getFloat(doc, "avg_rating"),
+// Export returns all documents of the collection, it is used to find drift from the database.
+func (t *Typesense) Export() ([]structures.TypesenseRecipe, error) {
+	res, err := t.client.Collection(aliasName).Documents().Export(context.Background(), &api.ExportDocumentsParams{})
+	if err != nil {
+		t.log.Error("Error exporting recipes from Typesense", sl.Err(err))
+		return nil, err
+	}
+	defer res.Close()
+
+	var recipes []structures.TypesenseRecipe
+	dec := json.NewDecoder(res)
+	for dec.More() {
+		var recipe structures.TypesenseRecipe
+		if err := dec.Decode(&recipe); err != nil {
+			t.log.Error("Error decoding exported recipe", sl.Err(err))
+			return nil, err
 		}
+		recipes = append(recipes, recipe)
 	}
 
 	return recipes, nil
 }
 
+// Healthy reports whether Typesense answers its health endpoint.
+func (t *Typesense) Healthy(ctx context.Context) bool {
+	ok, err := t.client.Health(ctx, healthTimeout)
+	return err == nil && ok
+}
+
 func getString(doc map[string]interface{}, key string) string {
 	if v, ok := doc[key].(string); ok {
 		return v
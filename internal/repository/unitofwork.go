@@ -0,0 +1,74 @@
+package repository
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+
+	"github.com/qwaq-dev/culina/pkg/logger/sl"
+)
+
+// UnitOfWork runs several repository calls in one transaction. Repositories
+// take the transaction from the context passed to fn, so the writes made with
+// it are committed or rolled back together, and reads see them.
+//
+// Slow side effects, like storing files, are done before Do with the context
+// of WithCompensations, so the transaction doesn't hold a connection while
+// they run. Their compensations run when the unit is rolled back.
+type UnitOfWork interface {
+	// Do commits when fn returns nil. Otherwise, or when the commit fails,
+	// the transaction is rolled back and the compensations registered by
+	// OnRollback run. A Do inside another one joins the outer unit.
+	Do(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+type compensationsKey struct{}
+
+// Compensations undo side effects of a unit of work outside the database,
+// like stored files.
+type Compensations struct {
+	mu   sync.Mutex
+	undo []func(ctx context.Context) error
+}
+
+// WithCompensations starts collecting compensations of a unit of work,
+// or returns the ones ctx already collects.
+func WithCompensations(ctx context.Context) (context.Context, *Compensations) {
+	if compensations, ok := ctx.Value(compensationsKey{}).(*Compensations); ok {
+		return ctx, compensations
+	}
+
+	compensations := &Compensations{}
+	return context.WithValue(ctx, compensationsKey{}, compensations), compensations
+}
+
+// OnRollback registers undo to run if the unit of work of ctx is rolled
+// back. Without compensations in ctx there is nothing to roll back and undo
+// is dropped.
+func OnRollback(ctx context.Context, undo func(ctx context.Context) error) {
+	compensations, ok := ctx.Value(compensationsKey{}).(*Compensations)
+	if !ok {
+		return
+	}
+
+	compensations.mu.Lock()
+	compensations.undo = append(compensations.undo, undo)
+	compensations.mu.Unlock()
+}
+
+// Run undoes the side effects in reverse order. Failures are logged, what
+// is left behind is up to the background cleanups. Compensations run after
+// the deadline of ctx too.
+func (c *Compensations) Run(ctx context.Context, log *slog.Logger) {
+	ctx = context.WithoutCancel(ctx)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for i := len(c.undo) - 1; i >= 0; i-- {
+		if err := c.undo[i](ctx); err != nil {
+			log.ErrorContext(ctx, "Error with compensating rolled back changes", sl.Err(err))
+		}
+	}
+	c.undo = nil
+}
@@ -6,7 +6,12 @@ import (
 	"github.com/gofiber/fiber/v2"
 	"github.com/qwaq-dev/culina/internal/handlers"
 	"github.com/qwaq-dev/culina/internal/repository"
-	"github.com/qwaq-dev/culina/internal/repository/typesense"
+	"github.com/qwaq-dev/culina/internal/service"
+	"github.com/qwaq-dev/culina/internal/service/analytics"
+	"github.com/qwaq-dev/culina/internal/service/images"
+	"github.com/qwaq-dev/culina/internal/service/ingredients"
+	"github.com/qwaq-dev/culina/internal/service/search"
+	"github.com/qwaq-dev/culina/internal/service/textfilter"
 )
 
 func InitRoutes(
@@ -14,33 +19,103 @@ func InitRoutes(
 	userRepo repository.UserRepository,
 	profileRepo repository.ProfileRepository,
 	dashboardRepo repository.DashboardRepository,
-	ts typesense.Typesense,
+	uow repository.UnitOfWork,
+	commentRepo repository.CommentRepository,
+	moderationRepo repository.ModerationRepository,
+	searchRulesRepo repository.SearchRulesRepository,
+	analyticsRepo repository.AnalyticsRepository,
+	searchIndex repository.SearchIndex,
+	suggestions *search.Suggestions,
+	ingredients *ingredients.Normalizer,
+	recorder *analytics.Recorder,
+	filter *textfilter.Pipeline,
+	uploader *service.Uploader,
+	store repository.BlobStore,
+	signer *images.Signer,
+	resumable *service.ResumableUploads,
 ) {
 	dashboard := app.Group("/dashboard")
 	profile := app.Group("/profile")
 	user := app.Group("/user")
+	moderation := app.Group("/moderation")
+	searchAdmin := app.Group("/admin/search")
 	userHandler := handlers.NewUserHandler(userRepo, log)
 	profileHandler := handlers.NewProfileHandler(profileRepo, log)
-	dashboardHandler := handlers.NewDashboardHandler(dashboardRepo, log, ts)
+	dashboardHandler := handlers.NewDashboardHandler(dashboardRepo, uow, log, searchIndex, suggestions, ingredients, recorder, filter, uploader, signer)
+	commentHandler := handlers.NewCommentHandler(commentRepo, log)
+	moderationHandler := handlers.NewModerationHandler(moderationRepo, log)
+	searchRulesHandler := handlers.NewSearchRulesHandler(searchRulesRepo, searchIndex, log)
+	analyticsHandler := handlers.NewAnalyticsHandler(analyticsRepo, log)
+	imageHandler := handlers.NewImageHandler(store, dashboardRepo, signer, log)
+	uploadHandler := handlers.NewUploadHandler(resumable, dashboardRepo, uow, log)
 
 	//Routes for dashboard page
 	dashboard.Post("/create-recipe", dashboardHandler.CreateRecipe)
 	dashboard.Post("/add-review", dashboardHandler.AddReview)
-	dashboard.Post("/filter", dashboardHandler.Filter)
-	dashboard.Get("/search-recipes/:query", dashboardHandler.SearchByTypesense)
+	dashboard.Put("/review/:id", dashboardHandler.UpdateReview)
+	dashboard.Delete("/review/:id", dashboardHandler.DeleteReview)
+	dashboard.Post("/review/:id/vote", dashboardHandler.VoteReview)
+	dashboard.Post("/search/click", dashboardHandler.SearchClick)
+	dashboard.Get("/suggest", dashboardHandler.Suggest)    // ?q=*&limit=*
+	dashboard.Get("/search", dashboardHandler.Search)      // ?q=*&filters=*,*&match=all|any&diff=*&with=*,*&without=*,*&author=*&min_rating=*&max_time=*&min_calories=*&max_calories=*&page=*&pageSize=*&user_id=*
 	dashboard.Get("/recipes", dashboardHandler.AllRecipes) // localhost:8080/dashboard/recipes?page=*&pageSize=*
 	dashboard.Get("/recipe/:id", dashboardHandler.RecipeById)
+	dashboard.Get("/recipe/:id/reviews", dashboardHandler.RecipeReviews) // ?page=*&pageSize=*&sort=helpful|newest|rating
+	dashboard.Get("/recipe/:id/photos", dashboardHandler.RecipePhotos)   // ?page=*&pageSize=*
+	dashboard.Post("/recipe/:id/pin-photo", dashboardHandler.PinPhoto)
+	dashboard.Get("/drafts/:authorId", dashboardHandler.Drafts)
+	dashboard.Post("/recipe/:id/publish", dashboardHandler.PublishRecipe)
+	dashboard.Post("/recipe/:id/media", uploadHandler.AttachToRecipe)
+
+	//Routes for recipe comments
+	dashboard.Get("/recipe/:id/comments", commentHandler.RecipeComments) // ?page=*&pageSize=*
+	dashboard.Post("/recipe/:id/comments", commentHandler.AddComment)
+	dashboard.Put("/comment/:id", commentHandler.UpdateComment)
+	dashboard.Delete("/comment/:id", commentHandler.DeleteComment)
 
 	//Routes for profile page
 	profile.Post("/username", profileHandler.ChangeUsername)
 	profile.Post("/password", profileHandler.ChangePassword)
 	profile.Post("/sex", profileHandler.ChangeSex)
 	profile.Get("/recipe/:author", profileHandler.RecipesFromThisAutor)
+	profile.Get("/notifications/:userId", profileHandler.Notifications) // ?unread=true
+	profile.Post("/notifications/read", profileHandler.ReadNotifications)
 
 	//Routes for user
 	user.Post("/sign-in", userHandler.SignIn)
 	user.Post("/sign-up", userHandler.SignUp)
 	user.Get("/auth", userHandler.Auth)
 
+	//Routes for moderation
+	moderation.Post("/report", moderationHandler.Report)
+	moderation.Get("/queue", moderationHandler.Queue) // ?moderator_id=*&status=open|claimed|resolved&page=*&pageSize=*
+	moderation.Post("/report/:id/claim", moderationHandler.Claim)
+	moderation.Post("/report/:id/resolve", moderationHandler.Resolve)
+	moderation.Get("/audit", moderationHandler.Audit) // ?moderator_id=*&target_type=*&target_id=*
+
+	//Routes for search tuning
+	searchAdmin.Get("/synonyms", searchRulesHandler.Synonyms) // ?admin_id=*
+	searchAdmin.Post("/synonyms", searchRulesHandler.AddSynonym)
+	searchAdmin.Put("/synonyms/:id", searchRulesHandler.UpdateSynonym)
+	searchAdmin.Delete("/synonyms/:id", searchRulesHandler.DeleteSynonym)
+	searchAdmin.Get("/overrides", searchRulesHandler.Overrides) // ?admin_id=*
+	searchAdmin.Post("/overrides", searchRulesHandler.AddOverride)
+	searchAdmin.Put("/overrides/:id", searchRulesHandler.UpdateOverride)
+	searchAdmin.Delete("/overrides/:id", searchRulesHandler.DeleteOverride)
+	searchAdmin.Get("/stopwords", searchRulesHandler.Stopwords) // ?admin_id=*
+	searchAdmin.Put("/stopwords", searchRulesHandler.ReplaceStopwords)
+	searchAdmin.Get("/analytics/top-queries", analyticsHandler.TopQueries) // ?admin_id=*&from=*&to=*&limit=*
+	searchAdmin.Get("/analytics/zero-results", analyticsHandler.ZeroResults)
+	searchAdmin.Get("/analytics/ctr", analyticsHandler.Ctr) // &min_searches=*
+
+	//Routes for resumable uploads
+	app.Post("/uploads", uploadHandler.Create)
+	app.Get("/uploads/:id", uploadHandler.Status)       // HEAD returns only Upload-Offset and Upload-Length
+	app.Patch("/uploads/:id", uploadHandler.WriteChunk) // Upload-Offset: *, Upload-Checksum: sha256 *
+	app.Delete("/uploads/:id", uploadHandler.Cancel)
+
+	//Routes for uploaded images
+	app.Get("/images/*", imageHandler.Serve) // ?expires=*&sig=* for draft and hidden recipes
+
 	log.Debug("All routes was initialized")
 }
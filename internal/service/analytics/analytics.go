@@ -0,0 +1,115 @@
+package analytics
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+	"time"
+
+	"github.com/qwaq-dev/culina/internal/repository"
+	"github.com/qwaq-dev/culina/pkg/config"
+	"github.com/qwaq-dev/culina/structures"
+)
+
+// Recorder writes searches and clicks in the background, so logging never
+// slows a search down. Events are dropped while the queue is full.
+type Recorder struct {
+	repo     repository.AnalyticsRepository
+	searches chan structures.SearchLogEntry
+	clicks   chan structures.SearchClick
+	cfg      config.Analytics
+	log      *slog.Logger
+}
+
+func New(repo repository.AnalyticsRepository, cfg config.Analytics, log *slog.Logger) *Recorder {
+	return &Recorder{
+		repo:     repo,
+		searches: make(chan structures.SearchLogEntry, cfg.QueueSize),
+		clicks:   make(chan structures.SearchClick, cfg.QueueSize),
+		cfg:      cfg,
+		log:      log,
+	}
+}
+
+// NewSearchId returns an id for a search, clients send it back with clicks.
+func NewSearchId() string {
+	id := make([]byte, 16)
+	rand.Read(id)
+	return hex.EncodeToString(id)
+}
+
+func (r *Recorder) LogSearch(entry structures.SearchLogEntry) {
+	select {
+	case r.searches <- entry:
+	default:
+		r.log.Warn("Analytics queue is full, search is not logged")
+	}
+}
+
+func (r *Recorder) LogClick(click structures.SearchClick) {
+	select {
+	case r.clicks <- click:
+	default:
+		r.log.Warn("Analytics queue is full, click is not logged")
+	}
+}
+
+// Start writes the queued events every FlushInterval or once BatchSize of them is queued.
+func (r *Recorder) Start() {
+	go func() {
+		ticker := time.NewTicker(r.cfg.FlushInterval)
+		defer ticker.Stop()
+
+		var searches []structures.SearchLogEntry
+		var clicks []structures.SearchClick
+		for {
+			select {
+			case entry := <-r.searches:
+				searches = append(searches, entry)
+				if len(searches) < r.cfg.BatchSize {
+					continue
+				}
+			case click := <-r.clicks:
+				clicks = append(clicks, click)
+				if len(clicks) < r.cfg.BatchSize {
+					continue
+				}
+			case <-ticker.C:
+			}
+
+			searches, clicks = r.flush(searches, clicks)
+		}
+	}()
+}
+
+// StartCleanup removes searches and clicks older than Retention every
+// CleanupInterval in the background.
+func (r *Recorder) StartCleanup() {
+	go func() {
+		ticker := time.NewTicker(r.cfg.CleanupInterval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			deleted, err := r.repo.DeleteSearchesBefore(time.Now().Add(-r.cfg.Retention), r.log)
+			if err != nil {
+				continue
+			}
+
+			if deleted > 0 {
+				r.log.Info("Old searches were removed", slog.Int64("count", deleted))
+			}
+		}
+	}()
+}
+
+// flush writes the events and returns what has to be written again. Failed
+// batches are kept until they grow over BatchSize, then they are dropped.
+func (r *Recorder) flush(searches []structures.SearchLogEntry, clicks []structures.SearchClick) ([]structures.SearchLogEntry, []structures.SearchClick) {
+	if err := r.repo.InsertSearches(searches, r.log); err == nil || len(searches) >= r.cfg.BatchSize {
+		searches = searches[:0]
+	}
+	if err := r.repo.InsertClicks(clicks, r.log); err == nil || len(clicks) >= r.cfg.BatchSize {
+		clicks = clicks[:0]
+	}
+	return searches, clicks
+}
@@ -0,0 +1,16 @@
+package service
+
+import "errors"
+
+var (
+	ErrNoImages      = errors.New("no files upload")
+	ErrTooManyImages = errors.New("too many images")
+
+	ErrUploadTooLarge   = errors.New("upload is too large")
+	ErrChunkTooLarge    = errors.New("chunk is too large")
+	ErrInvalidChecksum  = errors.New("invalid checksum header, expected \"sha256 <base64>\"")
+	ErrChecksumMismatch = errors.New("chunk checksum mismatch")
+	ErrUploadIncomplete = errors.New("upload is not complete")
+	ErrNotUploadOwner   = errors.New("user is not the upload owner")
+	ErrUnsupportedMedia = errors.New("unsupported media type")
+)
@@ -0,0 +1,153 @@
+package imagegc
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/qwaq-dev/culina/internal/repository"
+	"github.com/qwaq-dev/culina/internal/service"
+	"github.com/qwaq-dev/culina/pkg/logger/sl"
+)
+
+// Report describes one gc run. In dry run Removed lists files that would be removed.
+type Report struct {
+	DryRun     bool     `json:"dry_run"`
+	Scanned    int      `json:"scanned"`    // files in the store
+	Referenced int      `json:"referenced"` // files used by recipes and reviews
+	Recounted  int      `json:"recounted"`  // blobs with a wrong reference count
+	Removed    []string `json:"removed"`
+	FreedBytes int64    `json:"freed_bytes"`
+	Failed     []string `json:"failed,omitempty"`
+}
+
+// Collector removes uploaded files no recipe or review references.
+// Files younger than the grace period are kept, they may belong to a recipe
+// or review which is being saved right now.
+type Collector struct {
+	repo  repository.ImageRepository
+	store repository.BlobStore
+	grace time.Duration
+	log   *slog.Logger
+}
+
+func New(repo repository.ImageRepository, store repository.BlobStore, grace time.Duration, log *slog.Logger) *Collector {
+	return &Collector{repo: repo, store: store, grace: grace, log: log}
+}
+
+// Start runs the gc every interval in the background.
+func (c *Collector) Start(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			report, err := c.Run(context.Background(), false)
+			if err != nil {
+				c.log.Error("Error with images gc", sl.Err(err))
+				continue
+			}
+
+			c.log.Info("Images gc finished",
+				slog.Int("removed", len(report.Removed)),
+				slog.Int64("freed_bytes", report.FreedBytes),
+				slog.Int("recounted", report.Recounted),
+				slog.Int("failed", len(report.Failed)))
+		}
+	}()
+}
+
+// Run fixes reference counts of tracked blobs and removes unreferenced files.
+// Files uploaded before blobs were tracked are removed when nothing uses them.
+func (c *Collector) Run(ctx context.Context, dryRun bool) (Report, error) {
+	report := Report{DryRun: dryRun, Removed: []string{}}
+	olderThan := time.Now().Add(-c.grace)
+
+	refs, err := c.repo.SelectImageRefs(c.log)
+	if err != nil {
+		return report, err
+	}
+	report.Referenced = len(refs)
+
+	blobs, err := c.repo.SelectBlobs(c.log)
+	if err != nil {
+		return report, err
+	}
+
+	tracked := make(map[string]bool, len(blobs))
+	for _, blob := range blobs {
+		tracked[blob.Key] = true
+
+		count := refs[blob.Key]
+		if count != blob.Ref_count {
+			report.Recounted++
+			if !dryRun {
+				if err := c.repo.UpdateBlobRefs(blob.Key, blob.Ref_count, count, c.log); err != nil {
+					report.Failed = append(report.Failed, blob.Key)
+					continue
+				}
+			}
+		}
+
+		if count > 0 || blob.Updated_at.After(olderThan) {
+			continue
+		}
+
+		if dryRun {
+			report.Removed = append(report.Removed, blob.Key)
+			report.FreedBytes += blob.Size
+			continue
+		}
+
+		removed, err := c.repo.DeleteBlob(blob.Key, olderThan, func(key string) error {
+			return c.remove(ctx, key)
+		}, c.log)
+		if err != nil {
+			report.Failed = append(report.Failed, blob.Key)
+			continue
+		}
+		if removed {
+			report.Removed = append(report.Removed, blob.Key)
+			report.FreedBytes += blob.Size
+		}
+	}
+
+	files, err := c.store.List(ctx, "")
+	if err != nil {
+		return report, err
+	}
+	report.Scanned = len(files)
+
+	for _, file := range files {
+		// chunks of unfinished uploads are removed when the upload expires
+		if strings.HasPrefix(file.Key, service.ChunksPrefix) {
+			continue
+		}
+		if tracked[file.Key] || refs[file.Key] > 0 || file.LastModified.After(olderThan) {
+			continue
+		}
+
+		if !dryRun {
+			if err := c.remove(ctx, file.Key); err != nil {
+				c.log.Error("Error with removing image", slog.String("key", file.Key), sl.Err(err))
+				report.Failed = append(report.Failed, file.Key)
+				continue
+			}
+		}
+
+		report.Removed = append(report.Removed, file.Key)
+		report.FreedBytes += file.Size
+	}
+
+	return report, nil
+}
+
+func (c *Collector) remove(ctx context.Context, key string) error {
+	err := c.store.Delete(ctx, key)
+	if errors.Is(err, repository.ErrBlobNotFound) {
+		return nil
+	}
+	return err
+}
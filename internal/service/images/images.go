@@ -0,0 +1,220 @@
+package images
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"net/http"
+
+	"github.com/HugoSmits86/nativewebp"
+	"github.com/qwaq-dev/culina/pkg/config"
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+)
+
+var (
+	ErrUnsupportedType = errors.New("unsupported image type")
+	ErrFileTooLarge    = errors.New("image file is too large")
+	ErrImageTooLarge   = errors.New("image dimensions are too large")
+)
+
+// Formats accepted for upload, detected by magic bytes, not by the file name.
+var allowedTypes = map[string]bool{
+	"image/jpeg": true,
+	"image/png":  true,
+	"image/gif":  true,
+	"image/webp": true,
+}
+
+// placeholderSize is the size the image is scaled to for BlurHash and dominant color.
+const placeholderSize = 32
+
+// Variant names, from the smallest one.
+const (
+	Thumb  = "thumb"
+	Medium = "medium"
+	Large  = "large"
+)
+
+// Image is the processed upload: its variants and a placeholder shown while they load.
+type Image struct {
+	Variants []Encoded
+	BlurHash string
+	Color    string // dominant color, "#rrggbb"
+}
+
+// Encoded is one resized copy of the image ready to be stored.
+type Encoded struct {
+	Name        string
+	Width       int
+	Height      int
+	Data        []byte
+	Ext         string // ".jpg" or ".png"
+	ContentType string
+	WebP        []byte // nil when WebP is disabled
+}
+
+type Processor struct {
+	cfg   config.Images
+	sizes []variantSize
+}
+
+type variantSize struct {
+	name string
+	max  int
+}
+
+func NewProcessor(cfg config.Images) *Processor {
+	return &Processor{
+		cfg: cfg,
+		sizes: []variantSize{
+			{Thumb, cfg.ThumbSize},
+			{Medium, cfg.MediumSize},
+			{Large, cfg.LargeSize},
+		},
+	}
+}
+
+// MaxFileSize is the upload size limit in bytes.
+func (p *Processor) MaxFileSize() int64 {
+	return p.cfg.MaxFileSize
+}
+
+// Process validates the uploaded image and returns all its variants.
+// Images are decoded and encoded again, so EXIF (GPS, camera, etc.) and any
+// other metadata is dropped, the EXIF orientation is applied to the pixels.
+func (p *Processor) Process(r io.Reader) (Image, error) {
+	var result Image
+
+	data, err := io.ReadAll(io.LimitReader(r, p.cfg.MaxFileSize+1))
+	if err != nil {
+		return result, err
+	}
+	if int64(len(data)) > p.cfg.MaxFileSize {
+		return result, ErrFileTooLarge
+	}
+
+	contentType := http.DetectContentType(data)
+	if !allowedTypes[contentType] {
+		return result, fmt.Errorf("%w: %s", ErrUnsupportedType, contentType)
+	}
+
+	// check dimensions before decoding, so huge images never get into memory
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return result, fmt.Errorf("%w: %s", ErrUnsupportedType, err.Error())
+	}
+	if cfg.Width > p.cfg.MaxWidth || cfg.Height > p.cfg.MaxHeight {
+		return result, fmt.Errorf("%w: %dx%d", ErrImageTooLarge, cfg.Width, cfg.Height)
+	}
+
+	var src image.Image
+	if contentType == "image/gif" {
+		// only the first frame of animations is kept
+		src, err = gif.Decode(bytes.NewReader(data))
+	} else {
+		src, _, err = image.Decode(bytes.NewReader(data))
+	}
+	if err != nil {
+		return result, fmt.Errorf("%w: %s", ErrUnsupportedType, err.Error())
+	}
+
+	if contentType == "image/jpeg" {
+		src = applyOrientation(src, jpegOrientation(data))
+	}
+
+	opaque := isOpaque(src)
+
+	result.Variants = make([]Encoded, 0, len(p.sizes))
+	for _, size := range p.sizes {
+		resized := resize(src, size.max)
+
+		encoded, err := p.encode(resized, opaque)
+		if err != nil {
+			return result, err
+		}
+		encoded.Name = size.name
+
+		result.Variants = append(result.Variants, encoded)
+	}
+
+	small := resize(src, placeholderSize)
+	result.BlurHash = blurHash(small)
+	result.Color = dominantColor(small)
+
+	return result, nil
+}
+
+func (p *Processor) encode(img image.Image, opaque bool) (Encoded, error) {
+	var buf bytes.Buffer
+	encoded := Encoded{
+		Width:  img.Bounds().Dx(),
+		Height: img.Bounds().Dy(),
+	}
+
+	if opaque {
+		if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: p.cfg.JPEGQuality}); err != nil {
+			return encoded, err
+		}
+		encoded.Ext, encoded.ContentType = ".jpg", "image/jpeg"
+	} else {
+		if err := png.Encode(&buf, img); err != nil {
+			return encoded, err
+		}
+		encoded.Ext, encoded.ContentType = ".png", "image/png"
+	}
+	encoded.Data = buf.Bytes()
+
+	if p.cfg.WebP {
+		var webpBuf bytes.Buffer
+		if err := nativewebp.Encode(&webpBuf, img, nil); err != nil {
+			return encoded, err
+		}
+		encoded.WebP = webpBuf.Bytes()
+	}
+
+	return encoded, nil
+}
+
+// resize scales the image down to fit into max x max, smaller images are not enlarged.
+func resize(src image.Image, max int) *image.NRGBA {
+	bounds := src.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+
+	if max <= 0 || (width <= max && height <= max) {
+		dst := image.NewNRGBA(image.Rect(0, 0, width, height))
+		draw.Draw(dst, dst.Bounds(), src, bounds.Min, draw.Src)
+		return dst
+	}
+
+	if width >= height {
+		height = max * height / width
+		width = max
+	} else {
+		width = max * width / height
+		height = max
+	}
+
+	dst := image.NewNRGBA(image.Rect(0, 0, max1(width), max1(height)))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), src, bounds, draw.Src, nil)
+	return dst
+}
+
+func isOpaque(img image.Image) bool {
+	if o, ok := img.(interface{ Opaque() bool }); ok {
+		return o.Opaque()
+	}
+	return true
+}
+
+func max1(v int) int {
+	if v < 1 {
+		return 1
+	}
+	return v
+}
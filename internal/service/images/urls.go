@@ -0,0 +1,101 @@
+package images
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/url"
+	"path"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+// URLPrefix is the route images are served from.
+const URLPrefix = "/images/"
+
+// hashedName matches "<hash>.<ext>" blob names and "<name>.<hash>.<ext>"
+// names of files uploaded before blobs were content-addressed.
+var hashedName = regexp.MustCompile(`(?:^|\.)([0-9a-f]{16}|[0-9a-f]{64})\.[a-z0-9]+$`)
+
+// Hash returns the sha256 of the content in hex.
+func Hash(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// BlobKey returns the content-addressed key of the file, equal files get
+// equal keys and are stored once.
+func BlobKey(hash, ext string) string {
+	return path.Join("blobs", hash[:2], hash+ext)
+}
+
+// HashFromKey returns the content hash from the file name, if it has one.
+// Files with hashed names never change and can be cached forever.
+func HashFromKey(key string) (string, bool) {
+	m := hashedName.FindStringSubmatch(path.Base(key))
+	if m == nil {
+		return "", false
+	}
+	return m[1], true
+}
+
+// URL returns the public url of the image.
+func URL(key string) string {
+	return URLPrefix + key
+}
+
+// Signer makes expiring urls for images of private (draft or hidden) recipes.
+type Signer struct {
+	secret []byte
+	ttl    time.Duration
+}
+
+// NewSigner returns a signer, an empty secret disables signed urls.
+func NewSigner(secret string, ttl time.Duration) *Signer {
+	return &Signer{secret: []byte(secret), ttl: ttl}
+}
+
+func (s *Signer) Enabled() bool {
+	return len(s.secret) > 0
+}
+
+// SignURL returns the image url valid for the configured ttl.
+func (s *Signer) SignURL(key string) string {
+	expires := strconv.FormatInt(time.Now().Add(s.ttl).Unix(), 10)
+
+	query := url.Values{}
+	query.Set("expires", expires)
+	query.Set("sig", s.sign(key, expires))
+
+	return URL(key) + "?" + query.Encode()
+}
+
+// Verify checks the signature of the url and returns its expiration time.
+func (s *Signer) Verify(key, expires, sig string) (time.Time, bool) {
+	if !s.Enabled() || expires == "" || sig == "" {
+		return time.Time{}, false
+	}
+
+	unix, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	expiresAt := time.Unix(unix, 0)
+	if time.Now().After(expiresAt) {
+		return time.Time{}, false
+	}
+
+	if !hmac.Equal([]byte(sig), []byte(s.sign(key, expires))) {
+		return time.Time{}, false
+	}
+
+	return expiresAt, true
+}
+
+func (s *Signer) sign(key, expires string) string {
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write([]byte(key + "\n" + expires))
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,179 @@
+package ingredients
+
+import (
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/qwaq-dev/culina/internal/repository"
+	"github.com/qwaq-dev/culina/internal/service/textfilter"
+	"github.com/qwaq-dev/culina/pkg/logger/sl"
+)
+
+// backfillBatchSize is the number of recipes normalized in one transaction.
+const backfillBatchSize = 500
+
+// stopWords are units and amounts written next to ingredients, like
+// "200 г" or "по вкусу", they are not part of the name.
+var stopWords = map[string]bool{
+	"г": true, "гр": true, "грамм": true, "граммов": true, "кг": true, "мл": true, "л": true,
+	"шт": true, "штук": true, "штуки": true, "ст": true, "ч": true, "л.": true,
+	"ложка": true, "ложки": true, "ложек": true, "стакан": true, "стакана": true,
+	"щепотка": true, "пучок": true, "зубчик": true, "зубчика": true, "зубчиков": true,
+	"по": true, "вкусу": true,
+	"g": true, "kg": true, "ml": true, "l": true, "pcs": true, "tbsp": true, "tsp": true,
+	"cup": true, "cups": true, "pinch": true, "oz": true, "lb": true, "to": true, "taste": true,
+}
+
+// stopAdjectives describe the state of the ingredient, "свежие шампиньоны"
+// are found by "грибы" like any other.
+var stopAdjectives = map[string]bool{
+	"свежий": true, "свежая": true, "свежее": true, "свежие": true, "свежих": true, "свежего": true, "свежей": true,
+	"охлажденный": true, "охлажденная": true, "охлажденное": true, "охлажденные": true,
+	"замороженный": true, "замороженная": true, "замороженное": true, "замороженные": true,
+	"консервированный": true, "консервированная": true, "консервированные": true,
+	"крупный": true, "крупная": true, "крупное": true, "крупные": true,
+	"мелкий": true, "мелкая": true, "мелкое": true, "мелкие": true,
+	"средний": true, "средняя": true, "среднее": true, "средние": true,
+	"молотый": true, "молотая": true, "молотое": true, "молотые": true,
+	"нарезанный": true, "нарезанная": true, "нарезанное": true, "нарезанные": true,
+	"очищенный": true, "очищенная": true, "очищенное": true, "очищенные": true,
+	"отварной": true, "отварная": true, "отварное": true, "отварные": true,
+	"домашний": true, "домашняя": true, "домашнее": true, "домашние": true,
+	"fresh": true, "frozen": true, "canned": true, "chilled": true, "chopped": true,
+	"sliced": true, "diced": true, "peeled": true, "boiled": true, "large": true,
+	"medium": true, "small": true,
+}
+
+// Normalizer turns free text ingredients, like "Куриное филе 500 г", into
+// names recipes are indexed and searched by, like "курица".
+type Normalizer struct {
+	synonyms map[string]string // cleaned name -> first name of its group
+}
+
+// New loads synonym groups, one per line separated by commas.
+func New(synonymsFile string) (*Normalizer, error) {
+	lines, err := textfilter.LoadWords(synonymsFile)
+	if err != nil {
+		return nil, fmt.Errorf("load ingredient synonyms: %w", err)
+	}
+
+	n := &Normalizer{synonyms: make(map[string]string)}
+	for _, line := range lines {
+		var canonical string
+		for _, name := range strings.Split(line, ",") {
+			name = clean(name)
+			if name == "" {
+				continue
+			}
+			if canonical == "" {
+				canonical = name
+			}
+			n.synonyms[name] = canonical
+		}
+	}
+
+	return n, nil
+}
+
+// Normalize returns the name of the ingredient, or "" if nothing is left of it.
+// The longest known name in the text wins, so "филе куриное домашнее" and
+// "болгарский перец красный" are found by "курица" and "болгарский перец".
+func (n *Normalizer) Normalize(ingredient string) string {
+	name := clean(ingredient)
+	if canonical, ok := n.synonyms[name]; ok {
+		return canonical
+	}
+
+	words := strings.Fields(name)
+	for size := len(words) - 1; size > 0; size-- {
+		for start := 0; start+size <= len(words); start++ {
+			if canonical, ok := n.synonyms[strings.Join(words[start:start+size], " ")]; ok {
+				return canonical
+			}
+		}
+	}
+
+	return name
+}
+
+// Names returns sorted unique names of the recipe ingredients.
+func (n *Normalizer) Names(ingredients map[string]string) []string {
+	names := []string{}
+	for _, ingredient := range ingredients {
+		if name := n.Normalize(ingredient); name != "" && !slices.Contains(names, name) {
+			names = append(names, name)
+		}
+	}
+
+	slices.Sort(names)
+	return names
+}
+
+// NormalizeAll normalizes searched names, dropping the empty ones.
+func (n *Normalizer) NormalizeAll(ingredients []string) []string {
+	var names []string
+	for _, ingredient := range ingredients {
+		if name := n.Normalize(ingredient); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// clean lower cases the text and drops numbers, punctuation, units and
+// adjectives of the ingredient state.
+func clean(text string) string {
+	text = strings.ReplaceAll(strings.ToLower(text), "ё", "е")
+	words := strings.FieldsFunc(text, func(r rune) bool {
+		return !unicode.IsLetter(r) && r != '-'
+	})
+
+	kept := words[:0]
+	for _, word := range words {
+		word = strings.Trim(word, "-")
+		if word != "" && !stopWords[word] && !stopAdjectives[word] {
+			kept = append(kept, word)
+		}
+	}
+
+	return strings.Join(kept, " ")
+}
+
+// Backfill normalizes recipes saved before ingredient names were
+// introduced, in the background. Recipes are queued for the search index.
+func (n *Normalizer) Backfill(repo repository.IngredientRepository, log *slog.Logger) {
+	go func() {
+		total := 0
+		for {
+			ingredients, err := repo.SelectUnnormalizedIngredients(backfillBatchSize, log)
+			if err != nil {
+				log.Error("Error with normalizing ingredients, retrying later", sl.Err(err))
+				time.Sleep(time.Minute)
+				continue
+			}
+			if len(ingredients) == 0 {
+				break
+			}
+
+			names := make(map[int][]string, len(ingredients))
+			for id, recipeIngredients := range ingredients {
+				names[id] = n.Names(recipeIngredients)
+			}
+
+			if err := repo.UpdateIngredientNames(names, log); err != nil {
+				log.Error("Error with normalizing ingredients, retrying later", sl.Err(err))
+				time.Sleep(time.Minute)
+				continue
+			}
+			total += len(names)
+		}
+
+		if total > 0 {
+			log.Info("Ingredients of recipes were normalized", slog.Int("recipes", total))
+		}
+	}()
+}
@@ -0,0 +1,281 @@
+package service
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/qwaq-dev/culina/internal/repository"
+	"github.com/qwaq-dev/culina/pkg/config"
+	"github.com/qwaq-dev/culina/pkg/logger/sl"
+	"github.com/qwaq-dev/culina/structures"
+)
+
+// ChunksPrefix is where chunks of unfinished uploads are stored.
+const ChunksPrefix = "uploads/"
+
+// Videos accepted for recipes, detected by magic bytes.
+var videoTypes = map[string]string{
+	"video/mp4":  ".mp4",
+	"video/webm": ".webm",
+}
+
+// ResumableUploads receives large files by chunks. Every chunk is stored
+// separately, so an interrupted upload continues from the last received
+// chunk. The finished file is stored like other uploads and attached to a recipe.
+type ResumableUploads struct {
+	repo     repository.UploadRepository
+	store    repository.BlobStore
+	uploader *Uploader
+	cfg      config.Uploads
+	log      *slog.Logger
+}
+
+func NewResumableUploads(repo repository.UploadRepository, store repository.BlobStore, uploader *Uploader, cfg config.Uploads, log *slog.Logger) *ResumableUploads {
+	return &ResumableUploads{repo: repo, store: store, uploader: uploader, cfg: cfg, log: log}
+}
+
+// Create starts an upload of size bytes.
+func (u *ResumableUploads) Create(authorId int, filename string, size int64) (structures.UploadSession, error) {
+	if size <= 0 || size > u.cfg.MaxSize {
+		return structures.UploadSession{}, fmt.Errorf("%w: max %d bytes", ErrUploadTooLarge, u.cfg.MaxSize)
+	}
+
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return structures.UploadSession{}, err
+	}
+
+	return u.repo.InsertUpload(structures.UploadSession{
+		Id:        hex.EncodeToString(id),
+		Author_id: authorId,
+		Filename:  filename,
+		Size:      size,
+	}, u.cfg.SessionTTL, u.log)
+}
+
+func (u *ResumableUploads) Session(id string) (structures.UploadSession, error) {
+	return u.repo.SelectUpload(id, u.log)
+}
+
+// WriteChunk appends data at offset. checksum is optional, in the
+// "sha256 <base64>" form of the tus checksum extension.
+func (u *ResumableUploads) WriteChunk(id string, offset int64, data []byte, checksum string) (structures.UploadSession, error) {
+	session, err := u.repo.SelectUpload(id, u.log)
+	if err != nil {
+		return session, err
+	}
+
+	if offset != session.Offset {
+		return session, repository.ErrOffsetMismatch
+	}
+	if int64(len(data)) > u.cfg.MaxChunkSize {
+		return session, fmt.Errorf("%w: max %d bytes", ErrChunkTooLarge, u.cfg.MaxChunkSize)
+	}
+	if offset+int64(len(data)) > session.Size {
+		return session, ErrUploadTooLarge
+	}
+	if len(data) == 0 {
+		return session, nil
+	}
+
+	sum := sha256.Sum256(data)
+	if checksum != "" {
+		expected, err := parseChecksum(checksum)
+		if err != nil {
+			return session, err
+		}
+		if !bytes.Equal(expected, sum[:]) {
+			return session, ErrChecksumMismatch
+		}
+	}
+
+	// the hash of the whole file is counted chunk by chunk,
+	// so the finished file doesn't have to be read again
+	hash := sha256.New()
+	if len(session.Hash_state) > 0 {
+		if err := hash.(encoding.BinaryUnmarshaler).UnmarshalBinary(session.Hash_state); err != nil {
+			return session, err
+		}
+	}
+	hash.Write(data)
+
+	next := session
+	next.Offset = offset + int64(len(data))
+	if next.Hash_state, err = hash.(encoding.BinaryMarshaler).MarshalBinary(); err != nil {
+		return session, err
+	}
+	if next.Complete() {
+		next.Checksum = hex.EncodeToString(hash.Sum(nil))
+	}
+
+	chunk := structures.UploadChunk{
+		Offset: offset,
+		Size:   int64(len(data)),
+		Key:    fmt.Sprintf("%s%s/%d-%s", ChunksPrefix, id, offset, hex.EncodeToString(sum[:8])),
+	}
+
+	if err := u.store.Put(context.Background(), chunk.Key, bytes.NewReader(data), chunk.Size, "application/octet-stream"); err != nil {
+		u.log.Error("Error with storing upload chunk", slog.String("key", chunk.Key), sl.Err(err))
+		return session, err
+	}
+
+	return u.repo.InsertChunk(next, chunk, u.cfg.SessionTTL, u.log)
+}
+
+// Finish stores the received file as a recipe image or video.
+func (u *ResumableUploads) Finish(ctx context.Context, id string, authorId int) (structures.RecipeMedia, error) {
+	var media structures.RecipeMedia
+
+	session, err := u.repo.SelectUpload(id, u.log)
+	if err != nil {
+		return media, err
+	}
+	if session.Author_id != authorId {
+		return media, ErrNotUploadOwner
+	}
+	if !session.Complete() {
+		return media, ErrUploadIncomplete
+	}
+
+	chunks, err := u.repo.SelectChunks(id, u.log)
+	if err != nil {
+		return media, err
+	}
+
+	// chunks of a large video are read longer than a request may take
+	ctx, cancel := u.uploader.storeContext(ctx)
+	defer cancel()
+
+	file := &chunkReader{ctx: ctx, store: u.store, chunks: chunks}
+	defer file.Close()
+
+	r := bufio.NewReader(file)
+	head, err := r.Peek(512)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return media, err
+	}
+	contentType := http.DetectContentType(head)
+
+	if strings.HasPrefix(contentType, "image/") {
+		return u.uploader.SaveImage(ctx, r)
+	}
+
+	ext, ok := videoTypes[contentType]
+	if !ok {
+		return media, fmt.Errorf("%w: %s", ErrUnsupportedMedia, contentType)
+	}
+
+	return u.uploader.SaveVideo(ctx, r, session.Size, session.Checksum, ext, contentType)
+}
+
+// Remove deletes the upload with its chunks.
+func (u *ResumableUploads) Remove(id string) error {
+	ctx := context.Background()
+
+	chunks, err := u.store.List(ctx, ChunksPrefix+id+"/")
+	if err != nil {
+		return err
+	}
+	for _, chunk := range chunks {
+		if err := u.store.Delete(ctx, chunk.Key); err != nil && !errors.Is(err, repository.ErrBlobNotFound) {
+			u.log.Error("Error with removing upload chunk", slog.String("key", chunk.Key), sl.Err(err))
+		}
+	}
+
+	return u.repo.DeleteUpload(id, u.log)
+}
+
+// StartCleanup removes expired uploads every interval in the background.
+func (u *ResumableUploads) StartCleanup(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			ids, err := u.repo.SelectExpiredUploads(u.log)
+			if err != nil {
+				continue
+			}
+
+			for _, id := range ids {
+				if err := u.Remove(id); err != nil {
+					u.log.Error("Error with removing expired upload", slog.String("id", id), sl.Err(err))
+				}
+			}
+
+			if len(ids) > 0 {
+				u.log.Info("Expired uploads were removed", slog.Int("count", len(ids)))
+			}
+		}
+	}()
+}
+
+func parseChecksum(header string) ([]byte, error) {
+	algorithm, value, ok := strings.Cut(strings.TrimSpace(header), " ")
+	if !ok || algorithm != "sha256" {
+		return nil, ErrInvalidChecksum
+	}
+
+	sum, err := base64.StdEncoding.DecodeString(value)
+	if err != nil || len(sum) != sha256.Size {
+		return nil, ErrInvalidChecksum
+	}
+
+	return sum, nil
+}
+
+// chunkReader reads stored chunks one after another.
+type chunkReader struct {
+	ctx     context.Context
+	store   repository.BlobStore
+	chunks  []structures.UploadChunk
+	current io.ReadCloser
+}
+
+func (r *chunkReader) Read(p []byte) (int, error) {
+	for {
+		if r.current == nil {
+			if len(r.chunks) == 0 {
+				return 0, io.EOF
+			}
+
+			rc, _, err := r.store.Get(r.ctx, r.chunks[0].Key)
+			if err != nil {
+				return 0, err
+			}
+			r.current = rc
+			r.chunks = r.chunks[1:]
+		}
+
+		n, err := r.current.Read(p)
+		if errors.Is(err, io.EOF) {
+			r.current.Close()
+			r.current = nil
+			if n > 0 {
+				return n, nil
+			}
+			continue
+		}
+		return n, err
+	}
+}
+
+func (r *chunkReader) Close() error {
+	if r.current != nil {
+		return r.current.Close()
+	}
+	return nil
+}
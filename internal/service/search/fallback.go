@@ -0,0 +1,130 @@
+package search
+
+import (
+	"context"
+	"log/slog"
+	"sync/atomic"
+	"time"
+
+	"github.com/qwaq-dev/culina/internal/repository"
+	"github.com/qwaq-dev/culina/pkg/logger/sl"
+	"github.com/qwaq-dev/culina/structures"
+)
+
+// Fallback sends searches to the primary index while it is healthy and to the
+// fallback one otherwise. Changes made while the primary index is down are
+// lost there, so it is reindexed as soon as it is healthy again.
+type Fallback struct {
+	primary  repository.SearchIndex
+	fallback repository.SearchIndex
+	healthy  atomic.Bool
+	started  bool // primary was initialized, only used by check
+	log      *slog.Logger
+}
+
+func NewFallback(primary, fallback repository.SearchIndex, log *slog.Logger) *Fallback {
+	return &Fallback{primary: primary, fallback: fallback, log: log}
+}
+
+// Start checks the primary index now and then every interval in the background.
+func (f *Fallback) Start(interval time.Duration) {
+	f.check()
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			f.check()
+		}
+	}()
+}
+
+func (f *Fallback) check() {
+	healthy := f.Healthy(context.Background())
+
+	switch {
+	case healthy && !f.healthy.Load():
+		// at startup the index is only initialized, after an outage
+		// it misses the changes made meanwhile
+		prepare := f.primary.Reindex
+		if !f.started {
+			prepare = f.primary.Init
+		}
+		if err := prepare(); err != nil {
+			f.log.Error("Error with preparing search index, using fallback", sl.Err(err))
+			return
+		}
+		f.started = true
+		f.healthy.Store(true)
+		f.log.Info("Search index is healthy")
+	case !healthy && f.healthy.Swap(false):
+		f.log.Warn("Search index is unhealthy, using fallback")
+	}
+}
+
+func (f *Fallback) Healthy(ctx context.Context) bool {
+	return f.primary.Healthy(ctx)
+}
+
+func (f *Fallback) current() repository.SearchIndex {
+	if f.healthy.Load() {
+		return f.primary
+	}
+	return f.fallback
+}
+
+func (f *Fallback) IndexRecipe(recipe structures.Recipes) error {
+	return f.write(func(index repository.SearchIndex) error { return index.IndexRecipe(recipe) })
+}
+
+func (f *Fallback) IndexRecipeById(id int) error {
+	return f.write(func(index repository.SearchIndex) error { return index.IndexRecipeById(id) })
+}
+
+func (f *Fallback) DeleteRecipe(id int) error {
+	return f.write(func(index repository.SearchIndex) error { return index.DeleteRecipe(id) })
+}
+
+func (f *Fallback) Init() error {
+	return f.write(func(index repository.SearchIndex) error { return index.Init() })
+}
+
+func (f *Fallback) Reindex() error {
+	return f.write(func(index repository.SearchIndex) error { return index.Reindex() })
+}
+
+// SyncRules skips the primary index while it is down, the reindex after
+// the outage pushes the rules.
+func (f *Fallback) SyncRules() error {
+	return f.write(func(index repository.SearchIndex) error { return index.SyncRules() })
+}
+
+// write applies the change to both indexes. If the primary index fails
+// because it went down, the next successful check reindexes it.
+func (f *Fallback) write(apply func(index repository.SearchIndex) error) error {
+	if err := apply(f.fallback); err != nil {
+		return err
+	}
+
+	if !f.healthy.Load() {
+		return nil
+	}
+
+	err := apply(f.primary)
+	if err != nil && !f.primary.Healthy(context.Background()) && f.healthy.Swap(false) {
+		f.log.Warn("Search index write failed, using fallback", sl.Err(err))
+	}
+	return err
+}
+
+func (f *Fallback) Search(query structures.SearchQuery) (structures.SearchResult, error) {
+	index := f.current()
+
+	result, err := index.Search(query)
+	if err != nil && index == f.primary {
+		f.log.Warn("Search failed, using fallback", sl.Err(err))
+		return f.fallback.Search(query)
+	}
+	return result, err
+}
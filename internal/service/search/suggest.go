@@ -0,0 +1,77 @@
+package search
+
+import (
+	"log/slog"
+	"time"
+
+	"github.com/qwaq-dev/culina/internal/repository"
+	"github.com/qwaq-dev/culina/pkg/config"
+	"github.com/qwaq-dev/culina/pkg/logger/sl"
+	"github.com/qwaq-dev/culina/structures"
+)
+
+// Suggestions completes search prefixes from recipe names, ingredients, tags
+// and popular queries of the search log. The terms are loaded from the database every
+// SuggestInterval into the primary suggester, if any, and the in-memory trie,
+// which answers while the primary one fails.
+type Suggestions struct {
+	repo    repository.SuggestRepository
+	primary repository.Suggester
+	trie    *Trie
+	cfg     config.Search
+	log     *slog.Logger
+}
+
+// NewSuggestions creates the suggestions, primary may be nil to use only the trie.
+func NewSuggestions(repo repository.SuggestRepository, primary repository.Suggester, cfg config.Search, log *slog.Logger) *Suggestions {
+	return &Suggestions{
+		repo:    repo,
+		primary: primary,
+		trie:    NewTrie(),
+		cfg:     cfg,
+		log:     log,
+	}
+}
+
+// Start builds the suggestions now, then rebuilds them in the background.
+func (s *Suggestions) Start() {
+	s.rebuild()
+
+	go func() {
+		ticker := time.NewTicker(s.cfg.SuggestInterval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			s.rebuild()
+		}
+	}()
+}
+
+func (s *Suggestions) rebuild() {
+	terms, err := s.repo.SelectSuggestionTerms(s.cfg.SuggestMinQueryCount, s.log)
+	if err != nil {
+		s.log.Error("Error with loading suggestion terms", sl.Err(err))
+		return
+	}
+
+	s.trie.RebuildSuggestions(terms)
+	if s.primary != nil {
+		if err := s.primary.RebuildSuggestions(terms); err != nil {
+			s.log.Error("Error with rebuilding suggestions", sl.Err(err))
+		}
+	}
+
+	s.log.Info("Suggestions were rebuilt", slog.Int("terms", len(terms)))
+}
+
+func (s *Suggestions) Suggest(prefix string, limit int) ([]structures.Suggestion, error) {
+	if s.primary != nil {
+		suggestions, err := s.primary.Suggest(prefix, limit)
+		if err == nil {
+			return suggestions, nil
+		}
+		s.log.Warn("Suggest failed, using trie", sl.Err(err))
+	}
+
+	return s.trie.Suggest(prefix, limit)
+}
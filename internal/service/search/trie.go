@@ -0,0 +1,131 @@
+package search
+
+import (
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/qwaq-dev/culina/structures"
+)
+
+// MaxSuggestions is the most suggestions returned for one prefix.
+const MaxSuggestions = 10
+
+// Trie keeps suggestions in memory when there is no Typesense. Every node
+// keeps its best MaxSuggestions terms, so a lookup only walks the prefix.
+type Trie struct {
+	mu      sync.RWMutex
+	root    *trieNode
+	popular []structures.Suggestion // best queries, suggested for an empty prefix
+}
+
+type trieNode struct {
+	children map[rune]*trieNode
+	top      []*structures.Suggestion
+}
+
+func NewTrie() *Trie {
+	return &Trie{root: newTrieNode()}
+}
+
+func newTrieNode() *trieNode {
+	return &trieNode{children: make(map[rune]*trieNode)}
+}
+
+// RebuildSuggestions builds a new trie and swaps it in. A term can be found
+// by the start of any of its words, like "soup" for "tomato soup".
+func (t *Trie) RebuildSuggestions(terms []structures.Suggestion) error {
+	root := newTrieNode()
+	var popular []structures.Suggestion
+
+	for i := range terms {
+		term := &terms[i]
+		text := NormalizeQuery(term.Text)
+		if text == "" {
+			continue
+		}
+
+		words := strings.Fields(text)
+		for w := range words {
+			root.insert(strings.Join(words[w:], " "), term)
+		}
+
+		if term.Kind == structures.SuggestQuery {
+			popular = append(popular, *term)
+		}
+	}
+
+	slices.SortStableFunc(popular, func(a, b structures.Suggestion) int { return b.Weight - a.Weight })
+	if len(popular) > MaxSuggestions {
+		popular = popular[:MaxSuggestions]
+	}
+
+	t.mu.Lock()
+	t.root, t.popular = root, popular
+	t.mu.Unlock()
+	return nil
+}
+
+func (n *trieNode) insert(key string, term *structures.Suggestion) {
+	node := n
+	for _, r := range key {
+		child, ok := node.children[r]
+		if !ok {
+			child = newTrieNode()
+			node.children[r] = child
+		}
+		node = child
+		node.keep(term)
+	}
+}
+
+// keep adds the term to the best terms of the node, if it is good enough.
+func (n *trieNode) keep(term *structures.Suggestion) {
+	if slices.Contains(n.top, term) {
+		return
+	}
+
+	// after the terms with the same weight, the first one inserted wins
+	i := len(n.top)
+	for i > 0 && n.top[i-1].Weight < term.Weight {
+		i--
+	}
+	if i >= MaxSuggestions {
+		return
+	}
+	n.top = slices.Insert(n.top, i, term)
+	if len(n.top) > MaxSuggestions {
+		n.top = n.top[:MaxSuggestions]
+	}
+}
+
+func (t *Trie) Suggest(prefix string, limit int) ([]structures.Suggestion, error) {
+	prefix = NormalizeQuery(prefix)
+	limit = min(limit, MaxSuggestions)
+
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	suggestions := []structures.Suggestion{}
+	if prefix == "" {
+		return append(suggestions, t.popular[:min(limit, len(t.popular))]...), nil
+	}
+
+	node := t.root
+	for _, r := range prefix {
+		if node = node.children[r]; node == nil {
+			return suggestions, nil
+		}
+	}
+
+	for _, term := range node.top[:min(limit, len(node.top))] {
+		suggestions = append(suggestions, *term)
+	}
+	return suggestions, nil
+}
+
+// NormalizeQuery lower cases the query and collapses spaces, so the same
+// query typed differently is counted and suggested once.
+func NormalizeQuery(query string) string {
+	return strings.Join(strings.Fields(strings.ToLower(query)), " ")
+}
@@ -0,0 +1,141 @@
+package searchsync
+
+import (
+	"context"
+	"log/slog"
+	"math"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/qwaq-dev/culina/internal/repository"
+	"github.com/qwaq-dev/culina/pkg/logger/sl"
+	"github.com/qwaq-dev/culina/structures"
+)
+
+// reconcileBatchSize is the number of recipes read from the database at once.
+const reconcileBatchSize = 500
+
+// Exporter is an index which can list its documents.
+type Exporter interface {
+	Export() ([]structures.TypesenseRecipe, error)
+	Healthy(ctx context.Context) bool
+}
+
+// Drift lists recipes whose documents differ from the database.
+type Drift struct {
+	Missing []int `json:"missing"` // published recipes without a document
+	Stale   []int `json:"stale"`   // documents with outdated fields
+	Extra   []int `json:"extra"`   // documents of deleted, hidden or draft recipes
+}
+
+func (d Drift) Recipes() []int {
+	return slices.Concat(d.Missing, d.Stale, d.Extra)
+}
+
+// Reconciler compares the index with the database and queues every recipe
+// that differs, the relay then fixes it like any other change.
+type Reconciler struct {
+	recipes repository.DashboardRepository
+	outbox  repository.OutboxRepository
+	index   Exporter
+	log     *slog.Logger
+}
+
+func NewReconciler(recipes repository.DashboardRepository, outbox repository.OutboxRepository, index Exporter, log *slog.Logger) *Reconciler {
+	return &Reconciler{recipes: recipes, outbox: outbox, index: index, log: log}
+}
+
+// Start reconciles every interval in the background, while the index is healthy.
+func (r *Reconciler) Start(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			if !r.index.Healthy(context.Background()) {
+				continue
+			}
+
+			drift, err := r.Run()
+			if err != nil {
+				r.log.Error("Error with reconciling search index", sl.Err(err))
+				continue
+			}
+
+			if n := len(drift.Recipes()); n > 0 {
+				r.log.Warn("Search index drift was found", slog.Int("missing", len(drift.Missing)),
+					slog.Int("stale", len(drift.Stale)), slog.Int("extra", len(drift.Extra)))
+			}
+		}
+	}()
+}
+
+// Run finds the drift and queues the drifted recipes.
+func (r *Reconciler) Run() (Drift, error) {
+	var drift Drift
+
+	exported, err := r.index.Export()
+	if err != nil {
+		return drift, err
+	}
+
+	docs := make(map[string]structures.TypesenseRecipe, len(exported))
+	for _, doc := range exported {
+		docs[doc.Id] = doc
+	}
+
+	afterId := 0
+	for {
+		recipes, err := r.recipes.SelectRecipesForIndex(context.Background(), afterId, reconcileBatchSize, r.log)
+		if err != nil {
+			return drift, err
+		}
+		if len(recipes) == 0 {
+			break
+		}
+		afterId = recipes[len(recipes)-1].Id
+
+		for _, recipe := range recipes {
+			expected, err := recipe.ToTypesense()
+			if err != nil {
+				continue
+			}
+
+			actual, ok := docs[expected.Id]
+			delete(docs, expected.Id)
+
+			switch {
+			case !ok:
+				drift.Missing = append(drift.Missing, recipe.Id)
+			case !sameDocument(*expected, actual):
+				drift.Stale = append(drift.Stale, recipe.Id)
+			}
+		}
+	}
+
+	for id := range docs {
+		if recipeId, err := strconv.Atoi(id); err == nil {
+			drift.Extra = append(drift.Extra, recipeId)
+		}
+	}
+	slices.Sort(drift.Extra)
+
+	if err := r.outbox.InsertSearchEvents(drift.Recipes(), r.log); err != nil {
+		return drift, err
+	}
+
+	return drift, nil
+}
+
+// sameDocument compares the documents, the rating only up to the float
+// precision Typesense keeps.
+func sameDocument(a, b structures.TypesenseRecipe) bool {
+	return a.Id == b.Id && a.Name == b.Name && a.Descr == b.Descr && a.Diff == b.Diff &&
+		a.Cook_time == b.Cook_time && a.Calories == b.Calories &&
+		slices.Equal(a.Filters, b.Filters) && a.Imgs == b.Imgs && a.Placeholders == b.Placeholders &&
+		a.AuthorID == b.AuthorID && a.Ingredients == b.Ingredients &&
+		slices.Equal(a.Ingredient_names, b.Ingredient_names) && a.Steps == b.Steps &&
+		a.Lang == b.Lang && a.Translations == b.Translations &&
+		a.Review_count == b.Review_count && math.Abs(float64(a.Avg_rating-b.Avg_rating)) < 1e-3
+}
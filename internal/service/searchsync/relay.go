@@ -0,0 +1,99 @@
+package searchsync
+
+import (
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/qwaq-dev/culina/internal/repository"
+	"github.com/qwaq-dev/culina/pkg/config"
+	"github.com/qwaq-dev/culina/pkg/logger/sl"
+)
+
+// Relay applies outbox events to the search index. An event only names the
+// recipe, the relay upserts its current state or deletes the document if the
+// recipe is gone, hidden or a draft. So applying an event twice or out of
+// order leaves the index the same.
+type Relay struct {
+	outbox repository.OutboxRepository
+	index  repository.SearchIndex
+	cfg    config.Search
+	log    *slog.Logger
+}
+
+func NewRelay(outbox repository.OutboxRepository, index repository.SearchIndex, cfg config.Search, log *slog.Logger) *Relay {
+	return &Relay{outbox: outbox, index: index, cfg: cfg, log: log}
+}
+
+// Start processes due events every relay interval in the background.
+func (r *Relay) Start() {
+	go func() {
+		ticker := time.NewTicker(r.cfg.RelayInterval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			// a full batch means more events may be waiting
+			for {
+				n, err := r.RunOnce()
+				if err != nil || n < r.cfg.RelayBatchSize {
+					break
+				}
+			}
+		}
+	}()
+}
+
+// RunOnce applies one batch of events and returns how many were claimed.
+func (r *Relay) RunOnce() (int, error) {
+	events, err := r.outbox.ClaimSearchEvents(r.cfg.RelayBatchSize, r.cfg.RelayLease, r.log)
+	if err != nil {
+		return 0, err
+	}
+
+	// several events of one recipe are applied once
+	var order []int
+	byRecipe := make(map[int][]int64)
+	attempts := make(map[int]int)
+	for _, event := range events {
+		if _, ok := byRecipe[event.Recipe_id]; !ok {
+			order = append(order, event.Recipe_id)
+		}
+		byRecipe[event.Recipe_id] = append(byRecipe[event.Recipe_id], event.Id)
+		attempts[event.Recipe_id] = max(attempts[event.Recipe_id], event.Attempts)
+	}
+
+	for _, recipeId := range order {
+		ids := byRecipe[recipeId]
+
+		if err := r.apply(recipeId); err != nil {
+			delay := retryDelay(attempts[recipeId], r.cfg.MaxRetryDelay)
+			r.log.Warn("Error with syncing recipe to search, retrying", slog.Int("recipe_id", recipeId),
+				slog.Int("attempts", attempts[recipeId]), slog.Duration("delay", delay), sl.Err(err))
+
+			for _, id := range ids {
+				r.outbox.RetrySearchEvent(id, delay, err.Error(), r.log)
+			}
+			continue
+		}
+
+		r.outbox.DeleteSearchEvents(ids, r.log)
+	}
+
+	return len(events), nil
+}
+
+func (r *Relay) apply(recipeId int) error {
+	err := r.index.IndexRecipeById(recipeId)
+	if errors.Is(err, repository.ErrRecipeNotFound) {
+		return r.index.DeleteRecipe(recipeId)
+	}
+	return err
+}
+
+// retryDelay doubles with every attempt, starting from a second.
+func retryDelay(attempts int, limit time.Duration) time.Duration {
+	if attempts > 30 {
+		return limit
+	}
+	return min(time.Second<<attempts, limit)
+}
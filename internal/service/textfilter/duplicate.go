@@ -0,0 +1,108 @@
+package textfilter
+
+import (
+	"crypto/sha256"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DuplicateFilter remembers texts posted during the window. The same text
+// from the same author is rejected, the same text from several authors is held.
+// Texts are remembered by Record once they are saved, so a failed save can be
+// retried.
+type DuplicateFilter struct {
+	window     time.Duration
+	maxAuthors int
+
+	mu    sync.Mutex
+	seen  map[[sha256.Size]byte]map[int]time.Time
+	clean time.Time
+}
+
+func NewDuplicateFilter(window time.Duration, maxAuthors int) *DuplicateFilter {
+	return &DuplicateFilter{
+		window:     window,
+		maxAuthors: maxAuthors,
+		seen:       make(map[[sha256.Size]byte]map[int]time.Time),
+	}
+}
+
+func (f *DuplicateFilter) Name() string {
+	return "duplicate"
+}
+
+func (f *DuplicateFilter) Check(text Text) Verdict {
+	key, ok := fingerprint(text)
+	if !ok {
+		return Verdict{}
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.removeExpired(time.Now())
+
+	authors := f.seen[key]
+	_, posted := authors[text.AuthorId]
+	count := len(authors)
+	if !posted {
+		count++
+	}
+
+	switch {
+	// an edit keeps the author's own text, e.g. when only the rating changes
+	case posted && !text.Edit:
+		return Verdict{Action: Reject, Reasons: []string{"same text was already posted by the author"}}
+	case f.maxAuthors > 0 && count >= f.maxAuthors:
+		return Verdict{Action: Hold, Reasons: []string{"same text was posted by several authors"}}
+	}
+
+	return Verdict{}
+}
+
+// Record remembers the saved text.
+func (f *DuplicateFilter) Record(text Text) {
+	key, ok := fingerprint(text)
+	if !ok {
+		return
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	authors, ok := f.seen[key]
+	if !ok {
+		authors = make(map[int]time.Time)
+		f.seen[key] = authors
+	}
+	authors[text.AuthorId] = time.Now()
+}
+
+func fingerprint(text Text) ([sha256.Size]byte, bool) {
+	words := Words(text.Body)
+	// short texts like "very tasty!" are expected to repeat
+	if len(words) < 4 {
+		return [sha256.Size]byte{}, false
+	}
+
+	return sha256.Sum256([]byte(text.Kind + ":" + strings.Join(words, " "))), true
+}
+
+func (f *DuplicateFilter) removeExpired(now time.Time) {
+	if now.Sub(f.clean) < time.Minute {
+		return
+	}
+	f.clean = now
+
+	for key, authors := range f.seen {
+		for author, posted := range authors {
+			if now.Sub(posted) > f.window {
+				delete(authors, author)
+			}
+		}
+		if len(authors) == 0 {
+			delete(f.seen, key)
+		}
+	}
+}
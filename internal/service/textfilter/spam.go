@@ -0,0 +1,93 @@
+package textfilter
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+var (
+	linkRegexp = regexp.MustCompile(`(?i)(https?://|www\.)\S+|\b[a-z0-9-]+\.(com|ru|net|org|io|info|biz|xyz|top|online|site|shop|me|su|рф)\b`)
+	// numbers separated only by spaces are quantities like "200 250 300",
+	// a phone needs a leading +, a code in brackets or dashes between groups
+	phoneRegexp = regexp.MustCompile(`\+\d[\d\-\s()]{9,}\d|\d?[\s-]?\(\d{3,4}\)[\s-]?\d{2,3}[\s-]?\d{2}[\s-]?\d{2}|\b\d{1,4}(-\d{2,4}){3,}\b`)
+)
+
+// SpamFilter holds texts that look like advertisement: links, phone numbers,
+// shouting in capitals or the same word repeated over and over.
+type SpamFilter struct {
+	MaxLinks int
+}
+
+func NewSpamFilter(maxLinks int) *SpamFilter {
+	return &SpamFilter{MaxLinks: maxLinks}
+}
+
+func (f *SpamFilter) Name() string {
+	return "spam"
+}
+
+func (f *SpamFilter) Check(text Text) Verdict {
+	var verdict Verdict
+
+	if links := len(linkRegexp.FindAllString(text.Body, -1)); links > f.MaxLinks {
+		verdict.Reasons = append(verdict.Reasons, fmt.Sprintf("%d links", links))
+	}
+
+	if phoneRegexp.MatchString(text.Body) {
+		verdict.Reasons = append(verdict.Reasons, "phone number")
+	}
+
+	if capsRatio(text.Body) > 0.7 {
+		verdict.Reasons = append(verdict.Reasons, "too many capital letters")
+	}
+
+	if word, ok := repeatedWord(text.Body); ok {
+		verdict.Reasons = append(verdict.Reasons, "repeated word "+word)
+	}
+
+	if len(verdict.Reasons) > 0 {
+		verdict.Action = Hold
+	}
+
+	return verdict
+}
+
+// capsRatio returns the share of capital letters, short texts are skipped.
+func capsRatio(text string) float64 {
+	var letters, upper int
+	for _, r := range text {
+		if !unicode.IsLetter(r) {
+			continue
+		}
+		letters++
+		if unicode.IsUpper(r) {
+			upper++
+		}
+	}
+
+	if letters < 20 {
+		return 0
+	}
+
+	return float64(upper) / float64(letters)
+}
+
+// repeatedWord reports a word that takes more than half of a text of at least 6 words.
+func repeatedWord(text string) (string, bool) {
+	words := strings.Fields(strings.ToLower(text))
+	if len(words) < 6 {
+		return "", false
+	}
+
+	counts := make(map[string]int)
+	for _, word := range words {
+		counts[word]++
+		if counts[word]*2 > len(words) {
+			return word, true
+		}
+	}
+
+	return "", false
+}
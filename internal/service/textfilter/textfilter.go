@@ -0,0 +1,125 @@
+package textfilter
+
+import (
+	"log/slog"
+	"strings"
+
+	"github.com/qwaq-dev/culina/pkg/config"
+)
+
+// Action is what should happen with the checked text.
+type Action int
+
+const (
+	Allow Action = iota
+	Hold         // save the content hidden and send it to the moderation queue
+	Reject
+)
+
+func (a Action) String() string {
+	switch a {
+	case Hold:
+		return "hold"
+	case Reject:
+		return "reject"
+	}
+	return "allow"
+}
+
+// Text is the content passed through the pipeline.
+type Text struct {
+	AuthorId int
+	Kind     string // "review", "recipe"
+	Body     string
+	Edit     bool // the text replaces the author's own content
+}
+
+type Verdict struct {
+	Action  Action
+	Reasons []string
+}
+
+func (v Verdict) Reason() string {
+	return strings.Join(v.Reasons, "; ")
+}
+
+// Checker is one step of the pipeline.
+type Checker interface {
+	Name() string
+	Check(text Text) Verdict
+}
+
+// Recorder is a checker that remembers saved texts.
+type Recorder interface {
+	Record(text Text)
+}
+
+// Pipeline runs all checkers and returns the strictest verdict.
+type Pipeline struct {
+	checkers []Checker
+	log      *slog.Logger
+}
+
+func NewPipeline(log *slog.Logger, checkers ...Checker) *Pipeline {
+	return &Pipeline{checkers: checkers, log: log}
+}
+
+func (p *Pipeline) Check(text Text) Verdict {
+	var verdict Verdict
+
+	for _, checker := range p.checkers {
+		v := checker.Check(text)
+		if v.Action == Allow {
+			continue
+		}
+
+		for _, reason := range v.Reasons {
+			verdict.Reasons = append(verdict.Reasons, checker.Name()+": "+reason)
+		}
+		if v.Action > verdict.Action {
+			verdict.Action = v.Action
+		}
+	}
+
+	if verdict.Action != Allow {
+		p.log.Info("Text was flagged by filter", slog.String("kind", text.Kind), slog.Int("author_id", text.AuthorId),
+			slog.String("action", verdict.Action.String()), slog.String("reasons", verdict.Reason()))
+	}
+
+	return verdict
+}
+
+// Record passes the saved text to the checkers that remember texts.
+func (p *Pipeline) Record(text Text) {
+	for _, checker := range p.checkers {
+		if recorder, ok := checker.(Recorder); ok {
+			recorder.Record(text)
+		}
+	}
+}
+
+// New builds the default pipeline: word lists, spam heuristics and duplicates.
+func New(cfg config.TextFilter, log *slog.Logger) (*Pipeline, error) {
+	var reject, hold []string
+	var err error
+
+	if cfg.RejectWordsFile != "" {
+		if reject, err = LoadWords(cfg.RejectWordsFile); err != nil {
+			return nil, err
+		}
+	}
+
+	if cfg.HoldWordsFile != "" {
+		if hold, err = LoadWords(cfg.HoldWordsFile); err != nil {
+			return nil, err
+		}
+	}
+
+	log.Info("Text filter loaded", slog.Int("reject_words", len(reject)), slog.Int("hold_words", len(hold)))
+
+	return NewPipeline(log,
+		NewWordFilter(reject, hold),
+		NewSpamFilter(cfg.MaxLinks),
+		NewDuplicateFilter(cfg.DuplicateWindow, cfg.DuplicateAuthors),
+	), nil
+}
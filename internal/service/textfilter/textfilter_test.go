@@ -0,0 +1,164 @@
+package textfilter
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestNormalizeWord(t *testing.T) {
+	tests := []struct {
+		word string
+		want string
+	}{
+		// latin leetspeak
+		{"g00d", "good"},
+		{"1dea", "idea"},
+		{"gr3at", "great"},
+		{"4pple", "apple"},
+		{"5un", "sun"},
+		{"7op", "top"},
+		{"@pple", "apple"},
+		{"$un", "sun"},
+		{"h!t", "hit"},
+		{"|ine", "line"},
+
+		// cyrillic leetspeak
+		{"м0л0к0", "молоко"},
+		{"3уб", "зуб"},
+		{"4ай", "чай"},
+		{"6ык", "бык"},
+		{"@рбуз", "арбуз"},
+		{"$ок", "сок"},
+
+		// latin letters that look like cyrillic ones
+		{"кaша", "каша"},
+		{"bода", "вода"},
+		{"cыр", "сыр"},
+		{"лeс", "лес"},
+		{"hос", "нос"},
+		{"kот", "кот"},
+		{"mак", "мак"},
+		{"дoм", "дом"},
+		{"pот", "рот"},
+		{"tок", "ток"},
+		{"xлеб", "хлеб"},
+		{"yтро", "утро"},
+		{"uгра", "игра"},
+
+		{"ёж", "еж"},
+		{"2024", ""},
+	}
+
+	for _, tt := range tests {
+		if got := normalizeWord(tt.word); got != tt.want {
+			t.Errorf("normalizeWord(%q) = %q, want %q", tt.word, got, tt.want)
+		}
+	}
+}
+
+func TestWords(t *testing.T) {
+	tests := []struct {
+		text string
+		want []string
+	}{
+		{"Very tasty!", []string{"very", "tasty"}},
+		{"f.o.o bar", []string{"foo", "bar"}},
+		{"с у к а", []string{"сука"}},
+		{"с-у-к-а!", []string{"сука"}},
+		{"я и ты", []string{"я", "и", "ты"}},
+		{"суп с луком и с сыром", []string{"суп", "с", "луком", "и", "с", "сыром"}},
+		{"a b", []string{"a", "b"}},
+		{"200 г муки", []string{"г", "муки"}},
+	}
+
+	for _, tt := range tests {
+		if got := Words(tt.text); !slices.Equal(got, tt.want) {
+			t.Errorf("Words(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestWordFilter(t *testing.T) {
+	filter := NewWordFilter([]string{"shit", "хуй*"}, []string{"idiot", "crap", "дурак"})
+
+	tests := []struct {
+		text string
+		want Action
+	}{
+		{"tasty soup", Allow},
+
+		// endings
+		{"you idiots", Hold},
+		{"cooked by idiot", Hold},
+		{"это для дураков", Hold},
+		{"дурака", Hold},
+		{"crappy", Hold},
+
+		// words which only start or end like a listed one
+		{"idiotic", Allow},
+		{"scrap the pan", Allow},
+		{"дураковатый", Allow},
+		{"shitake", Allow},
+
+		// prefixes, repeats and lookalikes
+		{"хуйня", Reject},
+		{"shiiiit", Reject},
+		{"5h1t", Reject},
+		{"s h i t", Reject},
+		{"д у р а к", Hold},
+		{"дypaк", Hold},
+
+		{"idiot shit", Reject},
+	}
+
+	for _, tt := range tests {
+		if got := filter.Check(Text{Body: tt.text}); got.Action != tt.want {
+			t.Errorf("Check(%q) = %s, want %s", tt.text, got.Action, tt.want)
+		}
+	}
+}
+
+func TestDuplicateFilter(t *testing.T) {
+	const body = "Great soup, cooked it twice this week"
+
+	tests := []struct {
+		name     string
+		recorded []int
+		text     Text
+		want     Action
+	}{
+		{"new text", nil, Text{AuthorId: 1, Kind: "review", Body: body}, Allow},
+		{"same author", []int{1}, Text{AuthorId: 1, Kind: "review", Body: body}, Reject},
+		{"same author ignores case and punctuation", []int{1}, Text{AuthorId: 1, Kind: "review", Body: "great soup cooked it TWICE this week!!"}, Reject},
+		{"edit of own text", []int{1}, Text{AuthorId: 1, Kind: "review", Body: body, Edit: true}, Allow},
+		{"other author", []int{1}, Text{AuthorId: 2, Kind: "review", Body: body}, Allow},
+		{"several authors", []int{1, 2}, Text{AuthorId: 3, Kind: "review", Body: body}, Hold},
+		{"edit with several authors", []int{1, 2, 3}, Text{AuthorId: 1, Kind: "review", Body: body, Edit: true}, Hold},
+		{"other kind", []int{1}, Text{AuthorId: 1, Kind: "recipe", Body: body}, Allow},
+		{"short text", []int{1}, Text{AuthorId: 1, Kind: "review", Body: "very tasty!"}, Allow},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filter := NewDuplicateFilter(time.Hour, 3)
+			for _, author := range tt.recorded {
+				filter.Record(Text{AuthorId: author, Kind: "review", Body: body})
+			}
+
+			if got := filter.Check(tt.text); got.Action != tt.want {
+				t.Errorf("Check() = %s, want %s", got.Action, tt.want)
+			}
+		})
+	}
+}
+
+func TestDuplicateFilterCheckDoesNotRecord(t *testing.T) {
+	filter := NewDuplicateFilter(time.Hour, 3)
+	text := Text{AuthorId: 1, Kind: "review", Body: "Great soup, cooked it twice this week"}
+
+	filter.Check(text)
+	if got := filter.Check(text); got.Action != Allow {
+		t.Errorf("Check() after failed save = %s, want %s", got.Action, Allow)
+	}
+}
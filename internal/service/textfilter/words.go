@@ -0,0 +1,233 @@
+package textfilter
+
+import (
+	"bufio"
+	"os"
+	"strings"
+	"unicode"
+)
+
+// Common inflection endings, so "idiots" or "дурака" match "idiot" and "дурак".
+var (
+	englishEndings = []string{"s", "es", "ed", "ing", "er", "ers", "y"}
+	russianEndings = []string{"а", "у", "е", "ы", "и", "ой", "ом", "ов", "ам", "ами", "ах", "ей", "ям", "ях", "ю", "я", "ть", "л", "ла", "ли"}
+)
+
+var latinLeet = map[rune]rune{
+	'0': 'o', '1': 'i', '3': 'e', '4': 'a', '5': 's', '7': 't',
+	'@': 'a', '$': 's', '!': 'i', '|': 'l',
+}
+
+var cyrillicLeet = map[rune]rune{
+	'0': 'о', '3': 'з', '4': 'ч', '6': 'б', '@': 'а', '$': 'с',
+	// latin letters that look like cyrillic ones
+	'a': 'а', 'b': 'в', 'c': 'с', 'e': 'е', 'h': 'н', 'k': 'к', 'm': 'м',
+	'o': 'о', 'p': 'р', 't': 'т', 'x': 'х', 'y': 'у', 'u': 'и',
+}
+
+// WordFilter rejects or holds texts containing words from the lists.
+// List entry ending with "*" matches any word starting with it.
+type WordFilter struct {
+	reject wordList
+	hold   wordList
+}
+
+type wordList struct {
+	exact    map[string]bool
+	prefixes []string
+}
+
+func NewWordFilter(reject, hold []string) *WordFilter {
+	return &WordFilter{reject: newWordList(reject), hold: newWordList(hold)}
+}
+
+func (f *WordFilter) Name() string {
+	return "words"
+}
+
+func (f *WordFilter) Check(text Text) Verdict {
+	var verdict Verdict
+
+	for _, word := range Words(text.Body) {
+		switch {
+		case f.reject.match(word):
+			verdict.Action = Reject
+			verdict.Reasons = append(verdict.Reasons, "blocked word "+word)
+		case f.hold.match(word):
+			if verdict.Action < Hold {
+				verdict.Action = Hold
+			}
+			verdict.Reasons = append(verdict.Reasons, "suspicious word "+word)
+		}
+	}
+
+	return verdict
+}
+
+// LoadWords reads a word list file, one word per line, "#" starts a comment.
+func LoadWords(path string) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var words []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		words = append(words, line)
+	}
+
+	return words, scanner.Err()
+}
+
+func newWordList(words []string) wordList {
+	list := wordList{exact: make(map[string]bool)}
+
+	for _, word := range words {
+		word = strings.ToLower(strings.TrimSpace(word))
+		if strings.HasSuffix(word, "*") {
+			list.prefixes = append(list.prefixes, normalizeWord(strings.TrimSuffix(word, "*")))
+			continue
+		}
+		list.exact[normalizeWord(word)] = true
+	}
+
+	return list
+}
+
+func (l wordList) match(word string) bool {
+	if collapsed := collapseRepeats(word); collapsed != word && l.match(collapsed) {
+		return true
+	}
+
+	if l.exact[word] {
+		return true
+	}
+
+	endings := englishEndings
+	if isCyrillic(word) {
+		endings = russianEndings
+	}
+	for _, ending := range endings {
+		if stem, ok := strings.CutSuffix(word, ending); ok && l.exact[stem] {
+			return true
+		}
+	}
+
+	for _, prefix := range l.prefixes {
+		if strings.HasPrefix(word, prefix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// minGlued is the shortest run of single letters glued into a word, shorter
+// runs are usually one-letter words like "я и ты" or "a b".
+const minGlued = 3
+
+// Words splits the text into normalized words. Letters separated by dots,
+// dashes or spaces ("f.o.o", "ф у у") are glued back together.
+func Words(text string) []string {
+	var words []string
+	var single []string
+
+	add := func(token string) {
+		if word := normalizeWord(token); word != "" {
+			words = append(words, word)
+		}
+	}
+
+	flushSingle := func() {
+		if len(single) >= minGlued {
+			add(strings.Join(single, ""))
+		} else {
+			for _, token := range single {
+				add(token)
+			}
+		}
+		single = single[:0]
+	}
+
+	for _, token := range strings.FieldsFunc(strings.ToLower(text), isSeparator) {
+		// "!" is a leetspeak "i" only inside the word
+		token = strings.TrimRight(token, "!")
+		if token == "" {
+			continue
+		}
+		if len([]rune(token)) == 1 {
+			single = append(single, token)
+			continue
+		}
+		flushSingle()
+		add(token)
+	}
+	flushSingle()
+
+	return words
+}
+
+// normalizeWord replaces leetspeak and lookalike characters and "ё" with "е".
+// Tokens without letters (numbers) are dropped.
+func normalizeWord(word string) string {
+	if strings.IndexFunc(word, unicode.IsLetter) < 0 {
+		return ""
+	}
+
+	leet := latinLeet
+	if isCyrillic(word) {
+		leet = cyrillicLeet
+	}
+
+	var b strings.Builder
+	for _, r := range word {
+		if mapped, ok := leet[r]; ok {
+			r = mapped
+		}
+		if r == 'ё' {
+			r = 'е'
+		}
+		if unicode.IsLetter(r) {
+			b.WriteRune(r)
+		}
+	}
+
+	return b.String()
+}
+
+// collapseRepeats removes repeated letters ("тууупой" -> "тупой").
+func collapseRepeats(word string) string {
+	var b strings.Builder
+	var prev rune
+	for _, r := range word {
+		if r != prev {
+			b.WriteRune(r)
+		}
+		prev = r
+	}
+	return b.String()
+}
+
+func isCyrillic(word string) bool {
+	for _, r := range word {
+		if unicode.Is(unicode.Cyrillic, r) {
+			return true
+		}
+	}
+	return false
+}
+
+// isSeparator splits words by everything except letters and characters used in leetspeak.
+func isSeparator(r rune) bool {
+	if unicode.IsLetter(r) || unicode.IsDigit(r) {
+		return false
+	}
+	_, leet := latinLeet[r]
+	return !leet
+}
@@ -1,39 +1,124 @@
 package service
 
 import (
+	"bytes"
+	"context"
+	"errors"
 	"fmt"
-	"log"
+	"io"
+	"log/slog"
 	"mime/multipart"
-	"os"
 	"strconv"
 	"sync"
 	"time"
 
-	"github.com/gofiber/fiber/v2"
+	"github.com/qwaq-dev/culina/internal/repository"
+	"github.com/qwaq-dev/culina/internal/service/images"
+	"github.com/qwaq-dev/culina/structures"
 )
 
-func UploadImagesForReceip(form *multipart.Form, authorID int, c *fiber.Ctx) (map[string]string, string, error) {
+const (
+	MaxRecipeImages = 3
+	MaxReviewImages = 5
+)
+
+// Uploader validates uploaded images, makes their variants and saves them into the store.
+// Files are stored by content hash, so equal images are stored once. Files
+// which are not used by any recipe or review are removed by the images gc.
+type Uploader struct {
+	store     repository.BlobStore
+	blobs     repository.ImageRepository
+	processor *images.Processor
+	timeout   time.Duration
+	log       *slog.Logger
+}
+
+func NewUploader(store repository.BlobStore, blobs repository.ImageRepository, processor *images.Processor, timeout time.Duration, log *slog.Logger) *Uploader {
+	return &Uploader{store: store, blobs: blobs, processor: processor, timeout: timeout, log: log}
+}
+
+// storeContext detaches the storage calls from the request deadline, values
+// of ctx, like the compensations of the unit of work, are kept.
+func (u *Uploader) storeContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.WithoutCancel(ctx), u.timeout)
+}
+
+// uploaded is one saved image of the form.
+type uploaded struct {
+	variants    structures.ImageVariants
+	placeholder structures.ImagePlaceholder
+}
+
+// UploadImagesForReceip saves "images" of the recipe form. Images are
+// numbered from "1", imgs holds the large variant of every image.
+func (u *Uploader) UploadImagesForReceip(ctx context.Context, form *multipart.Form) (map[string]string, map[string]structures.ImageVariants, map[string]structures.ImagePlaceholder, error) {
 	imgs := make(map[string]string)
-	dirName := fmt.Sprintf("./uploads/%d", authorID)
 
 	files, ok := form.File["images"]
 	if !ok || len(files) == 0 {
-		return imgs, "", fmt.Errorf("no files upload")
+		return imgs, nil, nil, ErrNoImages
 	}
 
-	if _, err := os.Stat(dirName); os.IsNotExist(err) {
-		err := os.Mkdir(dirName, os.ModePerm)
-		if err != nil {
-			log.Println("Ошибка при создании папки:", err)
-			return imgs, "", fmt.Errorf("error with creating folder")
+	saved, err := u.uploadImages(ctx, files, MaxRecipeImages)
+	if err != nil {
+		return imgs, nil, nil, err
+	}
+
+	variants := make(map[string]structures.ImageVariants)
+	placeholders := make(map[string]structures.ImagePlaceholder)
+	for i, img := range saved {
+		imgs[i] = img.variants[images.Large].Key
+		variants[i] = img.variants
+		placeholders[i] = img.placeholder
+	}
+
+	return imgs, variants, placeholders, nil
+}
+
+// UploadImagesForReview saves "images" of the review form.
+// Photos are optional, so nothing is returned when the form has none.
+func (u *Uploader) UploadImagesForReview(ctx context.Context, form *multipart.Form) ([]structures.ReviewImage, error) {
+	var photos []structures.ReviewImage
+
+	files, ok := form.File["images"]
+	if !ok || len(files) == 0 {
+		return photos, nil
+	}
+
+	if len(files) > MaxReviewImages {
+		return photos, fmt.Errorf("%w: max %d", ErrTooManyImages, MaxReviewImages)
+	}
+
+	saved, err := u.uploadImages(ctx, files, MaxReviewImages)
+	if err != nil {
+		return photos, err
+	}
+
+	for i := 1; i <= len(files); i++ {
+		if img, ok := saved[strconv.Itoa(i)]; ok {
+			photos = append(photos, structures.ReviewImage{
+				Key:         img.variants[images.Large].Key,
+				Variants:    img.variants,
+				Placeholder: img.placeholder,
+			})
 		}
 	}
 
+	return photos, nil
+}
+
+func (u *Uploader) uploadImages(ctx context.Context, files []*multipart.FileHeader, limit int) (map[string]uploaded, error) {
+	result := make(map[string]uploaded)
+
+	ctx, cancel := u.storeContext(ctx)
+	defer cancel()
+
 	var wg sync.WaitGroup
 	var mu sync.Mutex
+	var uploadErr error
 
 	for i, file := range files {
-		if i+1 >= 4 {
+		if i >= limit {
 			break
 		}
 
@@ -41,19 +126,154 @@ func UploadImagesForReceip(form *multipart.Form, authorID int, c *fiber.Ctx) (ma
 		go func(i int, file *multipart.FileHeader) {
 			defer wg.Done()
 
-			filename := fmt.Sprintf("%s/%d_%s", dirName, time.Now().Unix(), file.Filename)
+			img, err := u.saveFile(ctx, file)
 
-			if err := c.SaveFile(file, filename); err != nil {
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				uploadErr = fmt.Errorf("%s: %w", file.Filename, err)
 				return
 			}
-
-			mu.Lock()
-			imgs[strconv.Itoa(i+1)] = filename
-			mu.Unlock()
+			result[strconv.Itoa(i+1)] = img
 		}(i, file)
 	}
 
 	wg.Wait()
 
-	return imgs, dirName, nil
+	if uploadErr != nil {
+		return make(map[string]uploaded), uploadErr
+	}
+
+	return result, nil
+}
+
+func (u *Uploader) saveFile(ctx context.Context, file *multipart.FileHeader) (uploaded, error) {
+	if file.Size > u.processor.MaxFileSize() {
+		return uploaded{}, images.ErrFileTooLarge
+	}
+
+	src, err := file.Open()
+	if err != nil {
+		return uploaded{}, err
+	}
+	defer src.Close()
+
+	return u.saveImage(ctx, src)
+}
+
+// SaveImage processes and stores the image read from r.
+func (u *Uploader) SaveImage(ctx context.Context, r io.Reader) (structures.RecipeMedia, error) {
+	ctx, cancel := u.storeContext(ctx)
+	defer cancel()
+
+	img, err := u.saveImage(ctx, r)
+	if err != nil {
+		return structures.RecipeMedia{}, err
+	}
+
+	return structures.RecipeMedia{
+		Kind:        structures.MediaImage,
+		Key:         img.variants[images.Large].Key,
+		Variants:    img.variants,
+		Placeholder: img.placeholder,
+	}, nil
+}
+
+// SaveVideo stores the video as is, hash is the sha256 of its content.
+func (u *Uploader) SaveVideo(ctx context.Context, r io.Reader, size int64, hash, ext, contentType string) (structures.RecipeMedia, error) {
+	ctx, cancel := u.storeContext(ctx)
+	defer cancel()
+
+	key := images.BlobKey(hash, ext)
+	if err := u.putBlob(ctx, key, r, size, contentType); err != nil {
+		return structures.RecipeMedia{}, err
+	}
+
+	return structures.RecipeMedia{
+		Kind: structures.MediaVideo,
+		Key:  key,
+		Video: &structures.RecipeVideo{
+			Key:          key,
+			Size:         size,
+			Content_type: contentType,
+		},
+	}, nil
+}
+
+func (u *Uploader) saveImage(ctx context.Context, r io.Reader) (uploaded, error) {
+	var img uploaded
+
+	processed, err := u.processor.Process(r)
+	if err != nil {
+		return img, err
+	}
+
+	img.variants = make(structures.ImageVariants)
+	img.placeholder = structures.ImagePlaceholder{BlurHash: processed.BlurHash, Color: processed.Color}
+
+	for _, e := range processed.Variants {
+		variant := structures.ImageVariant{
+			Width:  e.Width,
+			Height: e.Height,
+		}
+
+		if variant.Key, err = u.saveBlob(ctx, e.Data, e.Ext, e.ContentType); err != nil {
+			return img, err
+		}
+
+		if e.WebP != nil {
+			if variant.WebP, err = u.saveBlob(ctx, e.WebP, ".webp", "image/webp"); err != nil {
+				return img, err
+			}
+		}
+
+		img.variants[e.Name] = variant
+	}
+
+	return img, nil
+}
+
+// saveBlob stores the file under its content hash.
+func (u *Uploader) saveBlob(ctx context.Context, data []byte, ext, contentType string) (string, error) {
+	key := images.BlobKey(images.Hash(data), ext)
+	return key, u.putBlob(ctx, key, bytes.NewReader(data), int64(len(data)), contentType)
+}
+
+// putBlob stores the file, it is not uploaded again when the store already has it.
+// A file stored for the first time is removed if the unit of work of ctx is
+// rolled back, unless it was uploaded again meanwhile.
+func (u *Uploader) putBlob(ctx context.Context, key string, r io.Reader, size int64, contentType string) error {
+	inserted, touchedAt, err := u.blobs.TouchBlob(structures.ImageBlob{
+		Key:          key,
+		Size:         size,
+		Content_type: contentType,
+	}, u.log)
+	if err != nil {
+		return err
+	}
+
+	if !inserted {
+		_, err := u.store.Stat(ctx, key)
+		if err == nil {
+			return nil
+		}
+		if !errors.Is(err, repository.ErrBlobNotFound) {
+			return err
+		}
+	}
+
+	if err := u.store.Put(ctx, key, r, size, contentType); err != nil {
+		return err
+	}
+
+	if inserted {
+		repository.OnRollback(ctx, func(ctx context.Context) error {
+			// a later upload of the same file touches the blob and keeps it
+			_, err := u.blobs.DeleteBlob(key, touchedAt.Add(time.Microsecond), func(key string) error {
+				return u.store.Delete(ctx, key)
+			}, u.log)
+			return err
+		})
+	}
+	return nil
 }
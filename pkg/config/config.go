@@ -3,19 +3,31 @@ package config
 import (
 	"log"
 	"os"
+	"time"
 
 	"github.com/ilyakaznacheev/cleanenv"
 )
 
 type Config struct {
-	Env       string `yaml:"env" env-default:"dev" env-requried:"true"`
-	Server    `yaml:"server"`
-	Database  `yaml:"database"`
-	Typesense `yaml:"typesense"`
+	Env        string `yaml:"env" env-default:"dev" env-requried:"true"`
+	Server     `yaml:"server"`
+	Database   `yaml:"database"`
+	Typesense  `yaml:"typesense"`
+	Search     `yaml:"search"`
+	Moderation `yaml:"moderation"`
+	TextFilter `yaml:"text_filter"`
+	Storage    `yaml:"storage"`
+	Images     `yaml:"images"`
+	Uploads    `yaml:"uploads"`
+	Analytics  `yaml:"analytics"`
 }
 
 type Server struct {
-	Port string `yaml:"port" env-default:":8080"`
+	Port      string `yaml:"port" env-default:":8080"`
+	BodyLimit int    `yaml:"body_limit" env-default:"62914560"` // bytes
+
+	// Deadline of a whole request, queries still running are cancelled.
+	RequestTimeout time.Duration `yaml:"request_timeout" env-default:"30s"`
 }
 
 type Typesense struct {
@@ -23,6 +35,99 @@ type Typesense struct {
 	APIKey string `yaml:"api_key"`
 }
 
+// Search selects the recipe search index. With typesense, recipes are
+// searched by Postgres full-text search while Typesense is unhealthy.
+type Search struct {
+	Backend        string        `yaml:"backend" env-default:"typesense"` // typesense | postgres
+	HealthInterval time.Duration `yaml:"health_interval" env-default:"30s"`
+
+	// Changes reach the index through the outbox relay. Failed events are
+	// retried with a doubling delay up to MaxRetryDelay.
+	RelayInterval     time.Duration `yaml:"relay_interval" env-default:"2s"`
+	RelayBatchSize    int           `yaml:"relay_batch_size" env-default:"100"`
+	RelayLease        time.Duration `yaml:"relay_lease" env-default:"1m"`
+	MaxRetryDelay     time.Duration `yaml:"max_retry_delay" env-default:"1h"`
+	ReconcileInterval time.Duration `yaml:"reconcile_interval" env-default:"1h"`
+
+	// Suggestions are rebuilt from recipes and logged queries which found
+	// recipes at least SuggestMinQueryCount times.
+	SuggestInterval      time.Duration `yaml:"suggest_interval" env-default:"10m"`
+	SuggestMinQueryCount int           `yaml:"suggest_min_query_count" env-default:"3"`
+
+	// Ingredient names are normalized by the synonym groups of this file.
+	IngredientSynonymsFile string `yaml:"ingredient_synonyms_file" env-default:"./config/words/ingredients.txt"`
+}
+
+type Moderation struct {
+	AutoHideReports int `yaml:"auto_hide_reports" env-default:"5"`
+}
+
+type TextFilter struct {
+	RejectWordsFile  string        `yaml:"reject_words_file"`
+	HoldWordsFile    string        `yaml:"hold_words_file"`
+	MaxLinks         int           `yaml:"max_links" env-default:"1"`
+	DuplicateWindow  time.Duration `yaml:"duplicate_window" env-default:"24h"`
+	DuplicateAuthors int           `yaml:"duplicate_authors" env-default:"3"`
+}
+
+type Storage struct {
+	Backend  string `yaml:"backend" env-default:"local"` // local | s3
+	LocalDir string `yaml:"local_dir" env-default:"./uploads"`
+	// Timeout bounds storing the files of one upload, it doesn't share the
+	// request deadline, so large videos have time to be copied.
+	Timeout time.Duration `yaml:"timeout" env-default:"10m"`
+	S3      `yaml:"s3"`
+}
+
+type S3 struct {
+	Endpoint  string `yaml:"endpoint" env-default:"localhost:9000"`
+	AccessKey string `yaml:"access_key"`
+	SecretKey string `yaml:"secret_key"`
+	Bucket    string `yaml:"bucket" env-default:"culina"`
+	Region    string `yaml:"region"`
+	UseSSL    bool   `yaml:"use_ssl"`
+}
+
+type Images struct {
+	MaxFileSize int64 `yaml:"max_file_size" env-default:"10485760"` // bytes
+	MaxWidth    int   `yaml:"max_width" env-default:"8000"`
+	MaxHeight   int   `yaml:"max_height" env-default:"8000"`
+	ThumbSize   int   `yaml:"thumb_size" env-default:"320"`
+	MediumSize  int   `yaml:"medium_size" env-default:"800"`
+	LargeSize   int   `yaml:"large_size" env-default:"1600"`
+	JPEGQuality int   `yaml:"jpeg_quality" env-default:"85"`
+	WebP        bool  `yaml:"webp" env-default:"true"`
+
+	// Images of draft and hidden recipes are served only by signed urls,
+	// they are not served at all while the key is empty.
+	SigningKey   string        `yaml:"signing_key"`
+	SignedURLTTL time.Duration `yaml:"signed_url_ttl" env-default:"15m"`
+
+	// Unused files are removed by the images gc, files younger than
+	// the grace period are never removed.
+	GCInterval    time.Duration `yaml:"gc_interval" env-default:"6h"`
+	GCGracePeriod time.Duration `yaml:"gc_grace_period" env-default:"24h"`
+}
+
+// Uploads configures resumable uploads of recipe media.
+type Uploads struct {
+	MaxSize         int64         `yaml:"max_size" env-default:"524288000"`     // bytes
+	MaxChunkSize    int64         `yaml:"max_chunk_size" env-default:"8388608"` // bytes
+	SessionTTL      time.Duration `yaml:"session_ttl" env-default:"24h"`        // since the last chunk
+	CleanupInterval time.Duration `yaml:"cleanup_interval" env-default:"1h"`
+}
+
+// Analytics events are queued in memory and written in batches,
+// events are dropped while the queue is full.
+type Analytics struct {
+	QueueSize     int           `yaml:"queue_size" env-default:"10000"`
+	BatchSize     int           `yaml:"batch_size" env-default:"500"`
+	FlushInterval time.Duration `yaml:"flush_interval" env-default:"5s"`
+	// searches and clicks older than Retention are removed every CleanupInterval
+	Retention       time.Duration `yaml:"retention" env-default:"2160h"`
+	CleanupInterval time.Duration `yaml:"cleanup_interval" env-default:"24h"`
+}
+
 type Database struct {
 	Port       string `yaml:"port"`
 	DBhost     string `yaml:"host"`
@@ -30,6 +135,20 @@ type Database struct {
 	DBpassword string `yaml:"db_password"`
 	SSLMode    string `yaml:"sslmode"`
 	DBusername string `yaml:"db_username"`
+
+	// Pending migrations are applied on startup, instances starting
+	// together wait for each other on an advisory lock.
+	AutoMigrate bool `yaml:"auto_migrate" env-default:"false"`
+
+	Timeouts QueryTimeouts `yaml:"timeouts"`
+}
+
+// QueryTimeouts bound one repository operation within the request deadline.
+// Operations overrides the timeout of a method by its name, e.g. SelectAllRecipes.
+type QueryTimeouts struct {
+	Read       time.Duration            `yaml:"read" env-default:"5s"`
+	Write      time.Duration            `yaml:"write" env-default:"10s"`
+	Operations map[string]time.Duration `yaml:"operations"`
 }
 
 func MustLoad() *Config {
@@ -0,0 +1,66 @@
+// Package requestctx carries the request id and the acting user in a context,
+// and adds them to every record logged with that context.
+package requestctx
+
+import (
+	"context"
+	"log/slog"
+)
+
+type key int
+
+const (
+	requestIdKey key = iota
+	userKey
+)
+
+func WithRequestId(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIdKey, id)
+}
+
+// RequestId returns the id of the request, "" outside of requests.
+func RequestId(ctx context.Context) string {
+	id, _ := ctx.Value(requestIdKey).(string)
+	return id
+}
+
+// WithUser sets the user the request acts for, 0 is ignored.
+func WithUser(ctx context.Context, userId int) context.Context {
+	if userId == 0 {
+		return ctx
+	}
+	return context.WithValue(ctx, userKey, userId)
+}
+
+func User(ctx context.Context) (int, bool) {
+	userId, ok := ctx.Value(userKey).(int)
+	return userId, ok
+}
+
+// Handler adds request_id and user_id of the context to records logged
+// by the *Context methods of slog.Logger.
+type Handler struct {
+	slog.Handler
+}
+
+func NewHandler(h slog.Handler) *Handler {
+	return &Handler{Handler: h}
+}
+
+func (h *Handler) Handle(ctx context.Context, r slog.Record) error {
+	if id := RequestId(ctx); id != "" {
+		r.AddAttrs(slog.String("request_id", id))
+	}
+	if userId, ok := User(ctx); ok {
+		r.AddAttrs(slog.Int("user_id", userId))
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h *Handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &Handler{Handler: h.Handler.WithAttrs(attrs)}
+}
+
+func (h *Handler) WithGroup(name string) slog.Handler {
+	return &Handler{Handler: h.Handler.WithGroup(name)}
+}
@@ -0,0 +1,31 @@
+package structures
+
+// SearchLogEntry is one search, Params holds the SearchQuery without the text.
+type SearchLogEntry struct {
+	Id         string      `json:"id"`
+	Query      string      `json:"query"`
+	Params     SearchQuery `json:"params"`
+	Found      int         `json:"found"`
+	Latency_ms int         `json:"latency_ms"`
+	User_id    int         `json:"user_id,omitempty"`
+	Created_at string      `json:"created_at,omitempty"`
+}
+
+// SearchClick is a click on a recipe found by the search.
+type SearchClick struct {
+	Search_id string `json:"search_id"`
+	Recipe_id int    `json:"recipe_id"`
+	Position  int    `json:"position"`
+	User_id   int    `json:"user_id,omitempty"`
+}
+
+// QueryStats describes how a query performed during a period.
+type QueryStats struct {
+	Query        string  `json:"query"`
+	Searches     int     `json:"searches"`
+	Avg_found    float64 `json:"avg_found"`
+	Zero_results int     `json:"zero_results"`
+	Clicked      int     `json:"clicked"` // searches with at least one click
+	Ctr          float64 `json:"ctr"`     // Clicked / Searches
+	Avg_position float64 `json:"avg_position,omitempty"`
+}
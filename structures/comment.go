@@ -0,0 +1,27 @@
+package structures
+
+type Comment struct {
+	Id               int       `json:"id"`
+	Recipe_id        int       `json:"recipe_id"`
+	Parent_id        *int      `json:"parent_id,omitempty"`
+	Author_id        int       `json:"author_id"`
+	AuthorName       string    `json:"author_name,omitempty"`
+	Text             string    `json:"comment_text"`
+	Is_recipe_author bool      `json:"is_recipe_author"`
+	Edited           bool      `json:"edited"`
+	Deleted          bool      `json:"deleted"`
+	Created_at       string    `json:"created_at,omitempty"`
+	Replies          []Comment `json:"replies,omitempty"`
+}
+
+type Notification struct {
+	Id         int    `json:"id"`
+	User_id    int    `json:"user_id"`
+	Kind       string `json:"kind"`
+	Actor_id   int    `json:"actor_id"`
+	ActorName  string `json:"actor_name,omitempty"`
+	Recipe_id  int    `json:"recipe_id"`
+	Comment_id int    `json:"comment_id"`
+	Read       bool   `json:"read"`
+	Created_at string `json:"created_at"`
+}
@@ -0,0 +1,41 @@
+package structures
+
+import "time"
+
+// ImageVariant is one resized copy of an uploaded image.
+type ImageVariant struct {
+	Key    string `json:"key"`
+	WebP   string `json:"webp,omitempty"`
+	Width  int    `json:"width"`
+	Height int    `json:"height"`
+}
+
+// ImageVariants holds "thumb", "medium" and "large" copies of the image.
+type ImageVariants map[string]ImageVariant
+
+// Keys returns keys of all stored files of the image.
+func (v ImageVariants) Keys() []string {
+	var keys []string
+	for _, variant := range v {
+		keys = append(keys, variant.Key)
+		if variant.WebP != "" {
+			keys = append(keys, variant.WebP)
+		}
+	}
+	return keys
+}
+
+// ImagePlaceholder is shown by clients while the image loads.
+type ImagePlaceholder struct {
+	BlurHash string `json:"blurhash,omitempty"`
+	Color    string `json:"color,omitempty"` // dominant color, "#rrggbb"
+}
+
+// ImageBlob is a stored file, images with equal content share one blob.
+type ImageBlob struct {
+	Key          string    `json:"key"`
+	Size         int64     `json:"size"`
+	Content_type string    `json:"content_type"`
+	Ref_count    int       `json:"ref_count"`
+	Updated_at   time.Time `json:"updated_at"`
+}
@@ -5,35 +5,76 @@ import (
 	"strconv"
 )
 
+// Languages recipes are written and translated in. Every language has its own
+// fields in the search index, so adding one needs a new schema version.
+var Languages = []string{"ru", "en"}
+
+const DefaultLanguage = "ru"
+
+// RecipeTranslation holds the texts of a recipe in another language,
+// empty fields are shown in the original language.
+type RecipeTranslation struct {
+	Name        string            `json:"name,omitempty"`
+	Descr       string            `json:"descr,omitempty"`
+	Ingredients map[string]string `json:"ingredients,omitempty"`
+	Steps       map[string]string `json:"steps,omitempty"`
+}
+
 type Recipes struct {
-	Id           int               `json:"id"`
-	Name         string            `json:"name"`
-	Descr        string            `json:"descr"`
-	Diff         string            `json:"diff"` //difficult
-	Filters      []string          `json:"filters"`
-	Imgs         map[string]string `json:"imgs"`
-	AuthorID     int               `json:"authorid,omitempty"`
-	AuthorName   string            `json:"author_name"`
-	Ingredients  map[string]string `json:"ingredients"`
-	Steps        map[string]string `json:"steps"`
-	Review_count int               `json:"review_count,omitempty"`
-	Avg_rating   float32           `json:"avg_rating,omitempty"`
-	Reviews      []Review          `json:"reviews,omitempty"`
-	Created_at   string            `json:"created_at,omitempty"`
+	Id               int                          `json:"id"`
+	Name             string                       `json:"name"`
+	Descr            string                       `json:"descr"`
+	Diff             string                       `json:"diff"`      //difficult
+	Cook_time        int                          `json:"cook_time"` // minutes, 0 if unknown
+	Calories         int                          `json:"calories"`  // kcal per serving, 0 if unknown
+	Filters          []string                     `json:"filters"`
+	Imgs             map[string]string            `json:"imgs"`
+	Img_variants     map[string]ImageVariants     `json:"img_variants,omitempty"`
+	Img_placeholders map[string]ImagePlaceholder  `json:"img_placeholders,omitempty"`
+	Videos           []RecipeVideo                `json:"videos,omitempty"`
+	AuthorID         int                          `json:"authorid,omitempty"`
+	AuthorName       string                       `json:"author_name"`
+	Ingredients      map[string]string            `json:"ingredients"`
+	Ingredient_names []string                     `json:"ingredient_names,omitempty"` // normalized, for search
+	Steps            map[string]string            `json:"steps"`
+	Lang             string                       `json:"lang"`                   // language the recipe was written in
+	Translations     map[string]RecipeTranslation `json:"translations,omitempty"` // by language
+	Content_lang     string                       `json:"content_lang,omitempty"` // language of the texts in the response
+	Review_count     int                          `json:"review_count,omitempty"`
+	Avg_rating       float32                      `json:"avg_rating,omitempty"`
+	Reviews          []Review                     `json:"reviews,omitempty"`
+	Created_at       string                       `json:"created_at,omitempty"`
+	Draft            bool                         `json:"draft,omitempty"`
+
+	Rating_distribution map[int]int   `json:"rating_distribution,omitempty"` // stars -> count
+	Pinned_photo        *ReviewImage  `json:"pinned_photo,omitempty"`
+	Community_photos    []ReviewImage `json:"community_photos,omitempty"`
+	Hold_reason         string        `json:"-"` // set when the text filter sends the recipe to moderation
 }
 
 type TypesenseRecipe struct {
-	Id           string   `json:"id"`
-	Name         string   `json:"name"`
-	Descr        string   `json:"descr"`
-	Diff         string   `json:"diff"`
-	Filters      []string `json:"filters"`
-	Imgs         string   `json:"imgs"`
-	AuthorID     string   `json:"authorid"`
-	Ingredients  string   `json:"ingredients"`
-	Steps        string   `json:"steps"`
-	Review_count int      `json:"review_count"`
-	Avg_rating   float32  `json:"avg_rating"`
+	Id               string   `json:"id"`
+	Name             string   `json:"name"`
+	Descr            string   `json:"descr"`
+	Diff             string   `json:"diff"`
+	Cook_time        int      `json:"cook_time"`
+	Calories         int      `json:"calories"`
+	Filters          []string `json:"filters"`
+	Imgs             string   `json:"imgs"`
+	Placeholders     string   `json:"img_placeholders"`
+	AuthorID         string   `json:"authorid"`
+	Ingredients      string   `json:"ingredients"`
+	Ingredient_names []string `json:"ingredient_names"`
+	Steps            string   `json:"steps"`
+	Lang             string   `json:"lang"`
+	Name_ru          string   `json:"name_ru,omitempty"`
+	Descr_ru         string   `json:"descr_ru,omitempty"`
+	Name_en          string   `json:"name_en,omitempty"`
+	Descr_en         string   `json:"descr_en,omitempty"`
+	Translations     string   `json:"translations"`
+	Content_lang     string   `json:"content_lang,omitempty"`
+	Review_count     int      `json:"review_count"`
+	Avg_rating       float32  `json:"avg_rating"`
 }
 
 func (r Recipes) ToTypesense() (*TypesenseRecipe, error) {
@@ -41,6 +82,10 @@ func (r Recipes) ToTypesense() (*TypesenseRecipe, error) {
 	if err != nil {
 		return nil, err
 	}
+	placeholdersJSON, err := json.Marshal(r.Img_placeholders)
+	if err != nil {
+		return nil, err
+	}
 	ingredientsJSON, err := json.Marshal(r.Ingredients)
 	if err != nil {
 		return nil, err
@@ -49,18 +94,105 @@ func (r Recipes) ToTypesense() (*TypesenseRecipe, error) {
 	if err != nil {
 		return nil, err
 	}
+	translationsJSON, err := json.Marshal(r.Translations)
+	if err != nil {
+		return nil, err
+	}
+	ingredientNames := r.Ingredient_names
+	if ingredientNames == nil {
+		// Typesense doesn't accept null for an array field
+		ingredientNames = []string{}
+	}
+
+	nameRu, descrRu := r.texts("ru")
+	nameEn, descrEn := r.texts("en")
 
 	return &TypesenseRecipe{
-		Id:           strconv.Itoa(r.Id),
-		Name:         r.Name,
-		Descr:        r.Descr,
-		Diff:         r.Diff,
-		Filters:      r.Filters,
-		Imgs:         string(imgsJSON),
-		AuthorID:     strconv.Itoa(r.AuthorID),
-		Ingredients:  string(ingredientsJSON),
-		Steps:        string(stepsJSON),
-		Review_count: r.Review_count,
-		Avg_rating:   r.Avg_rating,
+		Id:               strconv.Itoa(r.Id),
+		Name:             r.Name,
+		Descr:            r.Descr,
+		Diff:             r.Diff,
+		Cook_time:        r.Cook_time,
+		Calories:         r.Calories,
+		Filters:          r.Filters,
+		Imgs:             string(imgsJSON),
+		Placeholders:     string(placeholdersJSON),
+		AuthorID:         strconv.Itoa(r.AuthorID),
+		Ingredients:      string(ingredientsJSON),
+		Ingredient_names: ingredientNames,
+		Steps:            string(stepsJSON),
+		Lang:             r.Lang,
+		Name_ru:          nameRu,
+		Descr_ru:         descrRu,
+		Name_en:          nameEn,
+		Descr_en:         descrEn,
+		Translations:     string(translationsJSON),
+		Review_count:     r.Review_count,
+		Avg_rating:       r.Avg_rating,
 	}, nil
 }
+
+// texts returns the name and the description in the language, empty without a translation.
+func (r Recipes) texts(lang string) (string, string) {
+	if lang == r.Lang {
+		return r.Name, r.Descr
+	}
+	t := r.Translations[lang]
+	return t.Name, t.Descr
+}
+
+// Localized returns the recipe with texts translated to lang where there
+// is a translation. Content_lang is set to the language of the name.
+func (r Recipes) Localized(lang string) Recipes {
+	r.Content_lang = r.Lang
+
+	t, ok := r.Translations[lang]
+	if !ok || lang == r.Lang {
+		return r
+	}
+
+	if t.Name != "" {
+		r.Name = t.Name
+		r.Content_lang = lang
+	}
+	if t.Descr != "" {
+		r.Descr = t.Descr
+	}
+	if len(t.Ingredients) > 0 {
+		r.Ingredients = t.Ingredients
+	}
+	if len(t.Steps) > 0 {
+		r.Steps = t.Steps
+	}
+	return r
+}
+
+// Localized is Recipes.Localized for search documents.
+func (d TypesenseRecipe) Localized(lang string) TypesenseRecipe {
+	d.Content_lang = d.Lang
+
+	var translations map[string]RecipeTranslation
+	json.Unmarshal([]byte(d.Translations), &translations)
+
+	t, ok := translations[lang]
+	if !ok || lang == d.Lang {
+		return d
+	}
+
+	if t.Name != "" {
+		d.Name = t.Name
+		d.Content_lang = lang
+	}
+	if t.Descr != "" {
+		d.Descr = t.Descr
+	}
+	if len(t.Ingredients) > 0 {
+		ingredientsJSON, _ := json.Marshal(t.Ingredients)
+		d.Ingredients = string(ingredientsJSON)
+	}
+	if len(t.Steps) > 0 {
+		stepsJSON, _ := json.Marshal(t.Steps)
+		d.Steps = string(stepsJSON)
+	}
+	return d
+}
@@ -0,0 +1,55 @@
+package structures
+
+// Report target types
+const (
+	TargetRecipe  = "recipe"
+	TargetReview  = "review"
+	TargetComment = "comment"
+	TargetUser    = "user"
+)
+
+// Moderation actions
+const (
+	ActionHide     = "hide"
+	ActionDelete   = "delete"
+	ActionWarn     = "warn"
+	ActionBan      = "ban"
+	ActionDismiss  = "dismiss"
+	ActionApprove  = "approve" // makes hidden content visible again
+	ActionAutoHide = "auto_hide"
+)
+
+// Report reason codes
+var ReportReasons = map[string]bool{
+	"spam":           true,
+	"offensive":      true,
+	"harassment":     true,
+	"copyright":      true,
+	"dangerous":      true,
+	"misinformation": true,
+	"other":          true,
+}
+
+type Report struct {
+	Id           int    `json:"id"`
+	Target_type  string `json:"target_type"`
+	Target_id    int    `json:"target_id"`
+	Reporter_id  int    `json:"reporter_id"`
+	Reason       string `json:"reason"`
+	Details      string `json:"details,omitempty"`
+	Status       string `json:"status"`
+	Moderator_id *int   `json:"moderator_id,omitempty"`
+	Reports      int    `json:"reports_on_target,omitempty"`
+	Created_at   string `json:"created_at,omitempty"`
+}
+
+type ModerationAction struct {
+	Id           int    `json:"id"`
+	Report_id    *int   `json:"report_id,omitempty"`
+	Moderator_id *int   `json:"moderator_id,omitempty"`
+	Target_type  string `json:"target_type"`
+	Target_id    int    `json:"target_id"`
+	Action       string `json:"action"`
+	Note         string `json:"note,omitempty"`
+	Created_at   string `json:"created_at"`
+}
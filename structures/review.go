@@ -1,9 +1,32 @@
 package structures
 
 type Review struct {
-	Id           int    `json:"id"`
-	Text         string `json:"review_text"`
-	Rating_value int    `json:"rating_value"`
-	Reviewed_by  int    `json:"author_id"`
-	Recipe_id    int    `json:"recipe_id"`
+	Id           int           `json:"id" form:"-"`
+	Text         string        `json:"review_text" form:"review_text"`
+	Rating_value int           `json:"rating_value" form:"rating_value"`
+	Reviewed_by  int           `json:"author_id" form:"author_id"`
+	Recipe_id    int           `json:"recipe_id" form:"recipe_id"`
+	Helpful_up   int           `json:"helpful_up" form:"-"`
+	Helpful_down int           `json:"helpful_down" form:"-"`
+	Imgs         []ReviewImage `json:"imgs,omitempty" form:"-"`
+	Created_at   string        `json:"created_at,omitempty" form:"-"`
+	Hold_reason  string        `json:"-" form:"-"` // set when the text filter sends the review to moderation
+}
+
+type ReviewVote struct {
+	ReviewId int `json:"review_id"`
+	UserId   int `json:"user_id"`
+	Value    int `json:"value"` // 1 - helpful, -1 - not helpful, 0 - remove vote
+}
+
+// ReviewImage is a community photo attached to a review ("I made this").
+type ReviewImage struct {
+	Id          int              `json:"id"`
+	ReviewId    int              `json:"review_id"`
+	AuthorId    int              `json:"author_id,omitempty"`
+	Key         string           `json:"key"`
+	Variants    ImageVariants    `json:"variants,omitempty"`
+	Placeholder ImagePlaceholder `json:"placeholder"`
+	Pinned      bool             `json:"pinned,omitempty"`
+	Created_at  string           `json:"created_at,omitempty"`
 }
@@ -0,0 +1,96 @@
+package structures
+
+// SearchEvent asks to bring the search document of the recipe up to date.
+// It carries no data, the current recipe is loaded when it is applied.
+type SearchEvent struct {
+	Id         int64  `json:"id"`
+	Recipe_id  int    `json:"recipe_id"`
+	Attempts   int    `json:"attempts"`
+	Last_error string `json:"last_error,omitempty"`
+}
+
+// How recipes are matched against several filters.
+const (
+	MatchAll = "all"
+	MatchAny = "any"
+)
+
+// SearchQuery is a full-text query with structured filters. Zero values
+// don't filter. Recipes without cook time or calories don't match their filters.
+type SearchQuery struct {
+	Q           string   `json:"q"`
+	Filters     []string `json:"filters"`
+	Match       string   `json:"match"`   // MatchAll or MatchAny, for Filters
+	Diff        []string `json:"diff"`    // any of
+	With        []string `json:"with"`    // normalized ingredient names, all of
+	Without     []string `json:"without"` // normalized ingredient names, none of
+	AuthorId    int      `json:"author_id"`
+	MinRating   float32  `json:"min_rating"`
+	MaxTime     int      `json:"max_time"`
+	MinCalories int      `json:"min_calories"`
+	MaxCalories int      `json:"max_calories"`
+	Page        int      `json:"page"`
+	PerPage     int      `json:"per_page"`
+	Lang        string   `json:"lang"` // searched first, found recipes are translated to it
+}
+
+type FacetCount struct {
+	Value string `json:"value"`
+	Count int    `json:"count"`
+}
+
+// SearchResult is one page of found recipes. Facets count all found
+// recipes by "filters", "diff" and "ingredient_names" values.
+type SearchResult struct {
+	Found   int                     `json:"found"`
+	Page    int                     `json:"page"`
+	Recipes []TypesenseRecipe       `json:"recipes"`
+	Facets  map[string][]FacetCount `json:"facets"`
+
+	Search_id string `json:"search_id,omitempty"` // sent back with clicks on the found recipes
+}
+
+// Kinds of suggestions.
+const (
+	SuggestRecipe     = "recipe"
+	SuggestIngredient = "ingredient"
+	SuggestTag        = "tag"
+	SuggestQuery      = "query"
+)
+
+// Suggestion completes what the user is typing. Weight orders suggestions
+// with the same prefix: reviews of a recipe, recipes with an ingredient or
+// a tag, or how many times the query was searched.
+type Suggestion struct {
+	Text   string `json:"text"`
+	Kind   string `json:"kind"`
+	Weight int    `json:"weight"`
+}
+
+// SearchSynonym makes the words find each other. With Root set it is one-way,
+// the synonyms find the root, like "pizza" for "margherita".
+type SearchSynonym struct {
+	Id         int      `json:"id"`
+	Root       string   `json:"root,omitempty"`
+	Synonyms   []string `json:"synonyms"`
+	Updated_by int      `json:"updated_by,omitempty"`
+	Updated_at string   `json:"updated_at,omitempty"`
+}
+
+// How an override matches the query.
+const (
+	OverrideExact    = "exact"
+	OverrideContains = "contains"
+)
+
+// SearchOverride curates the results of a query: Pinned recipes go first
+// in the given order, Hidden ones are never shown.
+type SearchOverride struct {
+	Id         int    `json:"id"`
+	Query      string `json:"query"`
+	Match      string `json:"match"`
+	Pinned     []int  `json:"pinned"`
+	Hidden     []int  `json:"hidden"`
+	Updated_by int    `json:"updated_by,omitempty"`
+	Updated_at string `json:"updated_at,omitempty"`
+}
@@ -0,0 +1,48 @@
+package structures
+
+// UploadSession is a resumable upload, the file is sent by chunks
+// and can be continued from Offset after a network error.
+type UploadSession struct {
+	Id         string `json:"id"`
+	Author_id  int    `json:"author_id"`
+	Filename   string `json:"filename"`
+	Size       int64  `json:"size"`
+	Offset     int64  `json:"offset"`
+	Checksum   string `json:"checksum,omitempty"` // sha256 of the file, set when all chunks are received
+	Expires_at string `json:"expires_at"`
+	Created_at string `json:"created_at,omitempty"`
+
+	Hash_state []byte `json:"-"`
+}
+
+func (s UploadSession) Complete() bool {
+	return s.Offset == s.Size
+}
+
+// UploadChunk is a received part of the file.
+type UploadChunk struct {
+	Offset int64
+	Size   int64
+	Key    string
+}
+
+// Kinds of recipe media
+const (
+	MediaImage = "image"
+	MediaVideo = "video"
+)
+
+// RecipeMedia is a finished upload ready to be attached to a recipe.
+type RecipeMedia struct {
+	Kind        string           `json:"kind"`
+	Key         string           `json:"key"` // large variant of images
+	Variants    ImageVariants    `json:"variants,omitempty"`
+	Placeholder ImagePlaceholder `json:"placeholder"`
+	Video       *RecipeVideo     `json:"video,omitempty"`
+}
+
+type RecipeVideo struct {
+	Key          string `json:"key"`
+	Size         int64  `json:"size"`
+	Content_type string `json:"content_type"`
+}
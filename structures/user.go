@@ -5,7 +5,8 @@ type User struct {
 	Email         string `json:"email"`
 	Username      string `json:"username"`
 	Password      string `json:"password"`
-	Role          string `json:"role, omitempty"`
-	Sex           string `json:"sex, omitempty"`
-	Recipes_count string `json:"receipts_count, omitempty"`
+	Role          string `json:"role,omitempty"`
+	Sex           string `json:"sex,omitempty"`
+	Recipes_count string `json:"receipts_count,omitempty"`
+	Banned        bool   `json:"banned,omitempty"`
 }